@@ -4,9 +4,63 @@
 package chains
 
 import (
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/rjman-self/platdot-utils/msg"
 )
 
 type Router interface {
 	Send(message msg.Message) error
 }
+
+// Admin is implemented by the chains that can be inspected and operated through the admin API
+type Admin interface {
+	// AdminStatus returns a JSON serializable snapshot of what the chain is processing
+	AdminStatus() interface{}
+	// PauseListener stops or resumes the processing of new blocks by the listener
+	PauseListener(pause bool)
+	// Requeue routes the deposit made on this chain with the nonce to dest again
+	Requeue(dest msg.ChainId, nonce msg.Nonce) error
+	// Skip makes the writer drop the message with the nonce from source
+	Skip(source msg.ChainId, nonce msg.Nonce)
+}
+
+// Replayer is implemented by the chains that can process the deposits of a past block again
+type Replayer interface {
+	// Replay returns the messages of the deposits made in block, in the order the listener routes them
+	Replay(block uint64) ([]msg.Message, error)
+	// ProposalHash returns the hash identifying the proposal the writer makes for the message
+	ProposalHash(m msg.Message) ([32]byte, error)
+}
+
+// RelayerRole is implemented by the chains whose relayers are the members of the RELAYER_ROLE of the bridge
+type RelayerRole interface {
+	// RoleRelayers returns the members of the RELAYER_ROLE and the relayer threshold of the bridge
+	RoleRelayers() ([]common.Address, uint8, error)
+	// WatchRelayers sends to changed when a relayer is added or removed or the threshold is changed
+	WatchRelayers(changed chan<- struct{}) error
+}
+
+// MultisigRelayers is implemented by the chains whose relayers are the signatories of a multisig
+type MultisigRelayers interface {
+	// RelayerSets returns the set controlling the multisig, followed by the set of a rotation
+	RelayerSets() []RelayerSet
+}
+
+// ResourceHandler is the handler a chain configures for a resource it bridges to dest
+type ResourceHandler struct {
+	ResourceId msg.ResourceId
+	Dest       msg.ChainId
+	Handler    common.Address
+}
+
+// BridgedResources is implemented by the chains that configure the handlers of their resources on the destinations
+type BridgedResources interface {
+	// ResourceHandlers returns the resources with a configured handler
+	ResourceHandlers() []ResourceHandler
+}
+
+// HandlerResolver is implemented by the chains whose bridge maps the resources to their handlers
+type HandlerResolver interface {
+	// ResourceHandler returns the handler of the resource on the bridge, the zero address if it has none
+	ResourceHandler(id msg.ResourceId) (common.Address, error)
+}
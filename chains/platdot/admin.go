@@ -0,0 +1,37 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package platdot
+
+import (
+	"github.com/rjman-self/Platdot/chains"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+var _ chains.Admin = &Chain{}
+
+// AdminStatus is the state of the Alaya chain exposed by the admin API
+type AdminStatus struct {
+	Paused     bool        `json:"paused"`
+	Executions []Execution `json:"executions"`
+}
+
+func (c *Chain) AdminStatus() interface{} {
+	return AdminStatus{
+		Paused:     c.listener.isPaused(),
+		Executions: c.writer.executions(),
+	}
+}
+
+func (c *Chain) PauseListener(pause bool) {
+	c.listener.pause(pause)
+}
+
+func (c *Chain) Requeue(dest msg.ChainId, nonce msg.Nonce) error {
+	return c.listener.requeue(dest, nonce)
+}
+
+func (c *Chain) Skip(source msg.ChainId, nonce msg.Nonce) {
+	c.writer.log.Warn("Skip message", "src", source, "nonce", nonce)
+	c.writer.skips.Add(source, nonce)
+}
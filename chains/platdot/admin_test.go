@@ -0,0 +1,123 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package platdot
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	bridge "github.com/rjman-self/Platdot/bindings/Bridge"
+	erc20Handler "github.com/rjman-self/Platdot/bindings/ERC20Handler"
+	erc721Handler "github.com/rjman-self/Platdot/bindings/ERC721Handler"
+	"github.com/rjman-self/Platdot/bindings/ERC721MinterBurnerPauser"
+	utils "github.com/rjman-self/Platdot/shared/platdot"
+	ethtest "github.com/rjman-self/Platdot/shared/platdot/testing"
+	"github.com/rjman-self/platdot-utils/crypto/secp256k1"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+// keypairConnection only serves the keypair, the contracts of the listener are bound to a simulated backend
+type keypairConnection struct {
+	Connection
+	kp *secp256k1.Keypair
+}
+
+func (c *keypairConnection) Keypair() *secp256k1.Keypair {
+	return c.kp
+}
+
+func TestListener_RequeueErc721(t *testing.T) {
+	from := AliceKp.CommonAddress()
+	alloc := core.GenesisAlloc{from: {Balance: new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)}}
+	backend := backends.NewSimulatedBackend(alloc, 100000000)
+	opts, err := bind.NewKeyedTransactorWithChainID(AliceKp.PrivateKey(), ethtest.SimulatedChainId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The bridge maps a resource to the ERC20 handler, the deposit is made on the ERC721 handler
+	bridgeAddr, erc20Resource := ethtest.DeploySimulatedBridge(t, backend, AliceKp.PrivateKey())
+	b, err := bridge.NewBridge(bridgeAddr, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	erc20HandlerAddr, err := b.ResourceIDToHandlerAddress(&bind.CallOpts{}, erc20Resource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	erc721HandlerAddr, _, _, err := erc721Handler.DeployERC721Handler(opts, backend, bridgeAddr, [][32]byte{}, []common.Address{}, []common.Address{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	erc721Addr, _, erc721, err := ERC721MinterBurnerPauser.DeployERC721MinterBurnerPauser(opts, backend, "token", "TKN", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+
+	src := msg.ChainId(0)
+	dst := msg.ChainId(1)
+	tokenId := big.NewInt(99)
+	resourceId := msg.ResourceIdFromSlice(append(common.LeftPadBytes(erc721Addr.Bytes(), 31), uint8(src)))
+	recipient := BobKp.CommonAddress()
+	_, err = b.AdminSetResource(opts, erc721HandlerAddr, resourceId, erc721Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = erc721.Mint(opts, from, tokenId, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+	_, err = erc721.Approve(opts, erc721HandlerAddr, tokenId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+	_, err = b.Deposit(opts, uint8(dst), resourceId, utils.ConstructErc721DepositData(tokenId, recipient.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+
+	cfg := *aliceTestConfig
+	cfg.id = src
+	cfg.bridgeContract = bridgeAddr
+	cfg.erc20HandlerContract = erc20HandlerAddr
+	cfg.erc721HandlerContract = erc721HandlerAddr
+	cfg.genericHandlerContract = utils.ZeroAddress
+	erc20HandlerContract, err := erc20Handler.NewERC20Handler(erc20HandlerAddr, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	erc721HandlerContract, err := erc721Handler.NewERC721Handler(erc721HandlerAddr, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := &MockRouter{msgs: make(chan msg.Message, 1)}
+	l := &listener{cfg: cfg, conn: &keypairConnection{kp: AliceKp}, log: TestLogger}
+	l.setContracts(b, erc20HandlerContract, erc721HandlerContract, nil)
+	l.setRouter(router)
+
+	err = l.requeue(dst, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := msg.NewNonFungibleTransfer(src, dst, 1, resourceId, tokenId, recipient.Bytes(), []byte{})
+	m := <-router.msgs
+	if m.Type != msg.NonFungibleTransfer || m.ResourceId != resourceId || !reflect.DeepEqual(m.Payload[:2], expected.Payload[:2]) {
+		t.Fatalf("Got: %v Expected: %v", m, expected)
+	}
+
+	// No handler holds a deposit of the next nonce
+	err = l.requeue(dst, 2)
+	if err == nil {
+		t.Fatal("Requeue of a nonce without deposit should fail")
+	}
+}
@@ -24,10 +24,14 @@ import (
 	"github.com/ChainSafe/log15"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	bridge "github.com/rjman-self/Platdot/bindings/Bridge"
 	erc20Handler "github.com/rjman-self/Platdot/bindings/ERC20Handler"
+	erc721Handler "github.com/rjman-self/Platdot/bindings/ERC721Handler"
+	"github.com/rjman-self/Platdot/bindings/GenericHandler"
 	connection "github.com/rjman-self/Platdot/connections/platdot"
+	utils "github.com/rjman-self/Platdot/shared/platdot"
 	"github.com/rjman-self/platdot-utils/blockstore"
 	"github.com/rjman-self/platdot-utils/core"
 	"github.com/rjman-self/platdot-utils/crypto/secp256k1"
@@ -49,6 +53,7 @@ type Connection interface {
 	CallOpts() *bind.CallOpts
 	LockAndUpdateOpts() error
 	UnlockOpts()
+	Transact(send func(opts *bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error)
 	Client() *ethclient.Client
 	EnsureHasBytecode(address common.Address) error
 	LatestBlock() (*big.Int, error)
@@ -75,6 +80,32 @@ func setupBlockstore(cfg *Config, kp *secp256k1.Keypair) (*blockstore.Blockstore
 	return bs, nil
 }
 
+// newGasPricer returns the gas price strategy of the config
+func newGasPricer(cfg *Config) connection.GasPricer {
+	switch cfg.gasPriceStrategy {
+	case StaticGasPriceStrategy:
+		return connection.StaticGasPrice{Price: cfg.gasPrice}
+	case PercentileGasPriceStrategy:
+		return connection.PercentileGasPrice{Percentile: cfg.gasPricePercentile, Blocks: cfg.gasPriceBlocks}
+	default:
+		return connection.SuggestedGasPrice{Multiplier: cfg.gasMultiplier}
+	}
+}
+
+// ensureHandlers checks that the configured handlers are deployed, the handlers that are not configured are skipped
+func ensureHandlers(conn Connection, cfg *Config) error {
+	for _, handler := range []common.Address{cfg.erc20HandlerContract, cfg.erc721HandlerContract, cfg.genericHandlerContract} {
+		if handler == utils.ZeroAddress {
+			continue
+		}
+		err := conn.EnsureHasBytecode(handler)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr chan<- error, m *metrics.ChainMetrics) (*Chain, error) {
 	// parse config
 	cfg, err := parseChainConfig(chainCfg)
@@ -104,9 +135,41 @@ func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr cha
 		return nil, err
 	}
 
+	return newChain(chainCfg, cfg, kp, bs, logger, sysErr, m)
+}
+
+// InitializeReplayer connects to the chain to inspect the deposits of past blocks. The keystore and the
+// blockstore are not opened, the chain must not be started.
+func InitializeReplayer(chainCfg *core.ChainConfig, logger log15.Logger) (*Chain, error) {
+	cfg, err := parseChainConfig(chainCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Setenv("networkId", cfg.networkId)
+	if err != nil {
+		return nil, err
+	}
+
+	// The connection needs a key to construct its transaction options, nothing is signed with it
+	kp, err := secp256k1.GenerateKeypair()
+	if err != nil {
+		return nil, err
+	}
+	return newChain(chainCfg, cfg, kp, nil, logger, make(chan error, 1), nil)
+}
+
+// newChain connects to the chain and sets up the listener and the writer of the relayer key
+func newChain(chainCfg *core.ChainConfig, cfg *Config, kp *secp256k1.Keypair, bs blockstore.Blockstorer, logger log15.Logger,
+	sysErr chan<- error, m *metrics.ChainMetrics) (*Chain, error) {
 	stop := make(chan int)
 	conn := connection.NewConnection(cfg.endpoint, cfg.http, kp, logger, cfg.gasLimit, cfg.maxGasPrice, cfg.gasMultiplier)
-	err = conn.Connect()
+	conn.SetGasPricer(newGasPricer(cfg))
+	conn.SetBumping(cfg.bumpBlocks, cfg.bumpPercent)
+	if m != nil {
+		conn.SetNonceMetrics(connection.NewNonceMetrics(cfg.name))
+	}
+	err := conn.Connect()
 	if err != nil {
 		return nil, err
 	}
@@ -121,11 +184,26 @@ func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr cha
 		return nil, err
 	}
 
+	err = ensureHandlers(conn, cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	erc20HandlerContract, err := erc20Handler.NewERC20Handler(cfg.erc20HandlerContract, conn.Client())
 	if err != nil {
 		return nil, err
 	}
 
+	erc721HandlerContract, err := erc721Handler.NewERC721Handler(cfg.erc721HandlerContract, conn.Client())
+	if err != nil {
+		return nil, err
+	}
+
+	genericHandlerContract, err := GenericHandler.NewGenericHandler(cfg.genericHandlerContract, conn.Client())
+	if err != nil {
+		return nil, err
+	}
+
 	if chainCfg.LatestBlock {
 		curr, err := conn.LatestBlock()
 		if err != nil {
@@ -135,7 +213,7 @@ func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr cha
 	}
 
 	listener := NewListener(conn, cfg, logger, bs, stop, sysErr, m)
-	listener.setContracts(bridgeContract, erc20HandlerContract)
+	listener.setContracts(bridgeContract, erc20HandlerContract, erc721HandlerContract, genericHandlerContract)
 
 	writer := NewWriter(conn, cfg, logger, stop, sysErr, m)
 	writer.setContract(bridgeContract)
@@ -6,6 +6,7 @@ package platdot
 import (
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"strconv"
 
@@ -19,6 +20,28 @@ const DefaultGasLimit = 6721975
 const DefaultGasPrice = 20000000000
 const DefaultBlockConfirmations = 10
 const DefaultGasMultiplier = 1
+const DefaultMaxBlockRange = 100
+const DefaultGasPricePercentile = 50
+const DefaultGasPriceBlocks = 20
+const DefaultBumpPercent = 10
+
+// Listener modes
+const (
+	// PollMode polls the latest block and queries the deposits of the confirmed blocks
+	PollMode = "poll"
+	// SubscribeMode follows the deposits with a websocket subscription
+	SubscribeMode = "subscribe"
+)
+
+// Gas price strategies
+const (
+	// StaticGasPriceStrategy always uses the gasPrice option
+	StaticGasPriceStrategy = "static"
+	// SuggestedGasPriceStrategy multiplies the price suggested by the node by the gasMultiplier option
+	SuggestedGasPriceStrategy = "suggested"
+	// PercentileGasPriceStrategy uses a percentile of the prices paid in the latest blocks
+	PercentileGasPriceStrategy = "percentile"
+)
 
 // Chain specific options
 var (
@@ -34,6 +57,14 @@ var (
 	BlockConfirmationsOpt = "blockConfirmations"
 	PrefixOpt             = "prefix"
 	NetWorkIdOpt          = "networkId"
+	ListenerModeOpt       = "listenerMode"
+	MaxBlockRangeOpt      = "maxBlockRange"
+	GasPriceStrategyOpt   = "gasPriceStrategy"
+	GasPriceOpt           = "gasPrice"
+	GasPricePercentileOpt = "gasPricePercentile"
+	GasPriceBlocksOpt     = "gasPriceBlocks"
+	BumpBlocksOpt         = "bumpBlocks"
+	BumpPercentOpt        = "bumpPercent"
 )
 
 // Config encapsulates all necessary parameters in ethereum compatible forms
@@ -57,6 +88,14 @@ type Config struct {
 	http                   bool // Config for type of connection
 	startBlock             *big.Int
 	blockConfirmations     *big.Int
+	listenerMode           string   // PollMode or SubscribeMode
+	maxBlockRange          *big.Int // Most blocks queried by one FilterLogs
+	gasPriceStrategy       string   // StaticGasPriceStrategy, SuggestedGasPriceStrategy or PercentileGasPriceStrategy
+	gasPrice               *big.Int // Price of the static strategy
+	gasPricePercentile     uint64
+	gasPriceBlocks         uint64 // Blocks sampled by the percentile strategy
+	bumpBlocks             uint64 // Blocks before a pending transaction is resubmitted, 0 disables bumping
+	bumpPercent            uint64
 }
 
 // parseChainConfig uses a core.ChainConfig to construct a corresponding Config
@@ -83,6 +122,12 @@ func parseChainConfig(chainCfg *core.ChainConfig) (*Config, error) {
 		networkId: 				chainCfg.Opts[NetWorkIdOpt],
 		startBlock:             big.NewInt(0),
 		blockConfirmations:     big.NewInt(0),
+		listenerMode:           PollMode,
+		maxBlockRange:          big.NewInt(DefaultMaxBlockRange),
+		gasPriceStrategy:       SuggestedGasPriceStrategy,
+		gasPricePercentile:     DefaultGasPricePercentile,
+		gasPriceBlocks:         DefaultGasPriceBlocks,
+		bumpPercent:            DefaultBumpPercent,
 	}
 	//fmt.Printf("load config: http is %v\n prefix is %v\nnetworkId is %v\n id is %v\n", config.http, config.prefix, config.networkId, config.id)
 	if contract, ok := chainCfg.Opts[BridgeOpt]; ok && contract != "" {
@@ -177,9 +222,89 @@ func parseChainConfig(chainCfg *core.ChainConfig) (*Config, error) {
 		delete(chainCfg.Opts, NetWorkIdOpt)
 	}
 
+	if mode, ok := chainCfg.Opts[ListenerModeOpt]; ok && mode != "" {
+		if mode != PollMode && mode != SubscribeMode {
+			return nil, fmt.Errorf("unknown %s %s, expected %s or %s", ListenerModeOpt, mode, PollMode, SubscribeMode)
+		}
+		if mode == SubscribeMode && config.http {
+			return nil, fmt.Errorf("%s %s requires a websocket endpoint", ListenerModeOpt, SubscribeMode)
+		}
+		config.listenerMode = mode
+		delete(chainCfg.Opts, ListenerModeOpt)
+	}
+
+	if maxBlockRange, ok := chainCfg.Opts[MaxBlockRangeOpt]; ok && maxBlockRange != "" {
+		val := big.NewInt(0)
+		_, pass := val.SetString(maxBlockRange, 10)
+		if pass && val.Sign() > 0 {
+			config.maxBlockRange = val
+			delete(chainCfg.Opts, MaxBlockRangeOpt)
+		} else {
+			return nil, fmt.Errorf("unable to parse %s", MaxBlockRangeOpt)
+		}
+	}
+
+	err := parseGasPriceOpts(config, chainCfg.Opts)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(chainCfg.Opts) != 0 {
 		return nil, fmt.Errorf("unknown Opts Encountered: %#v", chainCfg.Opts)
 	}
 
 	return config, nil
 }
+
+// parseGasPriceOpts sets the gas price strategy and the bumping of stuck transactions
+func parseGasPriceOpts(config *Config, opts map[string]string) error {
+	if strategy, ok := opts[GasPriceStrategyOpt]; ok && strategy != "" {
+		switch strategy {
+		case StaticGasPriceStrategy, SuggestedGasPriceStrategy, PercentileGasPriceStrategy:
+			config.gasPriceStrategy = strategy
+			delete(opts, GasPriceStrategyOpt)
+		default:
+			return fmt.Errorf("unknown %s %s, expected %s, %s or %s", GasPriceStrategyOpt, strategy,
+				StaticGasPriceStrategy, SuggestedGasPriceStrategy, PercentileGasPriceStrategy)
+		}
+	}
+
+	if gasPrice, ok := opts[GasPriceOpt]; ok && gasPrice != "" {
+		price := big.NewInt(0)
+		_, pass := price.SetString(gasPrice, 10)
+		if !pass || price.Sign() <= 0 {
+			return fmt.Errorf("unable to parse %s", GasPriceOpt)
+		}
+		config.gasPrice = price
+		delete(opts, GasPriceOpt)
+	}
+	if config.gasPriceStrategy == StaticGasPriceStrategy && config.gasPrice == nil {
+		return fmt.Errorf("%s %s requires %s", GasPriceStrategyOpt, StaticGasPriceStrategy, GasPriceOpt)
+	}
+
+	uintOpts := []struct {
+		name string
+		dest *uint64
+		min  uint64
+		max  uint64
+	}{
+		{GasPricePercentileOpt, &config.gasPricePercentile, 1, 100},
+		{GasPriceBlocksOpt, &config.gasPriceBlocks, 1, math.MaxUint64},
+		{BumpBlocksOpt, &config.bumpBlocks, 0, math.MaxUint64},
+		// The nodes reject replacements raising the price by less than 10%
+		{BumpPercentOpt, &config.bumpPercent, DefaultBumpPercent, math.MaxUint64},
+	}
+	for _, o := range uintOpts {
+		val, ok := opts[o.name]
+		if !ok || val == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(val, 10, 64)
+		if err != nil || n < o.min || n > o.max {
+			return fmt.Errorf("unable to parse %s", o.name)
+		}
+		*o.dest = n
+		delete(opts, o.name)
+	}
+	return nil
+}
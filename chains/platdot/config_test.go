@@ -58,6 +58,12 @@ func TestParseChainConfig(t *testing.T) {
 		http:                   true,
 		startBlock:             big.NewInt(10),
 		blockConfirmations:     big.NewInt(50),
+		listenerMode:           PollMode,
+		maxBlockRange:          big.NewInt(DefaultMaxBlockRange),
+		gasPriceStrategy:       SuggestedGasPriceStrategy,
+		gasPricePercentile:     DefaultGasPricePercentile,
+		gasPriceBlocks:         DefaultGasPriceBlocks,
+		bumpPercent:            DefaultBumpPercent,
 	}
 
 	if !reflect.DeepEqual(&expected, out) {
@@ -110,6 +116,12 @@ func TestParseChainConfigWithNoBlockConfirmations(t *testing.T) {
 		http:                   true,
 		startBlock:             big.NewInt(10),
 		blockConfirmations:     big.NewInt(DefaultBlockConfirmations),
+		listenerMode:           PollMode,
+		maxBlockRange:          big.NewInt(DefaultMaxBlockRange),
+		gasPriceStrategy:       SuggestedGasPriceStrategy,
+		gasPricePercentile:     DefaultGasPricePercentile,
+		gasPriceBlocks:         DefaultGasPriceBlocks,
+		bumpPercent:            DefaultBumpPercent,
 	}
 
 	if !reflect.DeepEqual(&expected, out) {
@@ -158,6 +170,12 @@ func TestChainConfigOneContract(t *testing.T) {
 		http:                 true,
 		startBlock:           big.NewInt(10),
 		blockConfirmations:   big.NewInt(DefaultBlockConfirmations),
+		listenerMode:         PollMode,
+		maxBlockRange:        big.NewInt(DefaultMaxBlockRange),
+		gasPriceStrategy:     SuggestedGasPriceStrategy,
+		gasPricePercentile:   DefaultGasPricePercentile,
+		gasPriceBlocks:       DefaultGasPriceBlocks,
+		bumpPercent:          DefaultBumpPercent,
 	}
 
 	if !reflect.DeepEqual(&expected, out) {
@@ -224,3 +242,90 @@ func TestExtraOpts(t *testing.T) {
 		t.Error("Config should not accept incorrect opts.")
 	}
 }
+
+func TestListenerModeOpts(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts map[string]string
+		mode string
+		err  bool
+	}{
+		{"default", map[string]string{}, PollMode, false},
+		{"subscribe", map[string]string{"listenerMode": "subscribe"}, SubscribeMode, false},
+		{"subscribe over http", map[string]string{"listenerMode": "subscribe", "http": "true"}, "", true},
+		{"unknown mode", map[string]string{"listenerMode": "push"}, "", true},
+		{"zero block range", map[string]string{"maxBlockRange": "0"}, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts["bridge"] = "0x1234"
+			input := core.ChainConfig{
+				Name:     "chain",
+				Id:       1,
+				Endpoint: "endpoint",
+				From:     "0x0",
+				Opts:     tc.opts,
+			}
+
+			out, err := parseChainConfig(&input)
+			if tc.err {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.listenerMode != tc.mode {
+				t.Fatalf("Got: %s Expected: %s", out.listenerMode, tc.mode)
+			}
+		})
+	}
+}
+
+func TestGasPriceOpts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     map[string]string
+		strategy string
+		err      bool
+	}{
+		{"default", map[string]string{}, SuggestedGasPriceStrategy, false},
+		{"static", map[string]string{"gasPriceStrategy": "static", "gasPrice": "10"}, StaticGasPriceStrategy, false},
+		{"static without price", map[string]string{"gasPriceStrategy": "static"}, "", true},
+		{"percentile", map[string]string{"gasPriceStrategy": "percentile", "gasPricePercentile": "90", "gasPriceBlocks": "5"}, PercentileGasPriceStrategy, false},
+		{"percentile above 100", map[string]string{"gasPriceStrategy": "percentile", "gasPricePercentile": "101"}, "", true},
+		{"bumping", map[string]string{"bumpBlocks": "3", "bumpPercent": "20"}, SuggestedGasPriceStrategy, false},
+		{"bump below replacement minimum", map[string]string{"bumpBlocks": "3", "bumpPercent": "5"}, "", true},
+		{"unknown strategy", map[string]string{"gasPriceStrategy": "oracle"}, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts["bridge"] = "0x1234"
+			input := core.ChainConfig{
+				Name:     "chain",
+				Id:       1,
+				Endpoint: "endpoint",
+				From:     "0x0",
+				Opts:     tc.opts,
+			}
+
+			out, err := parseChainConfig(&input)
+			if tc.err {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.gasPriceStrategy != tc.strategy {
+				t.Fatalf("Got: %s Expected: %s", out.gasPriceStrategy, tc.strategy)
+			}
+		})
+	}
+}
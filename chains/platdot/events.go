@@ -53,7 +53,7 @@ func (l *listener) handleGenericDepositedEvent(destId msg.ChainId, nonce msg.Non
 	record, err := l.genericHandlerContract.GetDepositRecord(&bind.CallOpts{From: l.conn.Keypair().CommonAddress()}, uint64(nonce), uint8(destId))
 	if err != nil {
 		l.log.Error("Error Unpacking Generic Deposit Record", "err", err)
-		return msg.Message{}, nil
+		return msg.Message{}, err
 	}
 
 	return msg.NewGenericTransfer(
@@ -8,6 +8,8 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/rjman-self/platdot-utils/blockstore"
@@ -17,6 +19,8 @@ import (
 	eth "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/rjman-self/Platdot/bindings/Bridge"
 	"github.com/rjman-self/Platdot/bindings/ERC20Handler"
 	"github.com/rjman-self/Platdot/bindings/ERC721Handler"
@@ -29,9 +33,16 @@ var BlockRetryInterval = time.Second * 5
 var BlockRetryLimit = 5
 var ErrFatalPolling = errors.New("listener block polling failed")
 
+// ChainReader returns the headers and the deposit logs of the chain
+type ChainReader interface {
+	HeaderReader
+	FilterLogs(ctx context.Context, q eth.FilterQuery) ([]types.Log, error)
+}
+
 type listener struct {
 	cfg                    Config
 	conn                   Connection
+	client                 ChainReader // The client of the connection, the chain the deposits are read from
 	router                 chains.Router
 	bridgeContract         *Bridge.Bridge // instance of bound bridge contract
 	erc20HandlerContract   *ERC20Handler.ERC20Handler
@@ -44,6 +55,8 @@ type listener struct {
 	latestBlock            metrics.LatestBlock
 	metrics                *metrics.ChainMetrics
 	blockConfirmations     *big.Int
+	reorg                  *reorgDetector
+	paused                 int32
 }
 
 // NewListener creates and returns a listener
@@ -51,6 +64,7 @@ func NewListener(conn Connection, cfg *Config, log log15.Logger, bs blockstore.B
 	return &listener{
 		cfg:                *cfg,
 		conn:               conn,
+		client:             conn.Client(),
 		log:                log,
 		blockstore:         bs,
 		stop:               stop,
@@ -58,12 +72,15 @@ func NewListener(conn Connection, cfg *Config, log log15.Logger, bs blockstore.B
 		latestBlock:        metrics.LatestBlock{LastUpdated: time.Now()},
 		metrics:            m,
 		blockConfirmations: cfg.blockConfirmations,
+		reorg:              newReorgDetector(conn.Client()),
 	}
 }
 
-func (l *listener) setContracts(bridge *Bridge.Bridge, erc20Handler *ERC20Handler.ERC20Handler) {
+func (l *listener) setContracts(bridge *Bridge.Bridge, erc20Handler *ERC20Handler.ERC20Handler, erc721Handler *ERC721Handler.ERC721Handler, genericHandler *GenericHandler.GenericHandler) {
 	l.bridgeContract = bridge
 	l.erc20HandlerContract = erc20Handler
+	l.erc721HandlerContract = erc721Handler
+	l.genericHandlerContract = genericHandler
 }
 
 // sets the router
@@ -73,10 +90,15 @@ func (l *listener) setRouter(r chains.Router) {
 
 // start registers all subscriptions provided by the config
 func (l *listener) start() error {
-	l.log.Debug("Starting listener...")
+	l.log.Debug("Starting listener...", "mode", l.cfg.listenerMode)
 
 	go func() {
-		err := l.pollBlocks()
+		var err error
+		if l.cfg.listenerMode == SubscribeMode {
+			err = l.watchBlocks(l.cfg.startBlock)
+		} else {
+			err = l.pollBlocks(l.cfg.startBlock)
+		}
 		if err != nil {
 			l.log.Error("Polling blocks failed", "err", err)
 		}
@@ -86,11 +108,11 @@ func (l *listener) start() error {
 }
 
 // pollBlocks will poll for the latest block and proceed to parse the associated events as it sees new blocks.
-// Polling begins at the block defined in `l.cfg.startBlock`. Failed attempts to fetch the latest block or parse
-// a block will be retried up to BlockRetryLimit times before continuing to the next block.
-func (l *listener) pollBlocks() error {
+// Polling begins at currentBlock. The confirmed blocks are queried in ranges of at most `l.cfg.maxBlockRange`
+// blocks. Failed attempts to fetch the latest block or parse a range will be retried up to BlockRetryLimit times
+// before the listener reports a fatal error.
+func (l *listener) pollBlocks(currentBlock *big.Int) error {
 	l.log.Info("Polling Blocks...")
-	var currentBlock = l.cfg.startBlock
 	var retry = BlockRetryLimit
 
 	for {
@@ -98,6 +120,11 @@ func (l *listener) pollBlocks() error {
 		case <-l.stop:
 			return errors.New("polling terminated")
 		default:
+			if l.isPaused() {
+				time.Sleep(BlockRetryInterval)
+				continue
+			}
+
 			// No more retries, goto next block
 			if retry == 0 {
 				l.log.Error("Polling failed, retries exceeded")
@@ -105,7 +132,7 @@ func (l *listener) pollBlocks() error {
 				return nil
 			}
 
-			latestBlock, err := l.conn.LatestBlock()
+			confirmedBlock, err := l.confirmedBlock()
 			if err != nil {
 				l.log.Error("Unable to get latest block", "block", currentBlock, "err", err)
 				retry--
@@ -113,92 +140,313 @@ func (l *listener) pollBlocks() error {
 				continue
 			}
 
-			if l.metrics != nil {
-				l.metrics.LatestKnownBlock.Set(float64(latestBlock.Int64()))
-			}
-
 			// Sleep if the difference is less than BlockDelay; (latest - current) < BlockDelay
-			if big.NewInt(0).Sub(latestBlock, currentBlock).Cmp(l.blockConfirmations) == -1 {
-				l.log.Debug("Block not ready, will retry", "target", currentBlock, "latest", latestBlock)
+			if confirmedBlock.Cmp(currentBlock) == -1 {
+				l.log.Debug("Block not ready, will retry", "target", currentBlock, "confirmed", confirmedBlock)
 				time.Sleep(BlockRetryInterval)
 				continue
 			}
 
 			// Parse out events
-			err = l.getDepositEventsForBlock(currentBlock)
+			next, err := l.processRange(currentBlock, confirmedBlock)
 			if err != nil {
-				l.log.Error("Failed to get events for block", "block", currentBlock, "err", err)
+				l.log.Error("Failed to get events for blocks", "from", currentBlock, "to", confirmedBlock, "err", err)
 				retry--
 				continue
 			}
 
-			// Write to block store. Not a critical operation, no need to retry
-			err = l.blockstore.StoreBlock(currentBlock)
-			if err != nil {
-				l.log.Error("Failed to write latest block to blockstore", "block", currentBlock, "err", err)
+			// Goto next range and reset retry counter
+			currentBlock = next
+			retry = BlockRetryLimit
+		}
+	}
+}
+
+// watchBlocks follows the deposits with a subscription to the Deposit events of the bridge, starting at currentBlock.
+// The deposits are only routed once their block is confirmed. If the subscription can not be created the
+// listener falls back to polling.
+func (l *listener) watchBlocks(currentBlock *big.Int) error {
+	l.log.Info("Watching deposits...")
+
+	for {
+		sink := make(chan *Bridge.BridgeDeposit)
+		sub, err := l.bridgeContract.WatchDeposit(&bind.WatchOpts{}, sink)
+		if err != nil {
+			l.log.Warn("Unable to subscribe to deposits, polling blocks instead", "err", err)
+			return l.pollBlocks(currentBlock)
+		}
+
+		currentBlock, err = l.followDeposits(currentBlock, sink, sub)
+		sub.Unsubscribe()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// followDeposits routes the deposits received on sink once their block is confirmed, and returns the next
+// block to process when the subscription fails. The blocks mined before the subscription was made are
+// queried with FilterLogs, as their deposits may be missing from the subscription.
+func (l *listener) followDeposits(currentBlock *big.Int, sink <-chan *Bridge.BridgeDeposit, sub event.Subscription) (*big.Int, error) {
+	subscribedBlock, err := l.conn.LatestBlock()
+	if err != nil {
+		l.log.Error("Unable to get latest block", "err", err)
+		time.Sleep(BlockRetryInterval)
+		return currentBlock, nil
+	}
+
+	var pending []types.Log
+	var retry = BlockRetryLimit
+	ticker := time.NewTicker(BlockRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return currentBlock, errors.New("watching terminated")
+		case err := <-sub.Err():
+			l.log.Warn("Deposit subscription failed, subscribing again", "block", currentBlock, "err", err)
+			return currentBlock, nil
+		case evt := <-sink:
+			if evt.Raw.Removed {
+				pending = removeLog(pending, evt.Raw)
+			} else {
+				pending = append(pending, evt.Raw)
+			}
+		case <-ticker.C:
+			if l.isPaused() {
+				continue
 			}
 
-			if l.metrics != nil {
-				l.metrics.BlocksProcessed.Inc()
-				l.metrics.LatestProcessedBlock.Set(float64(latestBlock.Int64()))
+			if retry == 0 {
+				l.log.Error("Watching failed, retries exceeded")
+				l.sysErr <- ErrFatalPolling
+				return currentBlock, ErrFatalPolling
 			}
 
-			l.latestBlock.Height = big.NewInt(0).Set(latestBlock)
-			l.latestBlock.LastUpdated = time.Now()
+			confirmedBlock, err := l.confirmedBlock()
+			if err != nil {
+				l.log.Error("Unable to get latest block", "block", currentBlock, "err", err)
+				retry--
+				continue
+			}
+			if confirmedBlock.Cmp(currentBlock) == -1 {
+				continue
+			}
 
-			// Goto next block and reset retry counter
-			currentBlock.Add(currentBlock, big.NewInt(1))
+			var next *big.Int
+			if currentBlock.Cmp(subscribedBlock) <= 0 {
+				next, err = l.processRange(currentBlock, minBig(confirmedBlock, subscribedBlock))
+			} else {
+				next, pending, err = l.processPending(currentBlock, confirmedBlock, pending)
+			}
+			if err != nil {
+				l.log.Error("Failed to process deposits", "from", currentBlock, "to", confirmedBlock, "err", err)
+				retry--
+				continue
+			}
+
+			// After a rollback the deposits of the new blocks are read again with FilterLogs
+			if next.Cmp(currentBlock) < 0 {
+				return next, nil
+			}
+			currentBlock = next
 			retry = BlockRetryLimit
 		}
 	}
 }
 
-// getDepositEventsForBlock looks for the deposit event in the latest block
-func (l *listener) getDepositEventsForBlock(latestBlock *big.Int) error {
-	l.log.Debug("Querying block for deposit events", "block", latestBlock)
+// confirmedBlock returns the latest block with enough confirmations to be processed
+func (l *listener) confirmedBlock() (*big.Int, error) {
+	latestBlock, err := l.conn.LatestBlock()
+	if err != nil {
+		return nil, err
+	}
 
-	query := buildQuery(l.cfg.bridgeContract, utils.Deposit, latestBlock, latestBlock)
+	if l.metrics != nil {
+		l.metrics.LatestKnownBlock.Set(float64(latestBlock.Int64()))
+	}
 
-	// Query for logs
-	logs, err := l.conn.Client().FilterLogs(context.Background(), query)
+	return new(big.Int).Sub(latestBlock, l.blockConfirmations), nil
+}
+
+// processRange queries the deposits from currentBlock up to endBlock with one FilterLogs, at most
+// `l.cfg.maxBlockRange` blocks at once, and returns the next block to process
+func (l *listener) processRange(currentBlock, endBlock *big.Int) (*big.Int, error) {
+	maxEnd := new(big.Int).Add(currentBlock, l.cfg.maxBlockRange)
+	maxEnd.Sub(maxEnd, big.NewInt(1))
+	endBlock = minBig(endBlock, maxEnd)
+
+	next, reorg, err := l.checkReorg(currentBlock)
+	if err != nil || reorg {
+		return next, err
+	}
+
+	// The hash of the end block is read before the logs, a reorg in between is found on the next range
+	header, err := l.client.HeaderByNumber(context.Background(), endBlock)
 	if err != nil {
-		return fmt.Errorf("unable to Filter Logs: %w", err)
+		return nil, err
 	}
 
-	// Read through the log events and handle their deposit event if handler is recognized
+	logs, err := l.depositLogs(currentBlock, endBlock)
+	if err != nil {
+		return nil, err
+	}
+	err = l.routeLogs(logs)
+	if err != nil {
+		return nil, err
+	}
+
+	l.reorg.track(endBlock.Uint64(), header.Hash())
+	l.retractOrphans(endBlock)
+	l.checkpoint(currentBlock, endBlock)
+	return new(big.Int).Add(endBlock, big.NewInt(1)), nil
+}
+
+// processPending routes the pending deposits from currentBlock up to endBlock and returns the next
+// block to process, with the deposits still waiting for confirmations
+func (l *listener) processPending(currentBlock, endBlock *big.Int, pending []types.Log) (*big.Int, []types.Log, error) {
+	next, reorg, err := l.checkReorg(currentBlock)
+	if err != nil || reorg {
+		return next, pending, err
+	}
+
+	header, err := l.client.HeaderByNumber(context.Background(), endBlock)
+	if err != nil {
+		return nil, pending, err
+	}
+
+	var ready, rest []types.Log
+	for _, log := range pending {
+		block := new(big.Int).SetUint64(log.BlockNumber)
+		if block.Cmp(currentBlock) < 0 {
+			continue
+		}
+		if block.Cmp(endBlock) <= 0 {
+			ready = append(ready, log)
+		} else {
+			rest = append(rest, log)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool {
+		if ready[i].BlockNumber != ready[j].BlockNumber {
+			return ready[i].BlockNumber < ready[j].BlockNumber
+		}
+		return ready[i].Index < ready[j].Index
+	})
+
+	err = l.routeLogs(ready)
+	if err != nil {
+		return nil, pending, err
+	}
+
+	l.reorg.track(endBlock.Uint64(), header.Hash())
+	l.retractOrphans(endBlock)
+	l.checkpoint(currentBlock, endBlock)
+	return new(big.Int).Add(endBlock, big.NewInt(1)), rest, nil
+}
+
+// checkReorg verifies that currentBlock extends the processed blocks. On a reorg the listener
+// rolls back to the common ancestor and returns the block following it.
+func (l *listener) checkReorg(currentBlock *big.Int) (*big.Int, bool, error) {
+	ancestor, reorg, err := l.reorg.check(currentBlock.Uint64())
+	if err != nil {
+		return nil, reorg, err
+	}
+	if !reorg {
+		return currentBlock, false, nil
+	}
+
+	ancestorBlock := new(big.Int).SetUint64(ancestor)
+	l.log.Warn("Chain reorg detected, rolling back", "block", currentBlock, "ancestor", ancestorBlock)
+	err = l.blockstore.StoreBlock(ancestorBlock)
+	if err != nil {
+		l.log.Error("Failed to write latest block to blockstore", "block", ancestorBlock, "err", err)
+	}
+	l.latestBlock.Height = ancestorBlock
+	l.latestBlock.LastUpdated = time.Now()
+	return new(big.Int).Add(ancestorBlock, big.NewInt(1)), true, nil
+}
+
+// routeLogs routes the messages of the deposit logs and records them with their block. A message
+// already routed before a reorg is not routed again.
+func (l *listener) routeLogs(logs []types.Log) error {
 	for _, log := range logs {
-		var m msg.Message
-		fmt.Printf("loop logs get %s\n", log.Topics[0])
-		dest := log.Data[:32]
-		destBig := new(big.Int).SetBytes(dest)
-		destId := msg.ChainId(destBig.Uint64())
-		rId := msg.ResourceIdFromSlice(log.Data[33:64])
-		nc := log.Data[65:96]
-		ncBig := new(big.Int).SetBytes(nc)
-		nonce := msg.Nonce(ncBig.Uint64())
-
-		l.log.Info("Parse event successfully.", "DestId", dest, "ResourceId", rId, "Nonce", nonce)
-		addr, err := l.bridgeContract.ResourceIDToHandlerAddress(&bind.CallOpts{From: l.conn.Keypair().CommonAddress()}, rId)
+		m, err := l.depositMessage(log)
 		if err != nil {
-			return fmt.Errorf("failed to get handler from resource ID %x", rId)
+			return err
+		}
+		if m == nil {
+			continue
 		}
 
-		if addr == l.cfg.erc20HandlerContract {
-			m, err = l.handleErc20DepositedEvent(destId, nonce)
-		} else if addr == l.cfg.erc721HandlerContract {
-			m, err = l.handleErc721DepositedEvent(destId, nonce)
-		} else if addr == l.cfg.genericHandlerContract {
-			m, err = l.handleGenericDepositedEvent(destId, nonce)
-		} else {
-			l.log.Error("event has unrecognized handler", "handler", addr.Hex())
-			return nil
+		l.reorg.track(log.BlockNumber, log.BlockHash, *m)
+		if l.reorg.rerouted(*m) {
+			l.log.Info("Deposit is still valid after the reorg", "dest", m.Destination, "nonce", m.DepositNonce, "block", log.BlockNumber)
+			continue
+		}
+
+		err = l.router.Send(*m)
+		if err != nil {
+			l.log.Error("subscription error: failed to route message", "err", err)
+		}
+	}
+	return nil
+}
+
+// retractOrphans retracts the messages of the orphaned deposits that were not found again
+// once the blocks up to endBlock are processed
+func (l *listener) retractOrphans(endBlock *big.Int) {
+	for _, m := range l.reorg.retractable(endBlock.Uint64()) {
+		l.log.Warn("Retract the message of a deposit orphaned by a reorg", "dest", m.Destination, "nonce", m.DepositNonce)
+		err := l.router.Send(chains.NewRetraction(m))
+		if err != nil {
+			l.log.Error("failed to route retraction", "err", err)
 		}
+	}
+}
 
+// checkpoint records that the blocks from currentBlock up to endBlock are processed
+func (l *listener) checkpoint(currentBlock, endBlock *big.Int) {
+	// Write to block store. Not a critical operation, no need to retry
+	err := l.blockstore.StoreBlock(endBlock)
+	if err != nil {
+		l.log.Error("Failed to write latest block to blockstore", "block", endBlock, "err", err)
+	}
+
+	if l.metrics != nil {
+		count := new(big.Int).Sub(endBlock, currentBlock)
+		l.metrics.BlocksProcessed.Add(float64(count.Int64() + 1))
+		l.metrics.LatestProcessedBlock.Set(float64(endBlock.Int64()))
+	}
+
+	l.latestBlock.Height = big.NewInt(0).Set(endBlock)
+	l.latestBlock.LastUpdated = time.Now()
+}
+
+// getDepositEventsForBlock looks for the deposit event in the latest block
+func (l *listener) getDepositEventsForBlock(latestBlock *big.Int) error {
+	return l.getDepositEventsForRange(latestBlock, latestBlock)
+}
+
+// getDepositEventsForRange looks for the deposit events from startBlock up to endBlock
+func (l *listener) getDepositEventsForRange(startBlock, endBlock *big.Int) error {
+	logs, err := l.depositLogs(startBlock, endBlock)
+	if err != nil {
+		return err
+	}
+
+	// Read through the log events and handle their deposit event if handler is recognized
+	for _, log := range logs {
+		m, err := l.depositMessage(log)
 		if err != nil {
 			return err
 		}
+		if m == nil {
+			continue
+		}
 
-		err = l.router.Send(m)
+		err = l.router.Send(*m)
 		if err != nil {
 			l.log.Error("subscription error: failed to route message", "err", err)
 		}
@@ -207,6 +455,126 @@ func (l *listener) getDepositEventsForBlock(latestBlock *big.Int) error {
 	return nil
 }
 
+// depositLogs queries the deposit events from startBlock up to endBlock
+func (l *listener) depositLogs(startBlock, endBlock *big.Int) ([]types.Log, error) {
+	l.log.Debug("Querying blocks for deposit events", "from", startBlock, "to", endBlock)
+
+	query := buildQuery(l.cfg.bridgeContract, utils.Deposit, startBlock, endBlock)
+
+	// Query for logs
+	logs, err := l.client.FilterLogs(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("unable to Filter Logs: %w", err)
+	}
+	return logs, nil
+}
+
+// depositMessage constructs the message of a deposit event, nil if its handler is not recognized
+func (l *listener) depositMessage(log types.Log) (*msg.Message, error) {
+	var m msg.Message
+	fmt.Printf("loop logs get %s\n", log.Topics[0])
+	dest := log.Data[:32]
+	destBig := new(big.Int).SetBytes(dest)
+	destId := msg.ChainId(destBig.Uint64())
+	rId := msg.ResourceIdFromSlice(log.Data[32:64])
+	nc := log.Data[64:96]
+	ncBig := new(big.Int).SetBytes(nc)
+	nonce := msg.Nonce(ncBig.Uint64())
+
+	l.log.Info("Parse event successfully.", "DestId", dest, "ResourceId", rId, "Nonce", nonce, "block", log.BlockNumber)
+	addr, err := l.bridgeContract.ResourceIDToHandlerAddress(&bind.CallOpts{From: l.conn.Keypair().CommonAddress()}, rId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get handler from resource ID %x", rId)
+	}
+
+	// The handlers that are not configured are the zero address, as the handler of an unknown resource
+	if addr == utils.ZeroAddress {
+		l.log.Error("event has no handler", "ResourceId", rId)
+		return nil, nil
+	} else if addr == l.cfg.erc20HandlerContract {
+		m, err = l.handleErc20DepositedEvent(destId, nonce)
+	} else if addr == l.cfg.erc721HandlerContract {
+		m, err = l.handleErc721DepositedEvent(destId, nonce)
+	} else if addr == l.cfg.genericHandlerContract {
+		m, err = l.handleGenericDepositedEvent(destId, nonce)
+	} else {
+		l.log.Error("event has unrecognized handler", "handler", addr.Hex())
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// removeLog drops a log removed by a reorg from the pending logs
+func removeLog(pending []types.Log, removed types.Log) []types.Log {
+	res := pending[:0]
+	for _, log := range pending {
+		if log.TxHash != removed.TxHash || log.Index != removed.Index {
+			res = append(res, log)
+		}
+	}
+	return res
+}
+
+func minBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) < 0 {
+		return a
+	}
+	return b
+}
+
+// requeue routes the deposit of the nonce to dest again. The nonces of a dest are shared by the handlers, the
+// deposit is the record of the handler its resource is mapped to, as for the deposit events.
+func (l *listener) requeue(destId msg.ChainId, nonce msg.Nonce) error {
+	handlers := []struct {
+		addr   ethcommon.Address
+		handle func(msg.ChainId, msg.Nonce) (msg.Message, error)
+	}{
+		{l.cfg.erc20HandlerContract, l.handleErc20DepositedEvent},
+		{l.cfg.erc721HandlerContract, l.handleErc721DepositedEvent},
+		{l.cfg.genericHandlerContract, l.handleGenericDepositedEvent},
+	}
+	for _, h := range handlers {
+		if h.addr == utils.ZeroAddress {
+			continue
+		}
+		m, err := h.handle(destId, nonce)
+		if err != nil {
+			return err
+		}
+		if m.ResourceId == [32]byte{} {
+			continue
+		}
+		addr, err := l.bridgeContract.ResourceIDToHandlerAddress(&bind.CallOpts{From: l.conn.Keypair().CommonAddress()}, m.ResourceId)
+		if err != nil {
+			return fmt.Errorf("failed to get handler from resource ID %x", m.ResourceId)
+		}
+		if addr != h.addr {
+			continue
+		}
+		l.log.Info("Requeue a deposit", "dest", destId, "nonce", nonce, "type", m.Type)
+		return l.router.Send(m)
+	}
+	return fmt.Errorf("no deposit record for nonce %d to chain %d", nonce, destId)
+}
+
+// pause stops or resumes the polling of new blocks
+func (l *listener) pause(pause bool) {
+	var v int32
+	if pause {
+		v = 1
+	}
+	atomic.StoreInt32(&l.paused, v)
+	l.log.Warn("Listener pause changed", "paused", pause)
+}
+
+func (l *listener) isPaused() bool {
+	return atomic.LoadInt32(&l.paused) == 1
+}
+
 // buildQuery constructs a query for the bridgeContract by hashing sig to get the event topic
 func buildQuery(contract ethcommon.Address, sig utils.EventSig, startBlock *big.Int, endBlock *big.Int) eth.FilterQuery {
 	query := eth.FilterQuery{
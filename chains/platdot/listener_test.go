@@ -63,7 +63,7 @@ func createTestListener(t *testing.T, config *Config, contracts *utils.DeployedC
 
 	router := &MockRouter{msgs: make(chan msg.Message)}
 	listener := NewListener(conn, &newConfig, TestLogger, &blockstore.EmptyStore{}, stop, sysErr, nil)
-	listener.setContracts(bridgeContract, erc20HandlerContract)
+	listener.setContracts(bridgeContract, erc20HandlerContract, nil, nil)
 	listener.setRouter(router)
 	// Start the listener
 	err = listener.start()
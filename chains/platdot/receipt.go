@@ -0,0 +1,267 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package platdot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ChainSafe/log15"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Time to wait for the receipt of a submitted tx
+var TxReceiptTimeout = time.Minute * 5
+
+// Time between queries of a missing receipt
+var TxReceiptInterval = time.Second * 5
+
+var ErrReceiptTimeout = errors.New("timed out waiting for the tx receipt")
+var ErrTxReplaced = errors.New("nonce of the tx used by another tx")
+
+// txOutcome is the result of a submitted tx once its receipt is checked
+type txOutcome int
+
+const (
+	// txSucceeded the tx was mined and succeeded
+	txSucceeded txOutcome = iota
+	// txComplete the tx reverted because the proposal already moved past the call, e.g. it was already voted on
+	txComplete
+	// txRetry the tx reverted for a reason submitting it again may fix
+	txRetry
+	// txUnconfirmed the receipt of the tx could not be found, e.g. another tx used its nonce
+	txUnconfirmed
+	// txFatal the tx reverted for a reason a retry does not fix, e.g. the relayer lost its role
+	txFatal
+)
+
+func (o txOutcome) String() string {
+	switch o {
+	case txSucceeded:
+		return "succeeded"
+	case txComplete:
+		return "complete"
+	case txRetry:
+		return "retry"
+	case txUnconfirmed:
+		return "unconfirmed"
+	case txFatal:
+		return "fatal"
+	}
+	return "unknown"
+}
+
+// Revert reasons of the bridge contract and the outcome they lead to
+var revertOutcomes = []struct {
+	reason  string
+	outcome txOutcome
+}{
+	{"already passed", txComplete},
+	{"already voted", txComplete},
+	{"already executed", txComplete},
+	{"already transferred", txComplete},
+	{"cancelled", txComplete},
+	{"expired", txComplete},
+	{"relayer role", txFatal},
+	{"not relayer", txFatal},
+	{"no handler", txFatal},
+	{"not mapped to handler", txFatal},
+	{"data doesn't match", txFatal},
+}
+
+// TxMetrics count the outcomes of the submitted vote and execute transactions
+type TxMetrics struct {
+	Outcomes         *prometheus.CounterVec
+	ConfirmationTime prometheus.Histogram
+}
+
+func NewTxMetrics(chain string) *TxMetrics {
+	metrics := &TxMetrics{
+		Outcomes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_tx_outcomes", chain),
+			Help: "Number of submitted transactions by method and outcome of their receipt",
+		}, []string{"method", "outcome"}),
+		ConfirmationTime: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    fmt.Sprintf("%s_tx_confirmation_seconds", chain),
+			Help:    "Time between the submission of a transaction and its receipt",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		}),
+	}
+
+	prometheus.MustRegister(metrics.Outcomes)
+	prometheus.MustRegister(metrics.ConfirmationTime)
+
+	return metrics
+}
+
+// ReceiptClient is the part of the client used to check the receipts
+type ReceiptClient interface {
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// TxClient is the part of the client used to follow a submitted tx until it is mined
+type TxClient interface {
+	ReceiptClient
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// waitReceipt polls the receipt of the tx until it is found or the timeout expires
+func waitReceipt(client ReceiptClient, hash common.Hash, timeout time.Duration, stop <-chan int) (*types.Receipt, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		receipt, err := client.TransactionReceipt(context.Background(), hash)
+		if err != nil && err != ethereum.NotFound {
+			return nil, err
+		} else if receipt != nil {
+			return receipt, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, ErrReceiptTimeout
+		}
+		select {
+		case <-stop:
+			return nil, errors.New("terminated")
+		case <-time.After(TxReceiptInterval):
+		}
+	}
+}
+
+// awaitReceipt waits for the receipt of tx. A tx without a receipt after the timeout keeps its nonce, it is
+// waited on again while pending and sent again if the node dropped it. ErrTxReplaced is returned once another
+// tx used its nonce.
+func awaitReceipt(client TxClient, tx *types.Transaction, timeout time.Duration, stop <-chan int, log log15.Logger) (*types.Receipt, error) {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		receipt, err := waitReceipt(client, tx.Hash(), timeout, stop)
+		if !errors.Is(err, ErrReceiptTimeout) {
+			return receipt, err
+		}
+
+		nonce, err := client.NonceAt(context.Background(), from, nil)
+		if err != nil {
+			log.Warn("Failed to get the account nonce, waiting for the tx", "tx", tx.Hash(), "err", err)
+			continue
+		}
+		if nonce > tx.Nonce() {
+			// The tx may have been mined since the last query of its receipt
+			receipt, err := client.TransactionReceipt(context.Background(), tx.Hash())
+			if err == nil && receipt != nil {
+				return receipt, nil
+			}
+			return nil, fmt.Errorf("%w: nonce %d", ErrTxReplaced, tx.Nonce())
+		}
+
+		_, pending, err := client.TransactionByHash(context.Background(), tx.Hash())
+		switch {
+		case err == ethereum.NotFound:
+			log.Warn("Tx dropped by the node, sending it again", "tx", tx.Hash(), "nonce", tx.Nonce())
+			if err := client.SendTransaction(context.Background(), tx); err != nil {
+				log.Warn("Failed to send the tx again", "tx", tx.Hash(), "err", err)
+			}
+		case err != nil:
+			log.Warn("Failed to get the tx, waiting for it", "tx", tx.Hash(), "err", err)
+		case pending:
+			log.Warn("Tx still pending, waiting for it", "tx", tx.Hash(), "nonce", tx.Nonce())
+		}
+	}
+}
+
+// revertReason replays the reverted tx in the state of its block and decodes the reason of the revert
+func revertReason(client ReceiptClient, tx *types.Transaction, receipt *types.Receipt) (string, error) {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return "", err
+	}
+	call := ethereum.CallMsg{
+		From:     from,
+		To:       tx.To(),
+		Gas:      tx.Gas(),
+		GasPrice: tx.GasPrice(),
+		Value:    tx.Value(),
+		Data:     tx.Data(),
+	}
+	_, err = client.CallContract(context.Background(), call, receipt.BlockNumber)
+	if err == nil {
+		return "", errors.New("tx does not revert when replayed")
+	}
+	return decodeRevert(err), nil
+}
+
+// decodeRevert returns the reason of the revert error returned by a call
+func decodeRevert(err error) string {
+	var dataErr interface{ ErrorData() interface{} }
+	if errors.As(err, &dataErr) {
+		if data, ok := dataErr.ErrorData().(string); ok {
+			if b, err := hexutil.Decode(data); err == nil {
+				if reason, err := abi.UnpackRevert(b); err == nil {
+					return reason
+				}
+			}
+		}
+	}
+	// Nodes without error data add the reason to the message
+	msg := err.Error()
+	if i := strings.Index(msg, "execution reverted: "); i >= 0 {
+		return msg[i+len("execution reverted: "):]
+	}
+	return msg
+}
+
+// classifyRevert returns the outcome of a tx reverted for reason
+func classifyRevert(reason string) txOutcome {
+	lower := strings.ToLower(reason)
+	for _, r := range revertOutcomes {
+		if strings.Contains(lower, r.reason) {
+			return r.outcome
+		}
+	}
+	return txRetry
+}
+
+// confirmTx waits for the receipt of a submitted tx and returns its outcome
+func (w *writer) confirmTx(method string, tx *types.Transaction) txOutcome {
+	submitted := time.Now()
+	outcome := txSucceeded
+	receipt, err := awaitReceipt(w.conn.Client(), tx, TxReceiptTimeout, w.stop, w.log)
+	switch {
+	case err != nil:
+		w.log.Warn("Failed to get tx receipt", "method", method, "tx", tx.Hash(), "err", err)
+		outcome = txUnconfirmed
+	case receipt.Status == types.ReceiptStatusSuccessful:
+		w.log.Debug("Tx succeeded", "method", method, "tx", tx.Hash(), "block", receipt.BlockNumber)
+	default:
+		reason, err := revertReason(w.conn.Client(), tx, receipt)
+		if err != nil {
+			w.log.Warn("Failed to get revert reason", "method", method, "tx", tx.Hash(), "err", err)
+			outcome = txRetry
+		} else {
+			outcome = classifyRevert(reason)
+		}
+		w.log.Warn("Tx reverted", "method", method, "tx", tx.Hash(), "block", receipt.BlockNumber, "reason", reason, "outcome", outcome)
+	}
+
+	if w.txMetrics != nil {
+		w.txMetrics.Outcomes.WithLabelValues(method, outcome.String()).Inc()
+		if err == nil {
+			w.txMetrics.ConfirmationTime.Observe(time.Since(submitted).Seconds())
+		}
+	}
+	return outcome
+}
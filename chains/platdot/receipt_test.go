@@ -0,0 +1,214 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package platdot
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/log15"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	bridge "github.com/rjman-self/Platdot/bindings/Bridge"
+	ethtest "github.com/rjman-self/Platdot/shared/platdot/testing"
+)
+
+// newSimulatedBridge deploys a bridge with the key as its only relayer and a threshold of 1
+func newSimulatedBridge(t *testing.T) (*backends.SimulatedBackend, *bridge.Bridge, *ecdsa.PrivateKey, [32]byte) {
+	key, err := ethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := ethcrypto.PubkeyToAddress(key.PublicKey)
+	alloc := core.GenesisAlloc{from: {Balance: new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)}}
+	backend := backends.NewSimulatedBackend(alloc, 100000000)
+
+	bridgeAddr, rId := ethtest.DeploySimulatedBridge(t, backend, key)
+	b, err := bridge.NewBridge(bridgeAddr, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backend, b, key, rId
+}
+
+// newSimulatedOpts returns opts with a fixed gas limit, so reverting calls are sent instead of failing the estimation
+func newSimulatedOpts(t *testing.T, key *ecdsa.PrivateKey) *bind.TransactOpts {
+	opts, err := bind.NewKeyedTransactorWithChainID(key, ethtest.SimulatedChainId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.GasLimit = 1000000
+	return opts
+}
+
+// voteOutcome mines a vote and returns the outcome of its receipt
+func voteOutcome(t *testing.T, backend *backends.SimulatedBackend, b *bridge.Bridge, key *ecdsa.PrivateKey, rId [32]byte) txOutcome {
+	tx, err := b.VoteProposal(newSimulatedOpts(t, key), 1, 1, rId, [32]byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+
+	receipt, err := waitReceipt(backend, tx.Hash(), 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status == types.ReceiptStatusSuccessful {
+		return txSucceeded
+	}
+	reason, err := revertReason(backend, tx, receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return classifyRevert(reason)
+}
+
+func TestReceipt_Outcomes(t *testing.T) {
+	backend, b, key, rId := newSimulatedBridge(t)
+
+	if res := voteOutcome(t, backend, b, key, rId); res != txSucceeded {
+		t.Fatalf("Got: %s Expected: %s", res, txSucceeded)
+	}
+	// The proposal passed with the first vote
+	if res := voteOutcome(t, backend, b, key, rId); res != txComplete {
+		t.Fatalf("Got: %s Expected: %s", res, txComplete)
+	}
+	// No handler is set for the resource id
+	if res := voteOutcome(t, backend, b, key, [32]byte{2}); res != txFatal {
+		t.Fatalf("Got: %s Expected: %s", res, txFatal)
+	}
+}
+
+func TestWaitReceipt_Timeout(t *testing.T) {
+	backend, _, _, _ := newSimulatedBridge(t)
+	interval := TxReceiptInterval
+	TxReceiptInterval = time.Millisecond
+	defer func() { TxReceiptInterval = interval }()
+
+	_, err := waitReceipt(backend, common.Hash{1}, time.Millisecond*10, nil)
+	if !errors.Is(err, ErrReceiptTimeout) {
+		t.Fatalf("Got: %v Expected: %v", err, ErrReceiptTimeout)
+	}
+}
+
+// signedTransfer returns a signed transfer of value from the key with the nonce, it is not sent
+func signedTransfer(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, value int64) *types.Transaction {
+	tx := types.NewTransaction(nonce, common.Address{1}, big.NewInt(value), 21000, big.NewInt(1000000000), nil)
+	signed, err := types.SignTx(tx, types.LatestSignerForChainID(ethtest.SimulatedChainId), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestAwaitReceipt_Pending(t *testing.T) {
+	backend, _, key, _ := newSimulatedBridge(t)
+	interval := TxReceiptInterval
+	TxReceiptInterval = time.Millisecond
+	defer func() { TxReceiptInterval = interval }()
+
+	nonce, err := backend.PendingNonceAt(context.Background(), ethcrypto.PubkeyToAddress(key.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := signedTransfer(t, key, nonce, 1)
+	err = backend.SendTransaction(context.Background(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The tx is mined after several timeouts
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		backend.Commit()
+	}()
+
+	receipt, err := awaitReceipt(backend, tx, time.Millisecond*5, nil, log15.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.TxHash != tx.Hash() {
+		t.Fatalf("Got: %s Expected: %s", receipt.TxHash, tx.Hash())
+	}
+}
+
+func TestAwaitReceipt_Replaced(t *testing.T) {
+	backend, _, key, _ := newSimulatedBridge(t)
+	interval := TxReceiptInterval
+	TxReceiptInterval = time.Millisecond
+	defer func() { TxReceiptInterval = interval }()
+
+	nonce, err := backend.PendingNonceAt(context.Background(), ethcrypto.PubkeyToAddress(key.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := signedTransfer(t, key, nonce, 1)
+	// Another tx uses the nonce
+	err = backend.SendTransaction(context.Background(), signedTransfer(t, key, nonce, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+
+	_, err = awaitReceipt(backend, tx, time.Millisecond*5, nil, log15.New())
+	if !errors.Is(err, ErrTxReplaced) {
+		t.Fatalf("Got: %v Expected: %v", err, ErrTxReplaced)
+	}
+}
+
+// dataError is a call error carrying the revert data, as returned by the rpc client
+type dataError string
+
+func (e dataError) Error() string          { return "execution reverted" }
+func (e dataError) ErrorData() interface{} { return string(e) }
+
+func TestDecodeRevert(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected string
+	}{
+		// Error(string) with the reason "relayer already voted"
+		{dataError("0x08c379a0" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"0000000000000000000000000000000000000000000000000000000000000015" +
+			"72656c6179657220616c726561647920766f7465640000000000000000000000"), "relayer already voted"},
+		{errors.New("execution reverted: Proposal must have Passed status"), "Proposal must have Passed status"},
+		{errors.New("out of gas"), "out of gas"},
+	}
+
+	for _, tc := range testCases {
+		if res := decodeRevert(tc.err); res != tc.expected {
+			t.Fatalf("Got: %s Expected: %s", res, tc.expected)
+		}
+	}
+}
+
+func TestClassifyRevert(t *testing.T) {
+	testCases := []struct {
+		reason   string
+		expected txOutcome
+	}{
+		{"proposal already passed/executed/cancelled", txComplete},
+		{"relayer already voted", txComplete},
+		{"proposal already cancelled", txComplete},
+		{"Proposal expired", txComplete},
+		{"sender doesn't have relayer role", txFatal},
+		{"no handler for resourceID", txFatal},
+		{"Proposal must have Passed status", txRetry},
+		{"Pausable: paused", txRetry},
+	}
+
+	for _, tc := range testCases {
+		if res := classifyRevert(tc.reason); res != tc.expected {
+			t.Fatalf("%s. Got: %s Expected: %s", tc.reason, res, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package platdot
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/rjman-self/Platdot/bindings/Bridge"
+	"github.com/rjman-self/Platdot/chains"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+var _ chains.RelayerRole = &Chain{}
+var _ chains.HandlerResolver = &Chain{}
+
+// ResourceHandler returns the handler the bridge maps the resource to
+func (c *Chain) ResourceHandler(id msg.ResourceId) (common.Address, error) {
+	return c.writer.bridgeContract.ResourceIDToHandlerAddress(c.conn.CallOpts(), id)
+}
+
+// RoleRelayers returns the members of the RELAYER_ROLE of the bridge and its relayer threshold
+func (c *Chain) RoleRelayers() ([]common.Address, uint8, error) {
+	bridge := c.writer.bridgeContract
+	opts := c.conn.CallOpts()
+	role, err := bridge.RELAYERROLE(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	count, err := bridge.GetRoleMemberCount(opts, role)
+	if err != nil {
+		return nil, 0, err
+	}
+	relayers := make([]common.Address, 0, count.Int64())
+	for i := int64(0); i < count.Int64(); i++ {
+		relayer, err := bridge.GetRoleMember(opts, role, big.NewInt(i))
+		if err != nil {
+			return nil, 0, err
+		}
+		relayers = append(relayers, relayer)
+	}
+	threshold, err := bridge.RelayerThreshold(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return relayers, threshold, nil
+}
+
+// WatchRelayers subscribes to the RelayerAdded, RelayerRemoved and RelayerThresholdChanged events of the bridge
+// and sends to changed on each of them until the chain is stopped. An error is returned if the subscriptions can
+// not be made, once made they are made again when they fail.
+func (c *Chain) WatchRelayers(changed chan<- struct{}) error {
+	w, err := newRelayerWatch(c.writer.bridgeContract)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-c.listener.stop:
+				w.unsubscribe()
+				return
+			case err := <-w.err:
+				c.listener.log.Warn("Relayer subscription failed, subscribing again", "err", err)
+				w.unsubscribe()
+				for {
+					w, err = newRelayerWatch(c.writer.bridgeContract)
+					if err == nil {
+						break
+					}
+					c.listener.log.Warn("Unable to subscribe to the relayers of the bridge", "err", err)
+					select {
+					case <-c.listener.stop:
+						return
+					case <-time.After(BlockRetryInterval):
+					}
+				}
+				// Relayers may have changed while the subscriptions were down
+				changed <- struct{}{}
+			case evt := <-w.added:
+				c.listener.log.Info("Relayer added to the bridge", "Relayer", evt.Relayer.Hex(), "Block", evt.Raw.BlockNumber)
+				changed <- struct{}{}
+			case evt := <-w.removed:
+				c.listener.log.Info("Relayer removed from the bridge", "Relayer", evt.Relayer.Hex(), "Block", evt.Raw.BlockNumber)
+				changed <- struct{}{}
+			case evt := <-w.threshold:
+				c.listener.log.Info("Relayer threshold of the bridge changed", "Threshold", evt.NewThreshold, "Block", evt.Raw.BlockNumber)
+				changed <- struct{}{}
+			}
+		}
+	}()
+	return nil
+}
+
+// relayerWatch holds the subscriptions to the relayer events of the bridge
+type relayerWatch struct {
+	subs      []event.Subscription
+	err       chan error
+	added     chan *Bridge.BridgeRelayerAdded
+	removed   chan *Bridge.BridgeRelayerRemoved
+	threshold chan *Bridge.BridgeRelayerThresholdChanged
+}
+
+func newRelayerWatch(bridge *Bridge.Bridge) (*relayerWatch, error) {
+	w := &relayerWatch{
+		err:       make(chan error, 3),
+		added:     make(chan *Bridge.BridgeRelayerAdded),
+		removed:   make(chan *Bridge.BridgeRelayerRemoved),
+		threshold: make(chan *Bridge.BridgeRelayerThresholdChanged),
+	}
+	opts := &bind.WatchOpts{}
+	sub, err := bridge.WatchRelayerAdded(opts, w.added)
+	if err != nil {
+		return nil, err
+	}
+	w.subs = append(w.subs, sub)
+	sub, err = bridge.WatchRelayerRemoved(opts, w.removed)
+	if err != nil {
+		w.unsubscribe()
+		return nil, err
+	}
+	w.subs = append(w.subs, sub)
+	sub, err = bridge.WatchRelayerThresholdChanged(opts, w.threshold)
+	if err != nil {
+		w.unsubscribe()
+		return nil, err
+	}
+	w.subs = append(w.subs, sub)
+
+	for _, sub := range w.subs {
+		go func(sub event.Subscription) {
+			if err, ok := <-sub.Err(); ok {
+				w.err <- err
+			}
+		}(sub)
+	}
+	return w, nil
+}
+
+func (w *relayerWatch) unsubscribe() {
+	for _, sub := range w.subs {
+		sub.Unsubscribe()
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package platdot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+// Number of processed blocks whose hash is kept to find the common ancestor of a reorg
+const MaxTrackedBlocks = 256
+
+var ErrReorgTooDeep = errors.New("reorg deeper than the tracked blocks")
+
+// HeaderReader returns the headers of the canonical chain
+type HeaderReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// trackedBlock is a processed block with the messages routed for its deposits
+type trackedBlock struct {
+	number   uint64
+	hash     common.Hash
+	messages []msg.Message
+}
+
+// orphan is a routed message whose deposit was in a block dropped by a reorg
+type orphan struct {
+	message msg.Message
+	tip     uint64 // Highest block processed before the reorg
+}
+
+// reorgDetector records the hash of the processed blocks. When the parent of a new block is not the
+// recorded block it finds the common ancestor, and keeps the messages of the dropped blocks until they
+// are either routed again or have to be retracted.
+type reorgDetector struct {
+	client  HeaderReader
+	blocks  []trackedBlock
+	orphans []orphan
+}
+
+func newReorgDetector(client HeaderReader) *reorgDetector {
+	return &reorgDetector{client: client}
+}
+
+// track records the hash of a processed block and the messages routed for its deposits. A block
+// processed again after a failed attempt keeps its messages once.
+func (d *reorgDetector) track(number uint64, hash common.Hash, messages ...msg.Message) {
+	i := sort.Search(len(d.blocks), func(i int) bool { return d.blocks[i].number >= number })
+	if i == len(d.blocks) || d.blocks[i].number != number {
+		d.blocks = append(d.blocks, trackedBlock{})
+		copy(d.blocks[i+1:], d.blocks[i:])
+		d.blocks[i] = trackedBlock{number: number}
+	}
+
+	b := &d.blocks[i]
+	b.hash = hash
+	for _, m := range messages {
+		if !containsMessage(b.messages, m) {
+			b.messages = append(b.messages, m)
+		}
+	}
+
+	if len(d.blocks) > MaxTrackedBlocks {
+		d.blocks = append([]trackedBlock{}, d.blocks[len(d.blocks)-MaxTrackedBlocks:]...)
+	}
+}
+
+// check compares the parent hash of the block with the hash recorded for the previous block. On a reorg the
+// blocks after the common ancestor are dropped and the ancestor is returned with reorg set.
+func (d *reorgDetector) check(number uint64) (ancestor uint64, reorg bool, err error) {
+	if len(d.blocks) == 0 || number == 0 {
+		return 0, false, nil
+	}
+	last := d.blocks[len(d.blocks)-1]
+	// Only a block following the tracked ones can be checked, e.g. not the first one after a replay
+	if last.number+1 != number {
+		return 0, false, nil
+	}
+
+	header, err := d.client.HeaderByNumber(context.Background(), new(big.Int).SetUint64(number))
+	if err != nil {
+		return 0, false, err
+	}
+	if header.ParentHash == last.hash {
+		return 0, false, nil
+	}
+
+	ancestor, err = d.findAncestor()
+	if err != nil {
+		return 0, true, err
+	}
+	d.rollback(ancestor)
+	return ancestor, true, nil
+}
+
+// findAncestor returns the highest tracked block that is still in the canonical chain
+func (d *reorgDetector) findAncestor() (uint64, error) {
+	for i := len(d.blocks) - 1; i >= 0; i-- {
+		header, err := d.client.HeaderByNumber(context.Background(), new(big.Int).SetUint64(d.blocks[i].number))
+		if err != nil {
+			return 0, err
+		}
+		if header.Hash() == d.blocks[i].hash {
+			return d.blocks[i].number, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: no common ancestor after block %d", ErrReorgTooDeep, d.blocks[0].number)
+}
+
+// rollback drops the blocks after ancestor, their messages become orphans
+func (d *reorgDetector) rollback(ancestor uint64) {
+	tip := d.blocks[len(d.blocks)-1].number
+	i := len(d.blocks)
+	for i > 0 && d.blocks[i-1].number > ancestor {
+		i--
+	}
+	for _, b := range d.blocks[i:] {
+		for _, m := range b.messages {
+			d.orphans = append(d.orphans, orphan{message: m, tip: tip})
+		}
+	}
+	d.blocks = d.blocks[:i]
+}
+
+// rerouted removes the orphan identical to m and returns true if there was one, m has
+// already been routed and is still valid
+func (d *reorgDetector) rerouted(m msg.Message) bool {
+	for i, o := range d.orphans {
+		if reflect.DeepEqual(o.message, m) {
+			d.orphans = append(d.orphans[:i], d.orphans[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// retractable returns the orphans that were not routed again once the blocks up to their tip are processed
+func (d *reorgDetector) retractable(processed uint64) []msg.Message {
+	var res []msg.Message
+	rest := d.orphans[:0]
+	for _, o := range d.orphans {
+		if o.tip <= processed {
+			res = append(res, o.message)
+		} else {
+			rest = append(rest, o)
+		}
+	}
+	d.orphans = rest
+	return res
+}
+
+func containsMessage(messages []msg.Message, m msg.Message) bool {
+	for _, e := range messages {
+		if reflect.DeepEqual(e, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,326 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package platdot
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	bridge "github.com/rjman-self/Platdot/bindings/Bridge"
+	erc20Handler "github.com/rjman-self/Platdot/bindings/ERC20Handler"
+	"github.com/rjman-self/Platdot/bindings/ERC20PresetMinterPauser"
+	"github.com/rjman-self/Platdot/chains"
+	utils "github.com/rjman-self/Platdot/shared/platdot"
+	ethtest "github.com/rjman-self/Platdot/shared/platdot/testing"
+	"github.com/rjman-self/platdot-utils/blockstore"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+// switchReader serves the headers of the backend the test points it to
+type switchReader struct {
+	backend *backends.SimulatedBackend
+}
+
+func (r *switchReader) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return r.backend.HeaderByNumber(ctx, number)
+}
+
+func headerHash(t *testing.T, backend *backends.SimulatedBackend, number uint64) common.Hash {
+	header, err := backend.HeaderByNumber(context.Background(), new(big.Int).SetUint64(number))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return header.Hash()
+}
+
+// newForkedBackends creates two chains sharing the blocks up to common, the first one has a
+// transaction in the next block and both are extended up to their length. The transactions of send
+// are made in the next block too, with a key funded on both chains.
+func newForkedBackends(t *testing.T, common, length uint64, send ...func(backend *backends.SimulatedBackend, key *ecdsa.PrivateKey, canonical bool)) (*backends.SimulatedBackend, *backends.SimulatedBackend) {
+	key, err := ethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := ethcrypto.PubkeyToAddress(key.PublicKey)
+	alloc := core.GenesisAlloc{from: {Balance: new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)}}
+	canonical := backends.NewSimulatedBackend(alloc, 100000000)
+	fork := backends.NewSimulatedBackend(alloc, 100000000)
+
+	for i := uint64(0); i < common; i++ {
+		canonical.Commit()
+		fork.Commit()
+	}
+
+	for _, f := range send {
+		f(canonical, key, true)
+		f(fork, key, false)
+	}
+	nonce, err := canonical.PendingNonceAt(context.Background(), from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := types.NewTransaction(nonce, from, big.NewInt(1), 21000, big.NewInt(1), nil)
+	signed, err := types.SignTx(tx, types.HomesteadSigner{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = canonical.SendTransaction(context.Background(), signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := common; i < length; i++ {
+		canonical.Commit()
+		fork.Commit()
+	}
+
+	if headerHash(t, canonical, common) != headerHash(t, fork, common) {
+		t.Fatalf("Chains should share block %d", common)
+	}
+	if headerHash(t, canonical, common+1) == headerHash(t, fork, common+1) {
+		t.Fatalf("Chains should differ at block %d", common+1)
+	}
+	return canonical, fork
+}
+
+func TestReorgDetector(t *testing.T) {
+	canonical, fork := newForkedBackends(t, 2, 5)
+	reader := &switchReader{backend: canonical}
+	d := newReorgDetector(reader)
+
+	orphaned := msg.NewFungibleTransfer(1, 2, 3, big.NewInt(10), msg.ResourceId{1}, []byte("recipient"))
+	kept := msg.NewFungibleTransfer(1, 2, 1, big.NewInt(10), msg.ResourceId{1}, []byte("recipient"))
+
+	// Process blocks 1 to 4 of the canonical chain, with deposits in blocks 2 and 3
+	for number := uint64(1); number <= 4; number++ {
+		_, reorg, err := d.check(number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reorg {
+			t.Fatalf("Unexpected reorg at block %d", number)
+		}
+		d.track(number, headerHash(t, canonical, number))
+	}
+	d.track(2, headerHash(t, canonical, 2), kept)
+	d.track(3, headerHash(t, canonical, 3), orphaned)
+	d.track(3, headerHash(t, canonical, 3), orphaned)
+
+	// The fork replaces the blocks after 2
+	reader.backend = fork
+	ancestor, reorg, err := d.check(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reorg {
+		t.Fatal("Reorg not detected")
+	}
+	if ancestor != 2 {
+		t.Fatalf("Got: %d Expected: %d", ancestor, 2)
+	}
+
+	// The block after the ancestor extends the tracked blocks again
+	_, reorg, err = d.check(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reorg {
+		t.Fatal("Unexpected reorg after the rollback")
+	}
+
+	if d.rerouted(kept) {
+		t.Fatal("Message of the ancestor should not be an orphan")
+	}
+	if res := d.retractable(3); len(res) != 0 {
+		t.Fatalf("Got: %d Expected: %d", len(res), 0)
+	}
+	if res := d.retractable(4); !reflect.DeepEqual(res, []msg.Message{orphaned}) {
+		t.Fatalf("Got: %v Expected: %v", res, []msg.Message{orphaned})
+	}
+}
+
+func TestReorgDetector_Rerouted(t *testing.T) {
+	canonical, fork := newForkedBackends(t, 1, 3)
+	reader := &switchReader{backend: canonical}
+	d := newReorgDetector(reader)
+
+	m := msg.NewFungibleTransfer(1, 2, 3, big.NewInt(10), msg.ResourceId{1}, []byte("recipient"))
+	for number := uint64(1); number <= 3; number++ {
+		d.track(number, headerHash(t, canonical, number))
+	}
+	d.track(2, headerHash(t, canonical, 2), m)
+
+	reader.backend = fork
+	fork.Commit()
+	_, reorg, err := d.check(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reorg {
+		t.Fatal("Reorg not detected")
+	}
+
+	// The deposit is found again in the new chain, it is not retracted
+	if !d.rerouted(m) {
+		t.Fatal("Orphaned message should be rerouted")
+	}
+	if res := d.retractable(3); len(res) != 0 {
+		t.Fatalf("Got: %d Expected: %d", len(res), 0)
+	}
+}
+
+func TestReorgDetector_TooDeep(t *testing.T) {
+	canonical, fork := newForkedBackends(t, 0, 2)
+	reader := &switchReader{backend: canonical}
+	d := newReorgDetector(reader)
+	for number := uint64(1); number <= 2; number++ {
+		d.track(number, headerHash(t, canonical, number))
+	}
+
+	reader.backend = fork
+	fork.Commit()
+	_, _, err := d.check(3)
+	if !errors.Is(err, ErrReorgTooDeep) {
+		t.Fatalf("Got: %v Expected: %v", err, ErrReorgTooDeep)
+	}
+}
+
+// switchBackend serves the chain the test points it to, to the listener and to its contracts
+type switchBackend struct {
+	*backends.SimulatedBackend
+}
+
+// sendErc20Deposits deploys a bridge with an ERC20 handler and deposits to chain 1, the canonical chain
+// has another deposit to chain 2. The contracts have the same addresses on both chains.
+func sendErc20Deposits(t *testing.T, backend *backends.SimulatedBackend, key *ecdsa.PrivateKey, canonical bool) (common.Address, common.Address, msg.ResourceId) {
+	from := ethcrypto.PubkeyToAddress(key.PublicKey)
+	opts, err := bind.NewKeyedTransactorWithChainID(key, ethtest.SimulatedChainId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bridgeAddr, _, b, err := bridge.DeployBridge(opts, backend, 0, []common.Address{from}, big.NewInt(1), big.NewInt(0), big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlerAddr, _, _, err := erc20Handler.DeployERC20Handler(opts, backend, bridgeAddr, [][32]byte{}, []common.Address{}, []common.Address{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenAddr, _, token, err := ERC20PresetMinterPauser.DeployERC20PresetMinterPauser(opts, backend, "token", "TKN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rId := msg.ResourceIdFromSlice(append(common.LeftPadBytes(tokenAddr.Bytes(), 31), 0))
+	_, err = b.AdminSetResource(opts, handlerAddr, rId, tokenAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = token.Mint(opts, from, big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = token.Approve(opts, handlerAddr, big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := utils.ConstructErc20DepositData(BobKp.CommonAddress().Bytes(), big.NewInt(10))
+	_, err = b.Deposit(opts, 1, rId, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canonical {
+		_, err = b.Deposit(opts, 2, rId, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return bridgeAddr, handlerAddr, rId
+}
+
+func TestListener_RetractOrphans(t *testing.T) {
+	var bridgeAddr, handlerAddr common.Address
+	var rId msg.ResourceId
+	canonical, fork := newForkedBackends(t, 2, 4, func(backend *backends.SimulatedBackend, key *ecdsa.PrivateKey, canonical bool) {
+		bridgeAddr, handlerAddr, rId = sendErc20Deposits(t, backend, key, canonical)
+	})
+
+	backend := &switchBackend{SimulatedBackend: canonical}
+	b, err := bridge.NewBridge(bridgeAddr, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := erc20Handler.NewERC20Handler(handlerAddr, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := *aliceTestConfig
+	cfg.bridgeContract = bridgeAddr
+	cfg.erc20HandlerContract = handlerAddr
+	cfg.maxBlockRange = big.NewInt(2)
+	router := &MockRouter{msgs: make(chan msg.Message, 4)}
+	l := &listener{
+		cfg:        cfg,
+		conn:       &keypairConnection{kp: AliceKp},
+		client:     backend,
+		log:        TestLogger,
+		blockstore: &blockstore.EmptyStore{},
+		reorg:      newReorgDetector(backend),
+	}
+	l.setContracts(b, handler, nil, nil)
+	l.setRouter(router)
+
+	rerouted := msg.NewFungibleTransfer(0, 1, 1, big.NewInt(10), rId, BobKp.CommonAddress().Bytes())
+	orphaned := msg.NewFungibleTransfer(0, 2, 1, big.NewInt(10), rId, BobKp.CommonAddress().Bytes())
+
+	// Process blocks 1 to 4 of the canonical chain, with both deposits in block 3
+	next := big.NewInt(1)
+	for next.Uint64() <= 4 {
+		next, err = l.processRange(next, big.NewInt(4))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, expected := range []msg.Message{rerouted, orphaned} {
+		if m := <-router.msgs; !reflect.DeepEqual(m, expected) {
+			t.Fatalf("Got: %v Expected: %v", m, expected)
+		}
+	}
+
+	// The fork replaces the blocks after 2, its block 3 only has the deposit to chain 1
+	backend.SimulatedBackend = fork
+	fork.Commit()
+	next, err = l.processRange(next, big.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Uint64() != 3 {
+		t.Fatalf("Got: %d Expected: %d", next, 3)
+	}
+	for next.Uint64() <= 5 {
+		next, err = l.processRange(next, big.NewInt(5))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the deposit that is not in the fork is retracted
+	if len(router.msgs) != 1 {
+		t.Fatalf("Got: %d Expected: %d", len(router.msgs), 1)
+	}
+	expected := chains.NewRetraction(orphaned)
+	if m := <-router.msgs; !reflect.DeepEqual(m, expected) {
+		t.Fatalf("Got: %v Expected: %v", m, expected)
+	}
+}
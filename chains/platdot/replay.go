@@ -0,0 +1,65 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package platdot
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rjman-self/Platdot/chains"
+	utils "github.com/rjman-self/Platdot/shared/platdot"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+var _ chains.Replayer = &Chain{}
+
+// collector is a router keeping the messages instead of delivering them
+type collector struct {
+	messages []msg.Message
+}
+
+func (c *collector) Send(m msg.Message) error {
+	c.messages = append(c.messages, m)
+	return nil
+}
+
+// Replay runs the deposit event handling of the listener for block. It must not be called once the chain is started.
+func (c *Chain) Replay(block uint64) ([]msg.Message, error) {
+	router := c.listener.router
+	defer func() { c.listener.router = router }()
+
+	col := &collector{}
+	c.listener.router = col
+	err := c.listener.getDepositEventsForBlock(new(big.Int).SetUint64(block))
+	if err != nil {
+		return nil, err
+	}
+	return col.messages, nil
+}
+
+// ProposalHash is the data hash of the bridge proposal the writer votes on for the message
+func (c *Chain) ProposalHash(m msg.Message) ([32]byte, error) {
+	switch m.Type {
+	case msg.FungibleTransfer:
+		recipient, err := common.PlatonToEth(string(m.Payload[1].([]byte)))
+		if err != nil {
+			return [32]byte{}, fmt.Errorf("invalid recipient: %w", err)
+		}
+		data := ConstructErc20ProposalData(m.Payload[0].([]byte), recipient)
+		return utils.Hash(append(c.writer.cfg.erc20HandlerContract.Bytes(), data...)), nil
+	case msg.NonFungibleTransfer:
+		recipient, err := common.PlatonToEth(string(m.Payload[1].([]byte)))
+		if err != nil {
+			return [32]byte{}, fmt.Errorf("invalid recipient: %w", err)
+		}
+		data := ConstructErc721ProposalData(m.Payload[0].([]byte), recipient, m.Payload[2].([]byte))
+		return utils.Hash(append(c.writer.cfg.erc721HandlerContract.Bytes(), data...)), nil
+	case msg.GenericTransfer:
+		data := ConstructGenericProposalData(m.Payload[0].([]byte))
+		return utils.Hash(append(c.writer.cfg.genericHandlerContract.Bytes(), data...)), nil
+	default:
+		return [32]byte{}, fmt.Errorf("unknown message type %s", m.Type)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package platdot
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rjman-self/Platdot/bindings/Bridge"
+	utils "github.com/rjman-self/Platdot/shared/platdot"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+var proposalStatusNames = []string{"Inactive", "Active", "Passed", "Transferred", "Cancelled"}
+
+// ProposalStatusName returns the name of a bridge proposal status
+func ProposalStatusName(status uint8) string {
+	if int(status) < len(proposalStatusNames) {
+		return proposalStatusNames[status]
+	}
+	return "Unknown"
+}
+
+// ProposalInfo is the current state of a bridge proposal
+type ProposalInfo struct {
+	Source        msg.ChainId
+	DepositNonce  msg.Nonce
+	DataHash      [32]byte
+	Status        uint8
+	YesVotes      uint8
+	ProposedBlock *big.Int
+	HasVoted      bool
+	Block         uint64
+	TxHash        common.Hash
+}
+
+// QueryProposals returns the proposals with a ProposalEvent between the from and to blocks (inclusive),
+// with their current status on the bridge and whether the relayer has voted for them.
+func QueryProposals(backend bind.ContractBackend, bridgeAddress common.Address, relayer common.Address, from, to uint64) ([]ProposalInfo, error) {
+	bridge, err := Bridge.NewBridge(bridgeAddress, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := bridge.FilterProposalEvent(&bind.FilterOpts{Start: from, End: &to})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	type proposalKey struct {
+		source   uint8
+		nonce    uint64
+		dataHash [32]byte
+	}
+
+	var res []ProposalInfo
+	// A proposal emits an event for each status change, it is listed once
+	seen := make(map[proposalKey]bool)
+	opts := &bind.CallOpts{From: relayer}
+	for iter.Next() {
+		evt := iter.Event
+		key := proposalKey{evt.OriginChainID, evt.DepositNonce, evt.DataHash}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		prop, err := bridge.GetProposal(opts, evt.OriginChainID, evt.DepositNonce, evt.DataHash)
+		if err != nil {
+			return nil, err
+		}
+		voted, err := bridge.HasVotedOnProposal(opts, utils.IDAndNonce(msg.ChainId(evt.OriginChainID), msg.Nonce(evt.DepositNonce)), evt.DataHash, relayer)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, ProposalInfo{
+			Source:        msg.ChainId(evt.OriginChainID),
+			DepositNonce:  msg.Nonce(evt.DepositNonce),
+			DataHash:      evt.DataHash,
+			Status:        prop.Status,
+			YesVotes:      prop.YesVotesTotal,
+			ProposedBlock: prop.ProposedBlock,
+			HasVoted:      voted,
+			Block:         evt.Raw.BlockNumber,
+			TxHash:        evt.Raw.TxHash,
+		})
+	}
+	return res, iter.Error()
+}
@@ -50,6 +50,8 @@ func createConfig(name string, startBlock *big.Int, contracts *utils.DeployedCon
 		http:                   false,
 		startBlock:             startBlock,
 		blockConfirmations:     big.NewInt(3),
+		listenerMode:           PollMode,
+		maxBlockRange:          big.NewInt(DefaultMaxBlockRange),
 	}
 
 	if contracts != nil {
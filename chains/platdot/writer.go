@@ -4,6 +4,10 @@
 package platdot
 
 import (
+	"sync"
+	"time"
+
+	"github.com/rjman-self/Platdot/chains"
 	"github.com/rjman-self/platdot-utils/core"
 	metrics "github.com/rjman-self/platdot-utils/metrics/types"
 	"github.com/rjman-self/platdot-utils/msg"
@@ -25,17 +29,42 @@ type writer struct {
 	stop           <-chan int
 	sysErr         chan<- error // Reports fatal error to core
 	metrics        *metrics.ChainMetrics
+	txMetrics      *TxMetrics
+	skips          *chains.SkipList
+	watchLock      sync.RWMutex
+	watching       map[watchKey]Execution
+}
+
+type watchKey struct {
+	source msg.ChainId
+	nonce  msg.Nonce
+}
+
+// Execution is a proposal the writer watches to execute once it is passed
+type Execution struct {
+	Source       msg.ChainId `json:"source"`
+	DepositNonce msg.Nonce   `json:"depositNonce"`
+	DataHash     string      `json:"dataHash"`
+	FromBlock    uint64      `json:"fromBlock"`
+	Started      time.Time   `json:"started"`
 }
 
 // NewWriter creates and returns writer
 func NewWriter(conn Connection, cfg *Config, log log15.Logger, stop <-chan int, sysErr chan<- error, m *metrics.ChainMetrics) *writer {
+	var txMetrics *TxMetrics
+	if m != nil {
+		txMetrics = NewTxMetrics(cfg.name)
+	}
 	return &writer{
-		cfg:     *cfg,
-		conn:    conn,
-		log:     log,
-		stop:    stop,
-		sysErr:  sysErr,
-		metrics: m,
+		cfg:       *cfg,
+		conn:      conn,
+		log:       log,
+		stop:      stop,
+		sysErr:    sysErr,
+		metrics:   m,
+		txMetrics: txMetrics,
+		skips:     chains.NewSkipList(),
+		watching:  make(map[watchKey]Execution),
 	}
 }
 
@@ -52,6 +81,10 @@ func (w *writer) setContract(bridge *Bridge.Bridge) {
 // ResolveMessage handles any given message based on type
 // A bool is returned to indicate failure/success, this should be ignored except for within tests.
 func (w *writer) ResolveMessage(m msg.Message) bool {
+	if w.skips.Has(m.Source, m.DepositNonce) {
+		w.log.Warn("Message is skipped", "src", m.Source, "nonce", m.DepositNonce)
+		return true
+	}
 	w.log.Info("Attempting to resolve message", "type", m.Type, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "rId", m.ResourceId.Hex(), "recipient", m.Payload[1])
 	switch m.Type {
 	case msg.FungibleTransfer:
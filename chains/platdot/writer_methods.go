@@ -11,6 +11,9 @@ import (
 	"time"
 
 	log "github.com/ChainSafe/log15"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	connection "github.com/rjman-self/Platdot/connections/platdot"
 	utils "github.com/rjman-self/Platdot/shared/platdot"
 	"github.com/rjman-self/platdot-utils/msg"
 )
@@ -24,8 +27,9 @@ const TxRetryInterval = time.Second * 2
 // Maximum number of tx retries before exiting
 const TxRetryLimit = 10
 
-var ErrNonceTooLow = errors.New("nonce too low")
-var ErrTxUnderpriced = errors.New("replacement transaction underpriced")
+var ErrNonceTooLow = connection.ErrNonceTooLow
+var ErrTxUnderpriced = connection.ErrTxUnderpriced
+var ErrTxNotMined = connection.ErrTxNotMined
 var ErrFatalTx = errors.New("submission of transaction failed")
 var ErrFatalQuery = errors.New("query of chain state failed")
 
@@ -129,7 +133,14 @@ func (w *writer) createErc20Proposal(m msg.Message) bool {
 func (w *writer) createErc721Proposal(m msg.Message) bool {
 	w.log.Info("Creating erc721 proposal", "src", m.Source, "nonce", m.DepositNonce)
 
-	data := ConstructErc721ProposalData(m.Payload[0].([]byte), m.Payload[1].([]byte), m.Payload[2].([]byte))
+	// The substrate listener carries the bech32 address of the recipient
+	recipient, err := common.PlatonToEth(string(m.Payload[1].([]byte)))
+	if err != nil {
+		w.log.Error("Invalid erc721 recipient", "src", m.Source, "nonce", m.DepositNonce, "err", err)
+		return false
+	}
+
+	data := ConstructErc721ProposalData(m.Payload[0].([]byte), recipient, m.Payload[2].([]byte))
 	dataHash := utils.Hash(append(w.cfg.erc721HandlerContract.Bytes(), data...))
 
 	if !w.shouldVote(m, dataHash) {
@@ -195,6 +206,8 @@ func (w *writer) createGenericDepositProposal(m msg.Message) bool {
 // watchThenExecute watches for the latest block and executes once the matching finalized event is found
 func (w *writer) watchThenExecute(m msg.Message, data []byte, dataHash [32]byte, latestBlock *big.Int) {
 	w.log.Info("Watching for finalization event", "src", m.Source, "nonce", m.DepositNonce)
+	w.startWatching(m, dataHash, latestBlock)
+	defer w.stopWatching(m)
 
 	// Watching for the latest block, querying and matching the finalized event will be retried up to ExecuteBlockWatchLimit times
 	for i := 0; i < ExecuteBlockWatchLimit; i++ {
@@ -202,6 +215,11 @@ func (w *writer) watchThenExecute(m msg.Message, data []byte, dataHash [32]byte,
 		case <-w.stop:
 			return
 		default:
+			if w.skips.Has(m.Source, m.DepositNonce) {
+				w.log.Warn("Message is skipped, stop watching", "src", m.Source, "nonce", m.DepositNonce)
+				return
+			}
+
 			// Watch for the lastest block, retry up to BlockRetryLimit times
 			for waitRetrys := 0; waitRetrys < BlockRetryLimit; waitRetrys++ {
 				err := w.conn.WaitForBlock(latestBlock, big.NewInt(1))
@@ -249,6 +267,35 @@ func (w *writer) watchThenExecute(m msg.Message, data []byte, dataHash [32]byte,
 	log.Warn("Block watch limit exceeded, skipping execution", "source", m.Source, "dest", m.Destination, "nonce", m.DepositNonce)
 }
 
+func (w *writer) startWatching(m msg.Message, dataHash [32]byte, fromBlock *big.Int) {
+	w.watchLock.Lock()
+	defer w.watchLock.Unlock()
+	w.watching[watchKey{m.Source, m.DepositNonce}] = Execution{
+		Source:       m.Source,
+		DepositNonce: m.DepositNonce,
+		DataHash:     common.Hash(dataHash).Hex(),
+		FromBlock:    fromBlock.Uint64(),
+		Started:      time.Now(),
+	}
+}
+
+func (w *writer) stopWatching(m msg.Message) {
+	w.watchLock.Lock()
+	defer w.watchLock.Unlock()
+	delete(w.watching, watchKey{m.Source, m.DepositNonce})
+}
+
+// executions returns the proposals being watched for execution
+func (w *writer) executions() []Execution {
+	w.watchLock.RLock()
+	defer w.watchLock.RUnlock()
+	res := make([]Execution, 0, len(w.watching))
+	for _, e := range w.watching {
+		res = append(res, e)
+	}
+	return res
+}
+
 // voteProposal submits a vote proposal
 // a vote proposal will try to be submitted up to the TxRetryLimit times
 func (w *writer) voteProposal(m msg.Message, dataHash [32]byte) {
@@ -257,29 +304,32 @@ func (w *writer) voteProposal(m msg.Message, dataHash [32]byte) {
 		case <-w.stop:
 			return
 		default:
-			err := w.conn.LockAndUpdateOpts()
-			if err != nil {
-				w.log.Error("Failed to update tx opts", "err", err)
-				continue
+			tx, err := w.conn.Transact(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+				return w.bridgeContract.VoteProposal(
+					opts,
+					uint8(m.Source),
+					uint64(m.DepositNonce),
+					m.ResourceId,
+					dataHash,
+				)
+			})
+			if errors.Is(err, ErrTxNotMined) {
+				// The tx keeps its nonce, it is waited on instead of being submitted with a new nonce
+				w.log.Warn("Vote not mined yet", "tx", tx.Hash(), "src", m.Source, "depositNonce", m.DepositNonce, "err", err)
+				err = nil
 			}
 
-			tx, err := w.bridgeContract.VoteProposal(
-				w.conn.Opts(),
-				uint8(m.Source),
-				uint64(m.DepositNonce),
-				m.ResourceId,
-				dataHash,
-			)
-			w.conn.UnlockOpts()
-
 			if err == nil {
 				w.log.Info("Submitted proposal vote", "tx", tx.Hash(), "src", m.Source, "depositNonce", m.DepositNonce)
 				if w.metrics != nil {
 					w.metrics.VotesSubmitted.Inc()
 				}
-				return
-			} else if err.Error() == ErrNonceTooLow.Error() || err.Error() == ErrTxUnderpriced.Error() {
-				w.log.Debug("Nonce too low, will retry")
+				if w.handleOutcome(w.confirmTx("vote", tx), m) {
+					return
+				}
+				time.Sleep(TxRetryInterval)
+			} else if errors.Is(err, ErrNonceTooLow) || errors.Is(err, ErrTxUnderpriced) {
+				w.log.Debug("Transaction rejected, will retry", "err", err)
 				time.Sleep(TxRetryInterval)
 			} else {
 				w.log.Warn("Voting failed", "source", m.Source, "dest", m.Destination, "depositNonce", m.DepositNonce, "err", err)
@@ -304,27 +354,30 @@ func (w *writer) executeProposal(m msg.Message, data []byte, dataHash [32]byte)
 		case <-w.stop:
 			return
 		default:
-			err := w.conn.LockAndUpdateOpts()
-			if err != nil {
-				w.log.Error("Failed to update nonce", "err", err)
-				return
+			tx, err := w.conn.Transact(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+				return w.bridgeContract.ExecuteProposal(
+					opts,
+					uint8(m.Source),
+					uint64(m.DepositNonce),
+					data,
+					m.ResourceId,
+				)
+			})
+			if errors.Is(err, ErrTxNotMined) {
+				// The tx keeps its nonce, it is waited on instead of being submitted with a new nonce
+				w.log.Warn("Execution not mined yet", "tx", tx.Hash(), "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+				err = nil
 			}
 
-			tx, err := w.bridgeContract.ExecuteProposal(
-				w.conn.Opts(),
-				uint8(m.Source),
-				uint64(m.DepositNonce),
-				data,
-				m.ResourceId,
-			)
-			w.conn.UnlockOpts()
-
 			if err == nil {
 				w.log.Info("Submitted proposal execution", "tx", tx.Hash(), "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
 				//TODO: store DepositNonce
-				return
-			} else if err.Error() == ErrNonceTooLow.Error() || err.Error() == ErrTxUnderpriced.Error() {
-				w.log.Error("Nonce too low, will retry")
+				if w.handleOutcome(w.confirmTx("execute", tx), m) {
+					return
+				}
+				time.Sleep(TxRetryInterval)
+			} else if errors.Is(err, ErrNonceTooLow) || errors.Is(err, ErrTxUnderpriced) {
+				w.log.Error("Transaction rejected, will retry", "err", err)
 				time.Sleep(TxRetryInterval)
 			} else {
 				w.log.Warn("Execution failed, proposal may already be complete", "err", err)
@@ -342,3 +395,20 @@ func (w *writer) executeProposal(m msg.Message, data []byte, dataHash [32]byte)
 	w.log.Error("Submission of Execute transaction failed", "source", m.Source, "dest", m.Destination, "depositNonce", m.DepositNonce)
 	w.sysErr <- ErrFatalTx
 }
+
+// handleOutcome reports the outcome of a confirmed vote or execute tx, it returns false if the tx should be submitted again
+func (w *writer) handleOutcome(outcome txOutcome, m msg.Message) bool {
+	switch outcome {
+	case txSucceeded:
+		w.log.Info("Transaction confirmed", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		return true
+	case txComplete:
+		w.log.Info("Transaction reverted, proposal already complete", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		return true
+	case txFatal:
+		w.log.Error("Transaction reverted, not retrying", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		w.sysErr <- ErrFatalTx
+		return true
+	}
+	return false
+}
@@ -168,7 +168,11 @@ func TestCreateAndExecuteErc721Proposal(t *testing.T) {
 	// Create initial transfer message
 	resourceId := msg.ResourceIdFromSlice(append(common.LeftPadBytes(erc721Contract.Bytes(), 31), 0))
 	recipient := ethcrypto.PubkeyToAddress(BobKp.PrivateKey().PublicKey)
-	m := msg.NewNonFungibleTransfer(1, 0, 0, resourceId, tokenId, recipient.Bytes(), []byte{})
+	atp, err := common.EthToPlaton(recipient.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := msg.NewNonFungibleTransfer(1, 0, 0, resourceId, tokenId, []byte(atp), []byte{})
 	// Helpful for debugging
 	go ethtest.WatchEvent(client, contracts.BridgeAddress, utils.ProposalEvent)
 	go ethtest.WatchEvent(client, contracts.BridgeAddress, utils.ProposalVote)
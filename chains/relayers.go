@@ -0,0 +1,168 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package chains
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/ChainSafe/go-schnorrkel"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var ErrInvalidRegistry = errors.New("invalid relayer registry")
+var ErrRelayersMismatch = errors.New("relayer sets disagree")
+
+// RelayerSet is the sr25519 accounts of the signatories of a multisig and the threshold of the multisig
+type RelayerSet struct {
+	Signatories [][32]byte
+	Threshold   uint16
+}
+
+// RelayerEntry maps the EVM relayer to its sr25519 account. The EVM key signs the claim of the entry like
+// personal_sign and the sr25519 key signs it in the substrate signing context, so neither can be claimed
+// without the consent of the other key.
+type RelayerEntry struct {
+	Evm                string `json:"evm"`       // Hex encoded address of the relayer on the EVM chain
+	Substrate          string `json:"substrate"` // Hex encoded sr25519 account id of the relayer
+	EvmSignature       string `json:"evmSignature"`
+	SubstrateSignature string `json:"substrateSignature"`
+}
+
+// RelayerClaim is the message both keys of a relayer sign
+func RelayerClaim(evm common.Address, account [32]byte) []byte {
+	return []byte(fmt.Sprintf("platdot:relayer:%s:%s", hexutil.Encode(evm.Bytes()), hexutil.Encode(account[:])))
+}
+
+// RelayerRegistry holds the sr25519 accounts of the EVM relayers
+type RelayerRegistry struct {
+	accounts map[common.Address][32]byte
+}
+
+// LoadRelayerRegistry reads the JSON list of relayer entries at path and verifies their signatures
+func LoadRelayerRegistry(path string) (*RelayerRegistry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []RelayerEntry
+	err = json.Unmarshal(data, &entries)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRegistry, err)
+	}
+	return NewRelayerRegistry(entries)
+}
+
+// NewRelayerRegistry verifies the signatures of the entries, an EVM relayer or an account may be mapped only once
+func NewRelayerRegistry(entries []RelayerEntry) (*RelayerRegistry, error) {
+	r := &RelayerRegistry{accounts: make(map[common.Address][32]byte)}
+	mapped := make(map[[32]byte]bool)
+	for _, e := range entries {
+		evm, account, err := verifyEntry(e)
+		if err != nil {
+			return nil, fmt.Errorf("%w: relayer %s: %s", ErrInvalidRegistry, e.Evm, err)
+		}
+		if _, ok := r.accounts[evm]; ok || mapped[account] {
+			return nil, fmt.Errorf("%w: relayer %s is mapped twice", ErrInvalidRegistry, e.Evm)
+		}
+		r.accounts[evm] = account
+		mapped[account] = true
+	}
+	return r, nil
+}
+
+func verifyEntry(e RelayerEntry) (common.Address, [32]byte, error) {
+	var account [32]byte
+	if !common.IsHexAddress(e.Evm) {
+		return common.Address{}, account, fmt.Errorf("invalid address")
+	}
+	evm := common.HexToAddress(e.Evm)
+	pub, err := hexutil.Decode(e.Substrate)
+	if err != nil || len(pub) != 32 {
+		return evm, account, fmt.Errorf("invalid sr25519 account %s", e.Substrate)
+	}
+	copy(account[:], pub)
+	claim := RelayerClaim(evm, account)
+
+	sig, err := hexutil.Decode(e.EvmSignature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return evm, account, fmt.Errorf("invalid evm signature")
+	}
+	// The recovery id of personal_sign is 27 or 28
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	signer, err := crypto.SigToPub(accounts.TextHash(claim), sig)
+	if err != nil || crypto.PubkeyToAddress(*signer) != evm {
+		return evm, account, fmt.Errorf("evm signature is not made by the relayer")
+	}
+
+	sig, err = hexutil.Decode(e.SubstrateSignature)
+	if err != nil || len(sig) != 64 {
+		return evm, account, fmt.Errorf("invalid sr25519 signature")
+	}
+	var sigBytes [64]byte
+	copy(sigBytes[:], sig)
+	var s schnorrkel.Signature
+	var pk schnorrkel.PublicKey
+	if s.Decode(sigBytes) != nil || pk.Decode(account) != nil ||
+		!pk.Verify(&s, schnorrkel.NewSigningContext([]byte("substrate"), claim)) {
+		return evm, account, fmt.Errorf("sr25519 signature is not made by the account")
+	}
+	return evm, account, nil
+}
+
+// Check maps the EVM relayers to their accounts and compares them and the threshold to the relayer sets.
+// They agree if one of the sets has the accounts as signatories and the threshold.
+func (r *RelayerRegistry) Check(relayers []common.Address, threshold uint8, sets []RelayerSet) error {
+	var unmapped []string
+	actual := make(map[[32]byte]bool)
+	for _, relayer := range relayers {
+		account, ok := r.accounts[relayer]
+		if !ok {
+			unmapped = append(unmapped, relayer.Hex())
+			continue
+		}
+		actual[account] = true
+	}
+	if len(unmapped) != 0 {
+		return fmt.Errorf("%w: relayers %s are not in the registry", ErrRelayersMismatch, strings.Join(unmapped, ", "))
+	}
+
+	var diffs []string
+	for _, set := range sets {
+		missing, unexpected := diffSignatories(actual, set.Signatories)
+		if len(missing) == 0 && len(unexpected) == 0 && uint16(threshold) == set.Threshold {
+			return nil
+		}
+		diffs = append(diffs, fmt.Sprintf("signatories without relayer role [%s], relayers that are not signatories [%s], threshold %d of %d",
+			strings.Join(missing, ", "), strings.Join(unexpected, ", "), set.Threshold, threshold))
+	}
+	return fmt.Errorf("%w: %s", ErrRelayersMismatch, strings.Join(diffs, "; "))
+}
+
+// diffSignatories returns the signatories missing from the accounts and the accounts that are not signatories
+func diffSignatories(accounts map[[32]byte]bool, signatories [][32]byte) (missing, unexpected []string) {
+	expected := make(map[[32]byte]bool)
+	for _, s := range signatories {
+		expected[s] = true
+		if !accounts[s] {
+			missing = append(missing, hexutil.Encode(s[:]))
+		}
+	}
+	for a := range accounts {
+		if !expected[a] {
+			unexpected = append(unexpected, hexutil.Encode(a[:]))
+		}
+	}
+	sort.Strings(unexpected)
+	return missing, unexpected
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package chains
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ChainSafe/go-schnorrkel"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// newTestRelayer generates the keys of a relayer and returns its signed registry entry
+func newTestRelayer(t *testing.T) (common.Address, [32]byte, RelayerEntry) {
+	evmKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk, pk, err := schnorrkel.GenerateKeypair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	evm := crypto.PubkeyToAddress(evmKey.PublicKey)
+	account := pk.Encode()
+	claim := RelayerClaim(evm, account)
+
+	evmSig, err := crypto.Sign(accounts.TextHash(claim), evmKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evmSig[crypto.RecoveryIDOffset] += 27
+	sig, err := sk.Sign(schnorrkel.NewSigningContext([]byte("substrate"), claim))
+	if err != nil {
+		t.Fatal(err)
+	}
+	substrateSig := sig.Encode()
+
+	return evm, account, RelayerEntry{
+		Evm:                evm.Hex(),
+		Substrate:          hexutil.Encode(account[:]),
+		EvmSignature:       hexutil.Encode(evmSig),
+		SubstrateSignature: hexutil.Encode(substrateSig[:]),
+	}
+}
+
+func TestLoadRelayerRegistry(t *testing.T) {
+	_, _, alice := newTestRelayer(t)
+	_, _, bob := newTestRelayer(t)
+
+	dir, err := ioutil.TempDir(os.TempDir(), "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "relayers.json")
+	data := `[{"evm":"` + alice.Evm + `","substrate":"` + alice.Substrate + `","evmSignature":"` + alice.EvmSignature +
+		`","substrateSignature":"` + alice.SubstrateSignature + `"},{"evm":"` + bob.Evm + `","substrate":"` + bob.Substrate +
+		`","evmSignature":"` + bob.EvmSignature + `","substrateSignature":"` + bob.SubstrateSignature + `"}]`
+	err = ioutil.WriteFile(path, []byte(data), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := LoadRelayerRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(registry.accounts) != 2 {
+		t.Fatalf("Got: %d Expected: %d", len(registry.accounts), 2)
+	}
+}
+
+func TestNewRelayerRegistry_Invalid(t *testing.T) {
+	_, _, alice := newTestRelayer(t)
+	_, _, bob := newTestRelayer(t)
+
+	// Claim the account of bob with the evm key of alice
+	stolen := alice
+	stolen.Substrate = bob.Substrate
+	// Claim the evm relayer of bob with the keys of alice
+	forged := alice
+	forged.Evm = bob.Evm
+	// Substrate signature of another claim
+	mixed := alice
+	mixed.SubstrateSignature = bob.SubstrateSignature
+	unsigned := alice
+	unsigned.EvmSignature = ""
+
+	testCases := [][]RelayerEntry{
+		{stolen},
+		{forged},
+		{mixed},
+		{unsigned},
+		{alice, alice},
+		{{Evm: "0x01", Substrate: alice.Substrate}},
+	}
+	for _, entries := range testCases {
+		_, err := NewRelayerRegistry(entries)
+		if !errors.Is(err, ErrInvalidRegistry) {
+			t.Fatalf("Got: %v Expected: %v", err, ErrInvalidRegistry)
+		}
+	}
+}
+
+func TestRelayerRegistry_Check(t *testing.T) {
+	aliceEvm, aliceAccount, alice := newTestRelayer(t)
+	bobEvm, bobAccount, bob := newTestRelayer(t)
+	charlieEvm, charlieAccount, charlie := newTestRelayer(t)
+	registry, err := NewRelayerRegistry([]RelayerEntry{alice, bob, charlie})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current := RelayerSet{Signatories: [][32]byte{aliceAccount, bobAccount}, Threshold: 2}
+	next := RelayerSet{Signatories: [][32]byte{charlieAccount, aliceAccount, bobAccount}, Threshold: 2}
+
+	testCases := []struct {
+		relayers  []common.Address
+		threshold uint8
+		sets      []RelayerSet
+		err       error
+	}{
+		{[]common.Address{bobEvm, aliceEvm}, 2, []RelayerSet{current}, nil},
+		// The bridge may be updated before the rotation of the multisig
+		{[]common.Address{aliceEvm, bobEvm, charlieEvm}, 2, []RelayerSet{current, next}, nil},
+		{[]common.Address{aliceEvm, bobEvm, charlieEvm}, 2, []RelayerSet{current}, ErrRelayersMismatch},
+		{[]common.Address{aliceEvm}, 2, []RelayerSet{current}, ErrRelayersMismatch},
+		{[]common.Address{aliceEvm, bobEvm}, 1, []RelayerSet{current}, ErrRelayersMismatch},
+		// Relayer without sr25519 account
+		{[]common.Address{aliceEvm, bobEvm, {0x01}}, 2, []RelayerSet{current}, ErrRelayersMismatch},
+	}
+	for i, tc := range testCases {
+		err := registry.Check(tc.relayers, tc.threshold, tc.sets)
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("%d. Got: %v Expected: %v", i, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package chains
+
+import (
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+// RetractTransfer is the type of the messages withdrawing a message whose deposit was orphaned by a reorg
+var RetractTransfer msg.TransferType = "RetractTransfer"
+
+// NewRetraction returns the message retracting m. The type of m is carried as the first element of the payload.
+func NewRetraction(m msg.Message) msg.Message {
+	return msg.Message{
+		Source:       m.Source,
+		Destination:  m.Destination,
+		Type:         RetractTransfer,
+		DepositNonce: m.DepositNonce,
+		ResourceId:   m.ResourceId,
+		Payload:      append([]interface{}{m.Type}, m.Payload...),
+	}
+}
+
+// Retracted returns the message withdrawn by a retraction
+func Retracted(r msg.Message) msg.Message {
+	m := r
+	m.Type = r.Payload[0].(msg.TransferType)
+	m.Payload = r.Payload[1:]
+	return m
+}
@@ -0,0 +1,24 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package chains
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+func TestRetraction(t *testing.T) {
+	m := msg.NewFungibleTransfer(1, 2, 3, big.NewInt(10), msg.ResourceId{1}, []byte("recipient"))
+
+	r := NewRetraction(m)
+	if r.Type != RetractTransfer {
+		t.Fatalf("Got: %s Expected: %s", r.Type, RetractTransfer)
+	}
+	if res := Retracted(r); !reflect.DeepEqual(res, m) {
+		t.Fatalf("Got: %#v Expected: %#v", res, m)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package chains
+
+import (
+	"sync"
+
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+type skipKey struct {
+	source msg.ChainId
+	nonce  msg.Nonce
+}
+
+// SkipList holds the messages an operator asked the writer to drop
+type SkipList struct {
+	lock sync.RWMutex
+	skip map[skipKey]bool
+}
+
+func NewSkipList() *SkipList {
+	return &SkipList{skip: make(map[skipKey]bool)}
+}
+
+func (s *SkipList) Add(source msg.ChainId, nonce msg.Nonce) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.skip[skipKey{source, nonce}] = true
+}
+
+func (s *SkipList) Has(source msg.ChainId, nonce msg.Nonce) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.skip[skipKey{source, nonce}]
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"fmt"
+
+	"github.com/rjman-self/Platdot/chains"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+var _ chains.Admin = &Chain{}
+
+// AdminMessage is a redemption the writer is processing
+type AdminMessage struct {
+	CallHash string `json:"callHash"`
+	Dest
+}
+
+// AdminRound is the round of the latest finalized block, the relayer votes when
+// (CurrentRelayer + DepositNonce) % TotalRelayers equals Round
+type AdminRound struct {
+	BlockHeight    uint64 `json:"blockHeight"`
+	Round          uint64 `json:"round"`
+	CurrentRelayer uint64 `json:"currentRelayer"`
+	TotalRelayers  uint64 `json:"totalRelayers"`
+}
+
+// AdminRotation is the relayer set taking over the multisig from the block on
+type AdminRotation struct {
+	Block         uint64 `json:"block"`
+	Multisig      string `json:"multisig"`
+	Threshold     uint16 `json:"threshold"`
+	TotalRelayers uint64 `json:"totalRelayers"`
+}
+
+// AdminStatus is the state of the substrate chain exposed by the admin API
+type AdminStatus struct {
+	Paused     bool              `json:"paused"`
+	Round      *AdminRound       `json:"round,omitempty"`
+	RoundError string            `json:"roundError,omitempty"`
+	Rotation   *AdminRotation    `json:"rotation,omitempty"`
+	Messages   []AdminMessage    `json:"messages"`
+	Refunds    []Refund          `json:"refunds"`
+	Rejected   []Rejected        `json:"rejected"`
+	StoreError string            `json:"storeError,omitempty"`
+	Multisigs  []MultiSigAsMulti `json:"multisigs"`
+}
+
+func (c *Chain) AdminStatus() interface{} {
+	status := AdminStatus{
+		Paused:    c.listener.isPaused(),
+		Messages:  []AdminMessage{},
+		Refunds:   []Refund{},
+		Rejected:  []Rejected{},
+		Multisigs: c.listener.multisigs(),
+	}
+
+	c.writer.msgLock.RLock()
+	for callHash, dest := range c.writer.messages {
+		status.Messages = append(status.Messages, AdminMessage{CallHash: callHash.Hex(), Dest: dest})
+	}
+	for _, r := range c.writer.refunds {
+		status.Refunds = append(status.Refunds, r)
+	}
+	c.writer.msgLock.RUnlock()
+
+	// The rejected redemptions are requeued on their source chain, they are cleared once redeemed
+	rejected, err := c.listener.msStore.LoadRejected()
+	if err != nil {
+		status.StoreError = err.Error()
+	}
+	status.Rejected = append(status.Rejected, rejected...)
+
+	if rotation := c.writer.relayer.rotation; rotation != nil {
+		status.Rotation = &AdminRotation{
+			Block:         rotation.Block,
+			Multisig:      c.listener.address(rotation.Next.multisig[:]),
+			Threshold:     rotation.Next.multiSignThreshold,
+			TotalRelayers: rotation.Next.totalRelayers,
+		}
+	}
+
+	round, err := c.writer.currentRound()
+	if err != nil {
+		status.RoundError = err.Error()
+	} else {
+		relayer := c.writer.relayer.at(round.blockHeight.Uint64())
+		status.Round = &AdminRound{
+			BlockHeight:    round.blockHeight.Uint64(),
+			Round:          round.blockRound.Uint64(),
+			CurrentRelayer: relayer.currentRelayer,
+			TotalRelayers:  relayer.totalRelayers,
+		}
+	}
+	return status
+}
+
+func (c *Chain) PauseListener(pause bool) {
+	c.listener.pause(pause)
+}
+
+func (c *Chain) Requeue(dest msg.ChainId, nonce msg.Nonce) error {
+	if !c.listener.dests.Has(dest) {
+		return fmt.Errorf("deposits of chain %d are not routed to chain %d", c.cfg.Id, dest)
+	}
+	return c.listener.requeue(nonce)
+}
+
+func (c *Chain) Skip(source msg.ChainId, nonce msg.Nonce) {
+	c.writer.log.Warn("Skip message", "Source", source, "DepositNonce", nonce)
+	c.writer.skips.Add(source, nonce)
+}
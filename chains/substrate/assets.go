@@ -0,0 +1,297 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"fmt"
+
+	"github.com/rjman-self/go-polkadot-rpc-client/expand/polkadot"
+	"github.com/rjmand/go-substrate-rpc-client/v2/types"
+)
+
+// Asset is a token of the substrate chain. The zero value is the native token.
+type Asset struct {
+	IsAsset  bool   // The token is an asset of the Assets pallet
+	IsUnique bool   // The tokens are the instances of a class of the Uniques pallet
+	Id       uint32 // Id of the asset in the Assets pallet or of the class in the Uniques pallet
+}
+
+// NativeAsset is the native token of the chain, transferred with the Balances pallet
+var NativeAsset = Asset{}
+
+func (a Asset) String() string {
+	if a.IsUnique {
+		return fmt.Sprintf("uniques class %d", a.Id)
+	}
+	if !a.IsAsset {
+		return "native"
+	}
+	return fmt.Sprintf("asset %d", a.Id)
+}
+
+// Events of the Assets pallet, as on Statemine-like chains with u32 asset ids and u128 balances
+type EventAssetsCreated struct {
+	Phase   types.Phase
+	AssetId types.U32
+	Creator types.AccountID
+	Owner   types.AccountID
+	Topics  []types.Hash
+}
+
+type EventAssetsIssued struct {
+	Phase   types.Phase
+	AssetId types.U32
+	Owner   types.AccountID
+	Amount  types.U128
+	Topics  []types.Hash
+}
+
+type EventAssetsTransferred struct {
+	Phase   types.Phase
+	AssetId types.U32
+	From    types.AccountID
+	To      types.AccountID
+	Amount  types.U128
+	Topics  []types.Hash
+}
+
+type EventAssetsBurned struct {
+	Phase   types.Phase
+	AssetId types.U32
+	Owner   types.AccountID
+	Balance types.U128
+	Topics  []types.Hash
+}
+
+type EventAssetsTeamChanged struct {
+	Phase   types.Phase
+	AssetId types.U32
+	Issuer  types.AccountID
+	Admin   types.AccountID
+	Freezer types.AccountID
+	Topics  []types.Hash
+}
+
+type EventAssetsAccount struct {
+	Phase   types.Phase
+	AssetId types.U32
+	Who     types.AccountID
+	Topics  []types.Hash
+}
+
+type EventAssetsAsset struct {
+	Phase   types.Phase
+	AssetId types.U32
+	Topics  []types.Hash
+}
+
+type EventAssetsMetadataSet struct {
+	Phase    types.Phase
+	AssetId  types.U32
+	Name     types.Bytes
+	Symbol   types.Bytes
+	Decimals types.U8
+	IsFrozen types.Bool
+	Topics   []types.Hash
+}
+
+type EventAssetsApprovedTransfer struct {
+	Phase    types.Phase
+	AssetId  types.U32
+	Source   types.AccountID
+	Delegate types.AccountID
+	Amount   types.U128
+	Topics   []types.Hash
+}
+
+type EventAssetsApprovalCancelled struct {
+	Phase    types.Phase
+	AssetId  types.U32
+	Owner    types.AccountID
+	Delegate types.AccountID
+	Topics   []types.Hash
+}
+
+type EventAssetsTransferredApproved struct {
+	Phase       types.Phase
+	AssetId     types.U32
+	Owner       types.AccountID
+	Delegate    types.AccountID
+	Destination types.AccountID
+	Amount      types.U128
+	Topics      []types.Hash
+}
+
+// Events of the Uniques pallet, as on Statemine-like chains with u32 class and instance ids
+type EventUniquesClass struct {
+	Phase   types.Phase
+	ClassId types.U32
+	Topics  []types.Hash
+}
+
+type EventUniquesClassAccount struct {
+	Phase   types.Phase
+	ClassId types.U32
+	Who     types.AccountID
+	Topics  []types.Hash
+}
+
+type EventUniquesCreated struct {
+	Phase   types.Phase
+	ClassId types.U32
+	Creator types.AccountID
+	Owner   types.AccountID
+	Topics  []types.Hash
+}
+
+type EventUniquesInstance struct {
+	Phase      types.Phase
+	ClassId    types.U32
+	InstanceId types.U32
+	Topics     []types.Hash
+}
+
+type EventUniquesInstanceAccount struct {
+	Phase      types.Phase
+	ClassId    types.U32
+	InstanceId types.U32
+	Who        types.AccountID
+	Topics     []types.Hash
+}
+
+type EventUniquesTransferred struct {
+	Phase      types.Phase
+	ClassId    types.U32
+	InstanceId types.U32
+	From       types.AccountID
+	To         types.AccountID
+	Topics     []types.Hash
+}
+
+type EventUniquesTeamChanged struct {
+	Phase   types.Phase
+	ClassId types.U32
+	Issuer  types.AccountID
+	Admin   types.AccountID
+	Freezer types.AccountID
+	Topics  []types.Hash
+}
+
+type EventUniquesApproval struct {
+	Phase      types.Phase
+	ClassId    types.U32
+	InstanceId types.U32
+	Owner      types.AccountID
+	Delegate   types.AccountID
+	Topics     []types.Hash
+}
+
+type EventUniquesClassMetadataSet struct {
+	Phase    types.Phase
+	ClassId  types.U32
+	Data     types.Bytes
+	IsFrozen types.Bool
+	Topics   []types.Hash
+}
+
+type EventUniquesMetadataSet struct {
+	Phase      types.Phase
+	ClassId    types.U32
+	InstanceId types.U32
+	Data       types.Bytes
+	IsFrozen   types.Bool
+	Topics     []types.Hash
+}
+
+type EventUniquesRedeposited struct {
+	Phase      types.Phase
+	ClassId    types.U32
+	Successful []types.U32
+	Topics     []types.Hash
+}
+
+type EventUniquesAttributeSet struct {
+	Phase      types.Phase
+	ClassId    types.U32
+	InstanceId types.OptionU32
+	Key        types.Bytes
+	Value      types.Bytes
+	Topics     []types.Hash
+}
+
+type EventUniquesAttributeCleared struct {
+	Phase      types.Phase
+	ClassId    types.U32
+	InstanceId types.OptionU32
+	Key        types.Bytes
+	Topics     []types.Hash
+}
+
+// instanceMetadata is the value of Uniques.InstanceMetadataOf
+type instanceMetadata struct {
+	Deposit  types.U128
+	Data     types.Bytes
+	IsFrozen types.Bool
+}
+
+// assetEventRecords are the events of a chain with the Assets and Uniques pallets. The decoder needs a
+// field for every event of the block, the events of other runtime specific pallets have to be added here.
+type assetEventRecords struct {
+	polkadot.PolkadotEventRecords
+	Assets_Created             []EventAssetsCreated
+	Assets_Issued              []EventAssetsIssued
+	Assets_Transferred         []EventAssetsTransferred
+	Assets_Burned              []EventAssetsBurned
+	Assets_TeamChanged         []EventAssetsTeamChanged
+	Assets_OwnerChanged        []EventAssetsAccount
+	Assets_Frozen              []EventAssetsAccount
+	Assets_Thawed              []EventAssetsAccount
+	Assets_AssetFrozen         []EventAssetsAsset
+	Assets_AssetThawed         []EventAssetsAsset
+	Assets_Destroyed           []EventAssetsAsset
+	Assets_ForceCreated        []EventAssetsAccount
+	Assets_MetadataSet         []EventAssetsMetadataSet
+	Assets_MetadataCleared     []EventAssetsAsset
+	Assets_ApprovedTransfer    []EventAssetsApprovedTransfer
+	Assets_ApprovalCancelled   []EventAssetsApprovalCancelled
+	Assets_TransferredApproved []EventAssetsTransferredApproved
+	Assets_AssetStatusChanged  []EventAssetsAsset
+
+	Uniques_Created              []EventUniquesCreated
+	Uniques_ForceCreated         []EventUniquesClassAccount
+	Uniques_Destroyed            []EventUniquesClass
+	Uniques_Issued               []EventUniquesInstanceAccount
+	Uniques_Transferred          []EventUniquesTransferred
+	Uniques_Burned               []EventUniquesInstanceAccount
+	Uniques_Frozen               []EventUniquesInstance
+	Uniques_Thawed               []EventUniquesInstance
+	Uniques_ClassFrozen          []EventUniquesClass
+	Uniques_ClassThawed          []EventUniquesClass
+	Uniques_OwnerChanged         []EventUniquesClassAccount
+	Uniques_TeamChanged          []EventUniquesTeamChanged
+	Uniques_ApprovedTransfer     []EventUniquesApproval
+	Uniques_ApprovalCancelled    []EventUniquesApproval
+	Uniques_AssetStatusChanged   []EventUniquesClass
+	Uniques_ClassMetadataSet     []EventUniquesClassMetadataSet
+	Uniques_ClassMetadataCleared []EventUniquesClass
+	Uniques_MetadataSet          []EventUniquesMetadataSet
+	Uniques_MetadataCleared      []EventUniquesInstance
+	Uniques_Redeposited          []EventUniquesRedeposited
+	Uniques_AttributeSet         []EventUniquesAttributeSet
+	Uniques_AttributeCleared     []EventUniquesAttributeCleared
+}
+
+func (a assetEventRecords) GetAssetsTransferred() []EventAssetsTransferred {
+	return a.Assets_Transferred
+}
+
+func (a assetEventRecords) GetUniquesTransferred() []EventUniquesTransferred {
+	return a.Uniques_Transferred
+}
+
+// assetEvents are the event records with the transfers of the Assets and Uniques pallets
+type assetEvents interface {
+	GetAssetsTransferred() []EventAssetsTransferred
+	GetUniquesTransferred() []EventUniquesTransferred
+}
@@ -26,8 +26,9 @@ package substrate
 import (
 	"fmt"
 	"github.com/ChainSafe/log15"
-	"github.com/JFJun/go-substrate-crypto/ss58"
 	"github.com/centrifuge/go-substrate-rpc-client/v2/signature"
+	utils "github.com/rjman-self/Platdot/shared/substrate"
+	rsignature "github.com/rjmand/go-substrate-rpc-client/v2/signature"
 	"github.com/rjman-self/go-polkadot-rpc-client/client"
 	"github.com/rjman-self/platdot-utils/blockstore"
 	"github.com/rjman-self/platdot-utils/core"
@@ -35,7 +36,6 @@ import (
 	"github.com/rjman-self/platdot-utils/keystore"
 	metrics "github.com/rjman-self/platdot-utils/metrics/types"
 	"github.com/rjman-self/platdot-utils/msg"
-	"github.com/rjmand/go-substrate-rpc-client/v2/types"
 )
 
 var _ core.Chain = &Chain{}
@@ -78,14 +78,39 @@ func InitializeChain(cfg *core.ChainConfig, logger log15.Logger, sysErr chan<- e
 		return nil, err
 	}
 
+	/// Load the multisig transactions and messages kept by the last run
+	ms, err := NewMultisigStore(cfg.BlockstorePath, cfg.Id, kp.Address())
+	if err != nil {
+		return nil, err
+	}
+
+	return newChain(cfg, krp, bs, ms, logger, sysErr, m)
+}
+
+// InitializeReplayer connects to the chain to inspect the deposits of past blocks. The keystore and the
+// stores are not opened, the chain must not be started.
+func InitializeReplayer(cfg *core.ChainConfig, logger log15.Logger) (*Chain, error) {
+	// Only the account of the relayer is known, nothing is signed
+	account, err := utils.ParseAccountID(cfg.From, parseSS58Prefix(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %s: %w", cfg.From, err)
+	}
+	krp := &rsignature.KeyringPair{Address: cfg.From, PublicKey: account[:]}
+	return newChain(cfg, krp, nil, nil, logger, make(chan error, 1), nil)
+}
+
+// newChain connects to the chain and sets up the listener and the writer of the relayer key
+func newChain(cfg *core.ChainConfig, krp *rsignature.KeyringPair, bs blockstore.Blockstorer, ms MultisigStorer, logger log15.Logger,
+	sysErr chan<- error, m *metrics.ChainMetrics) (*Chain, error) {
 	startBlock := parseStartBlock(cfg)
 
 	stop := make(chan int)
 
 	/// Setup connection
 	conn := NewConnection(cfg.Endpoint, cfg.Name, krp, logger, stop, sysErr)
+	conn.SetSigningConfig(parseSigningConfig(cfg))
 
-	err = conn.Connect()
+	err := conn.Connect()
 	if err != nil {
 		return nil, err
 	}
@@ -101,25 +126,51 @@ func InitializeChain(cfg *core.ChainConfig, logger log15.Logger, sysErr chan<- e
 
 	/// Load listener and writer needed config
 	ue := parseUseExtended(cfg)
+	useEvents := parseUseEvents(cfg)
+	fees := parseFeeSchedule(cfg)
+	decimals := parseDecimalsRegistry(cfg)
 	otherRelayers := parseOtherRelayer(cfg)
 	multiSignAddress := parseMultiSignAddress(cfg)
 	total, currentRelayer, threshold := parseMultiSignConfig(cfg)
 	weight := parseMaxWeight(cfg)
 	url := parseUrl(cfg)
-	dest := parseDestId(cfg)
-	resource := parseResourceId(cfg)
+	resources := parseResourceRegistry(cfg)
+	dests := parseDestRegistry(cfg, resources)
+	ss58Prefix := parseSS58Prefix(cfg)
+	logger.Info("Multisig account", "Address", utils.FormatAddress(multiSignAddress[:], ss58Prefix), "Threshold", threshold, "TotalRelayers", total)
+	if resources.HasAssets() && !useEvents {
+		return nil, fmt.Errorf("the deposits of the Assets pallet are only found with useEvents")
+	}
+	for _, res := range resources.List() {
+		logger.Info("Bridged resource", "ResourceId", res.ResourceId.Hex(), "Asset", res.Asset, "Dest", res.Dest, "Handler", res.Handler, "Call", res.Call)
+	}
+	for _, id := range dests.List() {
+		prefix, _ := dests.Prefix(id)
+		logger.Info("Bridged chain", "ChainId", id, "RecipientPrefix", prefix)
+	}
 
 	cli, err := client.New(url)
 	if err != nil {
 		panic(err)
 	}
-	cli.SetPrefix(ss58.PolkadotPrefix)
+	cli.SetPrefix(utils.SS58PrefixBytes(ss58Prefix))
 
 	/// Set relayer parameters
 	relayer := NewRelayer((signature.KeyringPair)(*krp), otherRelayers, total, threshold, currentRelayer)
+	relayer.multisig = multiSignAddress
+	// The relayer refuses to vote for a multisig its set can not execute, e.g. after a typo in an OtherRelayer
+	err = relayer.checkMultisig(ss58Prefix)
+	if err != nil {
+		return nil, err
+	}
+	relayer.rotation = parseRotation(cfg, relayer.kr)
+	if rotation := relayer.rotation; rotation != nil {
+		logger.Info("Rotate the relayer set", "Block", rotation.Block, "Multisig", utils.FormatAddress(rotation.Next.multisig[:], ss58Prefix),
+			"Threshold", rotation.Next.multiSignThreshold, "TotalRelayers", rotation.Next.totalRelayers, "CurrentRelayer", rotation.Next.currentRelayer)
+	}
 
 	/// Setup listener & writer
-	l := NewListener(conn, cfg.Name, cfg.Id, startBlock, logger, bs, stop, sysErr, m, types.AccountID(multiSignAddress), cli, resource, dest, relayer)
+	l := NewListener(conn, cfg.Name, cfg.Id, startBlock, logger, bs, ms, stop, sysErr, m, cli, resources, relayer, useEvents, fees, decimals, dests, ss58Prefix)
 	w := NewWriter(conn, l, logger, sysErr, m, ue, weight, relayer)
 
 	return &Chain{
@@ -136,6 +187,11 @@ func (c *Chain) Start() error {
 	if err != nil {
 		return err
 	}
+
+	err = c.writer.start()
+	if err != nil {
+		return err
+	}
 	c.conn.log.Debug("Successfully started chain", "chainId", c.cfg.Id)
 	return nil
 }
@@ -159,4 +215,7 @@ func (c *Chain) Name() string {
 
 func (c *Chain) Stop() {
 	close(c.stop)
+	if c.listener.msStore != nil {
+		_ = c.listener.msStore.Close()
+	}
 }
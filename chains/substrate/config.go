@@ -4,12 +4,13 @@
 package substrate
 
 import (
-	"github.com/rjman-self/platdot-utils/msg"
+	"fmt"
 	log "github.com/ChainSafe/log15"
+	"github.com/centrifuge/go-substrate-rpc-client/v2/signature"
 	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
-	"github.com/ethereum/go-ethereum/common"
 	"strconv"
 
+	utils "github.com/rjman-self/Platdot/shared/substrate"
 	"github.com/rjman-self/platdot-utils/core"
 )
 
@@ -35,24 +36,108 @@ func parseUseExtended(cfg *core.ChainConfig) bool {
 	return false
 }
 
+func parseUseEvents(cfg *core.ChainConfig) bool {
+	if b, ok := cfg.Opts["useEvents"]; ok {
+		res, err := strconv.ParseBool(b)
+		if err != nil {
+			panic(err)
+		}
+		return res
+	}
+	return false
+}
+
+func parseFeeSchedule(cfg *core.ChainConfig) *FeeSchedule {
+	fees, err := parseFeeOpts(cfg.Opts)
+	if err != nil {
+		panic(err)
+	}
+	return fees
+}
+
+func parseDecimalsRegistry(cfg *core.ChainConfig) *DecimalsRegistry {
+	decimals, err := parseDecimalsOpts(cfg.Opts)
+	if err != nil {
+		panic(err)
+	}
+	return decimals
+}
+
+func parseSigningConfig(cfg *core.ChainConfig) SigningConfig {
+	signing, err := parseSigningOpts(cfg.Opts)
+	if err != nil {
+		panic(err)
+	}
+	return signing
+}
+
+func parseRecipientPrefix(cfg *core.ChainConfig) string {
+	if prefix, ok := cfg.Opts["recipientPrefix"]; ok {
+		return prefix
+	}
+	return utils.DefaultRecipientPrefix
+}
+
+func parseDestRegistry(cfg *core.ChainConfig, resources *ResourceRegistry) *DestRegistry {
+	dests, err := parseDestOpts(cfg.Opts, resources, parseRecipientPrefix(cfg))
+	if err != nil {
+		panic(err)
+	}
+	return dests
+}
+
+// parseSS58Prefix reads the ss58Prefix of the addresses of the chain, Polkadot by default
+func parseSS58Prefix(cfg *core.ChainConfig) uint16 {
+	if prefix, ok := cfg.Opts["ss58Prefix"]; ok {
+		res, err := strconv.ParseUint(prefix, 10, 16)
+		if err != nil {
+			panic(err)
+		}
+		if uint16(res) > utils.MaxSS58Prefix {
+			panic(fmt.Errorf("ss58Prefix %d is above %d", res, utils.MaxSS58Prefix))
+		}
+		return uint16(res)
+	}
+	return utils.PolkadotSS58Prefix
+}
+
 func parseOtherRelayer(cfg *core.ChainConfig) []types.AccountID {
-	var otherSignatories []types.AccountID
 	if totalRelayer, ok := cfg.Opts["TotalRelayer"]; ok {
 		total, _ := strconv.ParseUint(totalRelayer, 10, 32)
-		for i := uint64(1); i < total; i++ {
-			relayedKey := "OtherRelayer" + string(strconv.Itoa(int(i)))
-			if relayer, ok := cfg.Opts[relayedKey]; ok {
-				address, _ := types.NewAddressFromHexAccountID(relayer)
-				otherSignatories = append(otherSignatories, address.AsAccountID)
-			} else {
-				log.Warn("Please set config 'OtherRelayer' from 1 to ...!")
-				log.Error("Polkadot OtherRelayer Not Found", "OtherRelayerNumber", i)
-			}
+		_, current, _ := parseMultiSignConfig(cfg)
+		signatories, err := parseSignatories(cfg.Opts, "", total, current, parseSS58Prefix(cfg))
+		if err != nil {
+			panic(err)
 		}
+		return signatories
 	} else {
 		log.Error("Please set config opts 'TotalRelayer'.")
 	}
-	return otherSignatories
+	return nil
+}
+
+// parseSignatories reads the <prefix>OtherRelayer options of a relayer set. There are total - 1 of them if the
+// relayer is a signatory of the set and total of them if the relayer is not. An invalid address is an error.
+func parseSignatories(opts map[string]string, prefix string, total, current uint64, ss58Prefix uint16) ([]types.AccountID, error) {
+	others := total - 1
+	if current == 0 {
+		others = total
+	}
+	var otherSignatories []types.AccountID
+	for i := uint64(1); i <= others; i++ {
+		relayedKey := prefix + "OtherRelayer" + string(strconv.Itoa(int(i)))
+		if relayer, ok := opts[relayedKey]; ok {
+			address, err := utils.ParseAccountID(relayer, ss58Prefix)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %s: %w", relayedKey, relayer, err)
+			}
+			otherSignatories = append(otherSignatories, types.NewAccountID(address))
+		} else {
+			log.Warn("Please set config '" + prefix + "OtherRelayer' from 1 to ...!")
+			log.Error("Polkadot OtherRelayer Not Found", "Option", relayedKey)
+		}
+	}
+	return otherSignatories, nil
 }
 
 func parseMultiSignConfig(cfg *core.ChainConfig) (uint64, uint64, uint16) {
@@ -64,7 +149,8 @@ func parseMultiSignConfig(cfg *core.ChainConfig) (uint64, uint64, uint16) {
 	}
 	if currentRelayerNumber, ok := cfg.Opts["CurrentRelayerNumber"]; ok {
 		current, _ = strconv.ParseUint(currentRelayerNumber, 10, 32)
-		if current == 0 {
+		// A relayer joining with a rotation is not a signatory of the current set
+		if _, rotation := cfg.Opts["RotationBlock"]; current == 0 && !rotation {
 			log.Error("Please set config opts 'CurrentRelayerNumber' from 1 to ...!")
 		}
 	}
@@ -76,7 +162,10 @@ func parseMultiSignConfig(cfg *core.ChainConfig) (uint64, uint64, uint16) {
 
 func parseMultiSignAddress(cfg *core.ChainConfig) types.AccountID {
 	if multisignAddress, ok := cfg.Opts["MultiSignAddress"]; ok {
-		multiSignPk, _ := types.HexDecodeString(multisignAddress)
+		multiSignPk, err := utils.ParseAccountID(multisignAddress, parseSS58Prefix(cfg))
+		if err != nil {
+			panic(fmt.Errorf("invalid MultiSignAddress %s: %w", multisignAddress, err))
+		}
 		multiSignAccount := types.NewAccountID(multiSignPk)
 		return multiSignAccount
 	} else {
@@ -100,20 +189,18 @@ func parseMaxWeight(cfg *core.ChainConfig) uint64 {
 	return 2269800000
 }
 
-func parseDestId(cfg *core.ChainConfig) msg.ChainId {
-	if id, ok := cfg.Opts["DestId"]; ok {
-		res, err := strconv.ParseUint(id, 10, 32)
-		if err != nil {
-			panic(err)
-		}
-		return msg.ChainId(res)
+func parseRotation(cfg *core.ChainConfig, kr signature.KeyringPair) *Rotation {
+	rotation, err := parseRotationOpts(cfg.Opts, kr, parseSS58Prefix(cfg))
+	if err != nil {
+		panic(err)
 	}
-	return 0
+	return rotation
 }
 
-func parseResourceId(cfg *core.ChainConfig) msg.ResourceId {
-	if resource, ok := cfg.Opts["ResourceId"]; ok {
-		return msg.ResourceIdFromSlice(common.FromHex(resource))
+func parseResourceRegistry(cfg *core.ChainConfig) *ResourceRegistry {
+	resources, err := parseResourceOpts(cfg.Opts)
+	if err != nil {
+		panic(err)
 	}
-	return msg.ResourceIdFromSlice(common.FromHex("0x0000000000000000000000000000000000000000000000000000000000000000"))
+	return resources
 }
@@ -6,6 +6,7 @@ package substrate
 import (
 	"testing"
 
+	utils "github.com/rjman-self/Platdot/shared/substrate"
 	"github.com/rjman-self/platdot-utils/core"
 )
 
@@ -28,3 +29,29 @@ func TestParseStartBlock(t *testing.T) {
 		t.Fatalf("Got: %d Expected: %d", blk, 0)
 	}
 }
+
+func TestParseMultiSignAddress(t *testing.T) {
+	cfg := &core.ChainConfig{Opts: map[string]string{"MultiSignAddress": multisigAddress, "ss58Prefix": "42"}}
+	multisig := parseMultiSignAddress(cfg)
+	if utils.FormatAddress(multisig[:], utils.SubstrateSS58Prefix) != multisigAddress {
+		t.Fatalf("Got: %x Expected: %s", multisig, multisigAddress)
+	}
+
+	// A typo in the address must not run the relayer with the zero account
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a panic for an invalid address")
+		}
+	}()
+	cfg.Opts["MultiSignAddress"] = multisigAddress[:len(multisigAddress)-1] + "8"
+	parseMultiSignAddress(cfg)
+}
+
+func TestParseOtherRelayer_Invalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a panic for an invalid address")
+		}
+	}()
+	parseOtherRelayer(&core.ChainConfig{Opts: map[string]string{"TotalRelayer": "2", "CurrentRelayerNumber": "1", "OtherRelayer1": "0x01"}})
+}
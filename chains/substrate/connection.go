@@ -5,6 +5,7 @@ package substrate
 
 import (
 	"fmt"
+	"math/big"
 	"sync"
 
 	"github.com/ChainSafe/log15"
@@ -31,10 +32,17 @@ type Connection struct {
 	stop        <-chan int             // Signals system shutdown, should be observed in all selects and loops
 	sysErr      chan<- error           // Propagates fatal errors to core
 	prefix      []byte                 // the prefix of token
+	signing     SigningConfig          // Mortality and tip of the signed extrinsics
 }
 
 func NewConnection(url string, name string, key *signature.KeyringPair, log log15.Logger, stop <-chan int, sysErr chan<- error) *Connection {
-	return &Connection{url: url, name: name, key: key, log: log, stop: stop, sysErr: sysErr}
+	return &Connection{url: url, name: name, key: key, log: log, stop: stop, sysErr: sysErr,
+		signing: SigningConfig{EraPeriod: DefaultEraPeriod, Tip: big.NewInt(0)}}
+}
+
+// SetSigningConfig sets the era period and tip of the extrinsics signed by the relayer
+func (c *Connection) SetSigningConfig(cfg SigningConfig) {
+	c.signing = cfg
 }
 
 func (c *Connection) getMetadata() (meta types.Metadata) {
@@ -100,12 +108,6 @@ func (c *Connection) SubmitTx(method utils.Method, args ...interface{}) error {
 	}
 	ext := types.NewExtrinsic(call)
 
-	// Get latest runtime version
-	rv, err := c.api.RPC.State.GetRuntimeVersionLatest()
-	if err != nil {
-		return err
-	}
-
 	c.nonceLock.Lock()
 	latestNonce, err := c.getLatestNonce()
 	if err != nil {
@@ -117,17 +119,13 @@ func (c *Connection) SubmitTx(method utils.Method, args ...interface{}) error {
 	}
 
 	// Sign the extrinsic
-	o := types.SignatureOptions{
-		BlockHash:          c.genesisHash,
-		Era:                types.ExtrinsicEra{IsMortalEra: false},
-		GenesisHash:        c.genesisHash,
-		Nonce:              types.NewUCompactFromUInt(uint64(c.nonce)),
-		SpecVersion:        rv.SpecVersion,
-		Tip:                types.NewUCompactFromUInt(0),
-		TransactionVersion: 1,
+	o, err := c.signingOptions(uint64(c.nonce))
+	if err != nil {
+		c.nonceLock.Unlock()
+		return err
 	}
 
-	err = ext.Sign(*c.key, o)
+	err = ext.Sign(*c.key, o.rjmand())
 	if err != nil {
 		c.nonceLock.Unlock()
 		return err
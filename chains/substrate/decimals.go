@@ -0,0 +1,122 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+// Decimals are the decimals of a token on the substrate chain (Source) and on the EVM chain (Dest)
+type Decimals struct {
+	Source uint8
+	Dest   uint8
+}
+
+// DefaultDecimals converts between KSM (12 decimals) and AKSM (18 decimals)
+var DefaultDecimals = Decimals{Source: 12, Dest: 18}
+
+// ToDest converts a substrate amount into EVM units, dust is what could not be represented
+func (d Decimals) ToDest(amount *big.Int) (res *big.Int, dust *big.Int) {
+	return ConvertDecimals(amount, d.Source, d.Dest)
+}
+
+// ToSource converts an EVM amount into substrate units, dust is what could not be represented
+func (d Decimals) ToSource(amount *big.Int) (res *big.Int, dust *big.Int) {
+	return ConvertDecimals(amount, d.Dest, d.Source)
+}
+
+// ConvertDecimals converts amount from one number of decimals to another. When decreasing the
+// decimals, the truncated remainder is returned as dust, in the units of the input amount.
+func ConvertDecimals(amount *big.Int, from, to uint8) (res *big.Int, dust *big.Int) {
+	if from <= to {
+		factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(to-from)), nil)
+		return new(big.Int).Mul(amount, factor), big.NewInt(0)
+	}
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(from-to)), nil)
+	res, dust = new(big.Int).QuoRem(amount, factor, new(big.Int))
+	return res, dust
+}
+
+// DecimalsRegistry holds the decimals of the resources bridged by a chain
+type DecimalsRegistry struct {
+	def       Decimals
+	resources map[msg.ResourceId]Decimals
+}
+
+func NewDecimalsRegistry(def Decimals) *DecimalsRegistry {
+	return &DecimalsRegistry{
+		def:       def,
+		resources: make(map[msg.ResourceId]Decimals),
+	}
+}
+
+// Set registers the decimals of a resource
+func (r *DecimalsRegistry) Set(resource msg.ResourceId, d Decimals) {
+	r.resources[resource] = d
+}
+
+// Get returns the decimals of the resource, or the chain default
+func (r *DecimalsRegistry) Get(resource msg.ResourceId) Decimals {
+	if d, ok := r.resources[resource]; ok {
+		return d
+	}
+	return r.def
+}
+
+// parseDecimalsOpts reads sourceDecimals and destDecimals from the chain config. They set the chain
+// default, the per resource form is sourceDecimals.<ResourceId> and destDecimals.<ResourceId>.
+// A resource only setting one side takes the other one from the chain default.
+func parseDecimalsOpts(opts map[string]string) (*DecimalsRegistry, error) {
+	def := DefaultDecimals
+	if err := parseDecimals(opts, "", &def); err != nil {
+		return nil, err
+	}
+	r := NewDecimalsRegistry(def)
+
+	for k := range opts {
+		i := strings.Index(k, ".")
+		if i < 0 || (k[:i] != "sourceDecimals" && k[:i] != "destDecimals") {
+			continue
+		}
+		rId := k[i+1:]
+		if !strings.HasPrefix(rId, "0x") || len(rId) != 66 {
+			return nil, fmt.Errorf("invalid resource id in decimals option %s", k)
+		}
+		resource := msg.ResourceIdFromSlice(common.FromHex(rId))
+		if _, ok := r.resources[resource]; ok {
+			continue
+		}
+		d := def
+		if err := parseDecimals(opts, "."+rId, &d); err != nil {
+			return nil, err
+		}
+		r.Set(resource, d)
+	}
+	return r, nil
+}
+
+func parseDecimals(opts map[string]string, suffix string, d *Decimals) error {
+	for _, opt := range []struct {
+		key   string
+		value *uint8
+	}{
+		{"sourceDecimals" + suffix, &d.Source},
+		{"destDecimals" + suffix, &d.Dest},
+	} {
+		if v, ok := opts[opt.key]; ok {
+			res, err := strconv.ParseUint(v, 10, 8)
+			if err != nil {
+				return fmt.Errorf("invalid decimals option %s: %w", opt.key, err)
+			}
+			*opt.value = uint8(res)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+func TestConvertDecimals(t *testing.T) {
+	testCases := []struct {
+		name   string
+		amount string
+		from   uint8
+		to     uint8
+		res    string
+		dust   string
+	}{
+		{"ksm to aksm", "1000000000000", 12, 18, "1000000000000000000", "0"},
+		{"aksm to ksm", "1000000000000000000", 18, 12, "1000000000000", "0"},
+		{"aksm to ksm with dust", "1000000000000123456", 18, 12, "1000000000000", "123456"},
+		{"only dust", "999999", 18, 12, "0", "999999"},
+		{"dot to adot", "10000000000", 10, 18, "1000000000000000000", "0"},
+		{"same decimals", "123", 18, 18, "123", "0"},
+		{"zero", "0", 18, 12, "0", "0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			amount, _ := new(big.Int).SetString(tc.amount, 10)
+			res, dust := ConvertDecimals(amount, tc.from, tc.to)
+			if res.String() != tc.res {
+				t.Fatalf("Got: %s Expected: %s", res, tc.res)
+			}
+			if dust.String() != tc.dust {
+				t.Fatalf("Got: %s Expected: %s", dust, tc.dust)
+			}
+		})
+	}
+}
+
+func TestParseDecimalsOpts(t *testing.T) {
+	rId := "0x0000000000000000000000000000000000000000000000000000000000000001"
+	resource := msg.ResourceIdFromSlice(common.FromHex(rId))
+	other := msg.ResourceIdFromSlice(common.FromHex("0x02"))
+
+	testCases := []struct {
+		name     string
+		opts     map[string]string
+		resource msg.ResourceId
+		expected Decimals
+	}{
+		{"default", map[string]string{}, resource, DefaultDecimals},
+		{"chain", map[string]string{"sourceDecimals": "10"}, other, Decimals{10, 18}},
+		{"resource", map[string]string{"sourceDecimals": "10", "destDecimals." + rId: "12"}, resource, Decimals{10, 12}},
+		{"other resource", map[string]string{"destDecimals." + rId: "12"}, other, DefaultDecimals},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := parseDecimalsOpts(tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := r.Get(tc.resource); d != tc.expected {
+				t.Fatalf("Got: %v Expected: %v", d, tc.expected)
+			}
+		})
+	}
+
+	for _, opts := range []map[string]string{
+		{"sourceDecimals": "256"},
+		{"destDecimals.0x01": "18"},
+	} {
+		_, err := parseDecimalsOpts(opts)
+		if err == nil {
+			t.Fatalf("Expected error for %v", opts)
+		}
+	}
+}
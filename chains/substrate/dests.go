@@ -0,0 +1,112 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+// DestRegistry is the whitelist of the chains the deposits are bridged to and the burns are redeemed
+// from. Every chain has the bech32 prefix of its addresses, the prefix of a recipient selects the chain
+// of the deposits that do not name one in their memo.
+type DestRegistry struct {
+	prefixes map[msg.ChainId]string
+	chains   map[string][]msg.ChainId
+}
+
+func NewDestRegistry() *DestRegistry {
+	return &DestRegistry{
+		prefixes: make(map[msg.ChainId]string),
+		chains:   make(map[string][]msg.ChainId),
+	}
+}
+
+// Add whitelists a chain, a chain can only be whitelisted once
+func (r *DestRegistry) Add(id msg.ChainId, prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("no recipient prefix for chain %d", id)
+	}
+	if _, ok := r.prefixes[id]; ok {
+		return fmt.Errorf("chain %d is whitelisted twice", id)
+	}
+	r.prefixes[id] = prefix
+	r.chains[prefix] = append(r.chains[prefix], id)
+	return nil
+}
+
+// Has returns whether the chain is whitelisted
+func (r *DestRegistry) Has(id msg.ChainId) bool {
+	_, ok := r.prefixes[id]
+	return ok
+}
+
+// Prefix returns the recipient prefix of a whitelisted chain
+func (r *DestRegistry) Prefix(id msg.ChainId) (string, bool) {
+	prefix, ok := r.prefixes[id]
+	return prefix, ok
+}
+
+// ByPrefix returns the whitelisted chain of a recipient prefix, a prefix shared by several chains
+// does not select a chain
+func (r *DestRegistry) ByPrefix(prefix string) (msg.ChainId, bool) {
+	ids := r.chains[prefix]
+	if len(ids) != 1 {
+		return 0, false
+	}
+	return ids[0], true
+}
+
+// List returns the whitelisted chains in ascending order
+func (r *DestRegistry) List() []msg.ChainId {
+	ids := make([]msg.ChainId, 0, len(r.prefixes))
+	for id := range r.prefixes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// parseDestOpts reads the whitelist from the options Dest.<chainId> set to the recipient prefix of the
+// chain. Without a whitelist the chains of the resources are whitelisted with the recipientPrefix.
+func parseDestOpts(opts map[string]string, resources *ResourceRegistry, prefix string) (*DestRegistry, error) {
+	r := NewDestRegistry()
+	for k, v := range opts {
+		if !strings.HasPrefix(k, "Dest.") {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimPrefix(k, "Dest."), 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dest option %s", k)
+		}
+		err = r.Add(msg.ChainId(id), v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(r.prefixes) != 0 {
+		// The chain of a resource also receives its legacy deposits
+		for _, res := range resources.List() {
+			if !r.Has(res.Dest) {
+				return nil, fmt.Errorf("chain %d of resource %s is not whitelisted", res.Dest, res.ResourceId.Hex())
+			}
+		}
+		return r, nil
+	}
+
+	for _, res := range resources.List() {
+		if r.Has(res.Dest) {
+			continue
+		}
+		err := r.Add(res.Dest, prefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
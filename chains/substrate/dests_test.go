@@ -0,0 +1,87 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+func TestParseDestOpts(t *testing.T) {
+	resources, err := parseResourceOpts(map[string]string{"DestId": "2", "ResourceId": ksmResource})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dests, err := parseDestOpts(map[string]string{"Dest.2": "atp", "Dest.3": "lat"}, resources, "atx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dests.List(), []msg.ChainId{2, 3}) {
+		t.Fatalf("Got: %v Expected: %v", dests.List(), []msg.ChainId{2, 3})
+	}
+	for prefix, expected := range map[string]msg.ChainId{"atp": 2, "lat": 3} {
+		id, ok := dests.ByPrefix(prefix)
+		if !ok || id != expected {
+			t.Fatalf("Got: %d Expected: %d", id, expected)
+		}
+	}
+	if _, ok := dests.ByPrefix("atx"); ok {
+		t.Fatal("Expected no chain for the recipientPrefix")
+	}
+}
+
+func TestParseDestOpts_Legacy(t *testing.T) {
+	resources, err := parseResourceOpts(map[string]string{
+		"DestId":                              "1",
+		"Resource." + ksmResource + ".asset":  "native",
+		"Resource." + usdtResource + ".asset": "1984",
+		"Resource." + usdtResource + ".dest":  "3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dests, err := parseDestOpts(map[string]string{}, resources, "atp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dests.List(), []msg.ChainId{1, 3}) {
+		t.Fatalf("Got: %v Expected: %v", dests.List(), []msg.ChainId{1, 3})
+	}
+	// The prefix is shared by the chains of the resources
+	if _, ok := dests.ByPrefix("atp"); ok {
+		t.Fatal("Expected no chain for a shared prefix")
+	}
+}
+
+func TestParseDestOpts_Invalid(t *testing.T) {
+	resources, err := parseResourceOpts(map[string]string{"DestId": "2", "ResourceId": ksmResource})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, opts := range []map[string]string{
+		{"Dest.3": "lat"},
+		{"Dest.2": "atp", "Dest.x": "lat"},
+		{"Dest.2": "atp", "Dest.256": "lat"},
+		{"Dest.2": ""},
+	} {
+		_, err := parseDestOpts(opts, resources, "atp")
+		if err == nil {
+			t.Fatalf("Expected an error for %v", opts)
+		}
+	}
+}
+
+func TestRedeemRemark(t *testing.T) {
+	res := Resource{Dest: 2}
+	if r := string(redeemRemark(res, 2, 7)); r != "platdot:redeem:7" {
+		t.Fatalf("Got: %s Expected: %s", r, "platdot:redeem:7")
+	}
+	if r := string(redeemRemark(res, 3, 7)); r != "platdot:redeem:3:7" {
+		t.Fatalf("Got: %s Expected: %s", r, "platdot:redeem:3:7")
+	}
+}
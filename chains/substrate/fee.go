@@ -0,0 +1,302 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+// FeeDirection is the direction of a transfer the fee is charged for
+type FeeDirection string
+
+const (
+	// Deposit is a transfer from substrate into the multisig, bridged to the EVM chain
+	Deposit FeeDirection = "Deposit"
+	// Redeem is a transfer from the EVM chain paid out by the multisig
+	Redeem FeeDirection = "Redeem"
+	// RefundFee is a deposit that can not be bridged, returned to its sender by the multisig
+	RefundFee FeeDirection = "Refund"
+)
+
+// Basis points of a whole amount, a Rate of 10 is 0.1%
+const RateDenominator = 10000
+
+var ErrAmountBelowFee = errors.New("amount does not cover the fee")
+var ErrAmountBelowMinimum = errors.New("amount left once the fee is paid is below the minimum")
+
+// FeeTier overrides the fixed fee and rate of a policy for amounts up to UpTo (inclusive).
+// A nil UpTo matches every amount.
+type FeeTier struct {
+	UpTo  *big.Int
+	Fixed *big.Int
+	Rate  uint64
+}
+
+// FeePolicy describes how the fee of a transfer is computed. All amounts are in the smallest
+// unit of the substrate chain. Min and Max cap the total fee when they are set. Minimum is the smallest
+// amount left once the fee is paid, e.g. the existential deposit of the asset.
+type FeePolicy struct {
+	Fixed   *big.Int
+	Rate    uint64
+	Tiers   []FeeTier
+	Min     *big.Int
+	Max     *big.Int
+	Minimum *big.Int
+}
+
+// FeeBreakdown is the result of applying a FeePolicy to an amount
+type FeeBreakdown struct {
+	Amount     *big.Int
+	FixedFee   *big.Int
+	PercentFee *big.Int
+	Fee        *big.Int
+	Net        *big.Int
+}
+
+// DefaultFeePolicy is the fee policy used when a chain does not configure one: 0.03 KSM plus 0.1%
+var DefaultFeePolicy = FeePolicy{
+	Fixed: big.NewInt(KSM * 3 / 100),
+	Rate:  10,
+}
+
+// DefaultRefundPolicy is the refund policy used when a chain does not configure one: 0.03 KSM, and at least
+// the existential deposit of Kusama is refunded
+var DefaultRefundPolicy = FeePolicy{
+	Fixed:   big.NewInt(KSM * 3 / 100),
+	Minimum: big.NewInt(KSM / 30000),
+}
+
+// Breakdown applies the policy to amount. ErrAmountBelowFee is returned if nothing is left once the fee is paid
+// and ErrAmountBelowMinimum if less than the Minimum is left.
+func (p FeePolicy) Breakdown(amount *big.Int) (FeeBreakdown, error) {
+	fixed, rate := p.Fixed, p.Rate
+	for _, tier := range p.Tiers {
+		if tier.UpTo == nil || amount.Cmp(tier.UpTo) <= 0 {
+			fixed, rate = tier.Fixed, tier.Rate
+			break
+		}
+	}
+	if fixed == nil {
+		fixed = big.NewInt(0)
+	}
+
+	res := FeeBreakdown{
+		Amount:     new(big.Int).Set(amount),
+		FixedFee:   new(big.Int).Set(fixed),
+		PercentFee: new(big.Int).Div(new(big.Int).Mul(amount, new(big.Int).SetUint64(rate)), big.NewInt(RateDenominator)),
+	}
+
+	fee := new(big.Int).Add(res.FixedFee, res.PercentFee)
+	if p.Min != nil && fee.Cmp(p.Min) < 0 {
+		fee.Set(p.Min)
+	}
+	if p.Max != nil && fee.Cmp(p.Max) > 0 {
+		fee.Set(p.Max)
+	}
+	res.Fee = fee
+	res.Net = new(big.Int).Sub(amount, fee)
+
+	if res.Net.Sign() <= 0 {
+		return res, fmt.Errorf("%w: amount %s, fee %s", ErrAmountBelowFee, amount, fee)
+	}
+	if p.Minimum != nil && res.Net.Cmp(p.Minimum) < 0 {
+		return res, fmt.Errorf("%w: amount %s, fee %s, minimum %s", ErrAmountBelowMinimum, amount, fee, p.Minimum)
+	}
+	return res, nil
+}
+
+type feeScope struct {
+	resource  msg.ResourceId
+	anyRes    bool
+	direction FeeDirection
+}
+
+// FeeSchedule holds the fee policies of a chain, per resource and per direction
+type FeeSchedule struct {
+	policies map[feeScope]FeePolicy
+}
+
+// NewFeeSchedule creates a schedule that applies def to every transfer without a more specific policy
+func NewFeeSchedule(def FeePolicy) *FeeSchedule {
+	return &FeeSchedule{
+		policies: map[feeScope]FeePolicy{{anyRes: true}: def},
+	}
+}
+
+// Set registers the policy for a resource and direction. A nil resource applies to all resources,
+// an empty direction to both directions.
+func (s *FeeSchedule) Set(resource *msg.ResourceId, direction FeeDirection, p FeePolicy) {
+	s.policies[newFeeScope(resource, direction)] = p
+}
+
+// Policy returns the most specific policy for the resource and direction, looked up in the order
+// resource+direction, resource, direction, chain default.
+func (s *FeeSchedule) Policy(resource msg.ResourceId, direction FeeDirection) FeePolicy {
+	scopes := []feeScope{
+		{resource: resource, direction: direction},
+		{resource: resource},
+		{anyRes: true, direction: direction},
+		{anyRes: true},
+	}
+	for _, scope := range scopes {
+		if p, ok := s.policies[scope]; ok {
+			return p
+		}
+	}
+	return DefaultFeePolicy
+}
+
+// Breakdown computes the fee of a transfer of amount
+func (s *FeeSchedule) Breakdown(resource msg.ResourceId, direction FeeDirection, amount *big.Int) (FeeBreakdown, error) {
+	return s.Policy(resource, direction).Breakdown(amount)
+}
+
+// RefundPolicy returns the policy of the refunds of the resource, looked up in the order resource+Refund,
+// Refund, DefaultRefundPolicy. The policies of the deposits and redemptions do not apply to the refunds.
+func (s *FeeSchedule) RefundPolicy(resource msg.ResourceId) FeePolicy {
+	for _, scope := range []feeScope{{resource: resource, direction: RefundFee}, {anyRes: true, direction: RefundFee}} {
+		if p, ok := s.policies[scope]; ok {
+			return p
+		}
+	}
+	return DefaultRefundPolicy
+}
+
+func newFeeScope(resource *msg.ResourceId, direction FeeDirection) feeScope {
+	if resource == nil {
+		return feeScope{anyRes: true, direction: direction}
+	}
+	return feeScope{resource: *resource, direction: direction}
+}
+
+// parseFeeOpts reads the fee options of the chain config. Options have the form
+// Fee[.<ResourceId>][.<Direction>].<Field>, where Field is one of Fixed, Rate, Min, Max, Minimum or Tiers.
+// Tiers is a comma separated list of upTo:fixed:rate, an empty upTo matches any amount.
+// The options of a scope make up a whole policy, unset fields are not inherited from a wider scope.
+//
+//	"Fee.Fixed": "30000000000",
+//	"Fee.Rate": "10",
+//	"Fee.0x...01.Redeem.Max": "1000000000000",
+//	"Fee.Refund.Minimum": "33333333",
+func parseFeeOpts(opts map[string]string) (*FeeSchedule, error) {
+	type entry struct {
+		resource  *msg.ResourceId
+		direction FeeDirection
+		policy    FeePolicy
+	}
+	entries := make(map[feeScope]*entry)
+
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		if strings.HasPrefix(k, "Fee.") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		parts := strings.Split(strings.TrimPrefix(k, "Fee."), ".")
+		field := parts[len(parts)-1]
+		var resource *msg.ResourceId
+		var direction FeeDirection
+		for _, part := range parts[:len(parts)-1] {
+			switch {
+			case part == string(Deposit) || part == string(Redeem) || part == string(RefundFee):
+				direction = FeeDirection(part)
+			case strings.HasPrefix(part, "0x") && len(part) == 66 && resource == nil:
+				r := msg.ResourceIdFromSlice(common.FromHex(part))
+				resource = &r
+			default:
+				return nil, fmt.Errorf("invalid fee option %s", k)
+			}
+		}
+
+		scope := newFeeScope(resource, direction)
+		e, ok := entries[scope]
+		if !ok {
+			e = &entry{resource: resource, direction: direction}
+			entries[scope] = e
+		}
+
+		var err error
+		value := opts[k]
+		switch field {
+		case "Fixed":
+			e.policy.Fixed, err = parseFeeAmount(value)
+		case "Rate":
+			e.policy.Rate, err = strconv.ParseUint(value, 10, 64)
+		case "Min":
+			e.policy.Min, err = parseFeeAmount(value)
+		case "Max":
+			e.policy.Max, err = parseFeeAmount(value)
+		case "Minimum":
+			e.policy.Minimum, err = parseFeeAmount(value)
+		case "Tiers":
+			e.policy.Tiers, err = parseFeeTiers(value)
+		default:
+			err = fmt.Errorf("unknown field %s", field)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid fee option %s: %w", k, err)
+		}
+	}
+
+	s := NewFeeSchedule(DefaultFeePolicy)
+	for _, e := range entries {
+		s.Set(e.resource, e.direction, e.policy)
+	}
+	return s, nil
+}
+
+func parseFeeAmount(value string) (*big.Int, error) {
+	res, ok := new(big.Int).SetString(value, 10)
+	if !ok || res.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", value)
+	}
+	return res, nil
+}
+
+func parseFeeTiers(value string) ([]FeeTier, error) {
+	var tiers []FeeTier
+	for _, t := range strings.Split(value, ",") {
+		fields := strings.Split(strings.TrimSpace(t), ":")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid tier %q, expected upTo:fixed:rate", t)
+		}
+		var tier FeeTier
+		var err error
+		if fields[0] != "" {
+			tier.UpTo, err = parseFeeAmount(fields[0])
+			if err != nil {
+				return nil, err
+			}
+		}
+		tier.Fixed, err = parseFeeAmount(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		tier.Rate, err = strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, tier)
+	}
+
+	// Tiers apply from the smallest bound, the unbounded one last
+	sort.SliceStable(tiers, func(i, j int) bool {
+		if tiers[j].UpTo == nil {
+			return tiers[i].UpTo != nil
+		}
+		return tiers[i].UpTo != nil && tiers[i].UpTo.Cmp(tiers[j].UpTo) < 0
+	})
+	return tiers, nil
+}
@@ -0,0 +1,178 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+func TestFeePolicyBreakdown(t *testing.T) {
+	tiered := FeePolicy{
+		Tiers: []FeeTier{
+			{UpTo: big.NewInt(1000), Fixed: big.NewInt(10), Rate: 0},
+			{UpTo: big.NewInt(100000), Fixed: big.NewInt(5), Rate: 10},
+			{Fixed: big.NewInt(0), Rate: 5},
+		},
+	}
+
+	testCases := []struct {
+		name    string
+		policy  FeePolicy
+		amount  int64
+		fixed   int64
+		percent int64
+		fee     int64
+		err     error
+	}{
+		{"fixed", FeePolicy{Fixed: big.NewInt(30)}, 1000, 30, 0, 30, nil},
+		{"percentage", FeePolicy{Rate: 100}, 1000, 0, 10, 10, nil},
+		{"fixed and percentage", FeePolicy{Fixed: big.NewInt(30), Rate: 10}, 100000, 30, 100, 130, nil},
+		{"min cap", FeePolicy{Rate: 10, Min: big.NewInt(50)}, 1000, 0, 1, 50, nil},
+		{"max cap", FeePolicy{Rate: 100, Max: big.NewInt(500)}, 1000000, 0, 10000, 500, nil},
+		{"first tier", tiered, 1000, 10, 0, 10, nil},
+		{"second tier", tiered, 50000, 5, 50, 55, nil},
+		{"last tier", tiered, 1000000, 0, 500, 500, nil},
+		{"below fee", FeePolicy{Fixed: big.NewInt(30)}, 30, 30, 0, 30, ErrAmountBelowFee},
+		{"minimum", FeePolicy{Fixed: big.NewInt(30), Minimum: big.NewInt(20)}, 50, 30, 0, 30, nil},
+		{"below minimum", FeePolicy{Fixed: big.NewInt(30), Minimum: big.NewInt(20)}, 49, 30, 0, 30, ErrAmountBelowMinimum},
+		{"legacy default", DefaultFeePolicy, KSM, KSM * 3 / 100, KSM / 1000, KSM*3/100 + KSM/1000, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.policy.Breakdown(big.NewInt(tc.amount))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Got: %v Expected: %v", err, tc.err)
+			}
+			if res.FixedFee.Int64() != tc.fixed {
+				t.Fatalf("Got: %d Expected: %d", res.FixedFee, tc.fixed)
+			}
+			if res.PercentFee.Int64() != tc.percent {
+				t.Fatalf("Got: %d Expected: %d", res.PercentFee, tc.percent)
+			}
+			if res.Fee.Int64() != tc.fee {
+				t.Fatalf("Got: %d Expected: %d", res.Fee, tc.fee)
+			}
+			if res.Net.Int64() != tc.amount-tc.fee {
+				t.Fatalf("Got: %d Expected: %d", res.Net, tc.amount-tc.fee)
+			}
+		})
+	}
+}
+
+func TestParseFeeOpts(t *testing.T) {
+	rId := "0x0000000000000000000000000000000000000000000000000000000000000001"
+	resource := msg.ResourceIdFromSlice(common.FromHex(rId))
+	other := msg.ResourceIdFromSlice(common.FromHex("0x02"))
+
+	fees, err := parseFeeOpts(map[string]string{
+		"Fee.Fixed":                     "30",
+		"Fee.Rate":                      "10",
+		"Fee.Redeem.Fixed":              "20",
+		"Fee." + rId + ".Rate":          "100",
+		"Fee." + rId + ".Deposit.Tiers": "1000:1:0,:2:0",
+		"Fee." + rId + ".Deposit.Max":   "1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		resource  msg.ResourceId
+		direction FeeDirection
+		amount    int64
+		fee       int64
+	}{
+		{other, Deposit, 100000, 130},
+		{other, Redeem, 100000, 20},
+		{resource, Redeem, 100000, 1000},
+		{resource, Deposit, 100000, 1},
+	}
+	for _, tc := range testCases {
+		res, err := fees.Breakdown(tc.resource, tc.direction, big.NewInt(tc.amount))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Fee.Int64() != tc.fee {
+			t.Fatalf("Got: %d Expected: %d", res.Fee, tc.fee)
+		}
+	}
+
+	// Without options the legacy fee applies
+	fees, err = parseFeeOpts(map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := fees.Breakdown(resource, Redeem, big.NewInt(KSM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Fee.Int64() != KSM*3/100+KSM/1000 {
+		t.Fatalf("Got: %d Expected: %d", res.Fee, KSM*3/100+KSM/1000)
+	}
+
+	for _, opts := range []map[string]string{
+		{"Fee.Fixed": "-1"},
+		{"Fee.Refund.Minimum": "-1"},
+		{"Fee.Unknown": "1"},
+		{"Fee.Sideways.Rate": "1"},
+		{"Fee.Tiers": "1:2"},
+	} {
+		_, err = parseFeeOpts(opts)
+		if err == nil {
+			t.Fatalf("Expected error for %v", opts)
+		}
+	}
+}
+
+func TestRefundPolicy(t *testing.T) {
+	rId := "0x0000000000000000000000000000000000000000000000000000000000000001"
+	resource := msg.ResourceIdFromSlice(common.FromHex(rId))
+	other := msg.ResourceIdFromSlice(common.FromHex("0x02"))
+
+	// Without refund options the default refund policy applies, not the fee of the deposits
+	fees, err := parseFeeOpts(map[string]string{"Fee.Fixed": "30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fees.RefundPolicy(resource).Breakdown(big.NewInt(KSM*3/100 + KSM/30000 - 1))
+	if !errors.Is(err, ErrAmountBelowMinimum) {
+		t.Fatalf("Got: %v Expected: %v", err, ErrAmountBelowMinimum)
+	}
+
+	fees, err = parseFeeOpts(map[string]string{
+		"Fee.Refund.Fixed":             "10",
+		"Fee.Refund.Minimum":           "100",
+		"Fee." + rId + ".Refund.Fixed": "20",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		resource msg.ResourceId
+		amount   int64
+		net      int64
+		err      error
+	}{
+		{other, 110, 100, nil},
+		{other, 109, 99, ErrAmountBelowMinimum},
+		{other, 10, 0, ErrAmountBelowFee},
+		// The policy of the resource has no minimum
+		{resource, 21, 1, nil},
+	}
+	for _, tc := range testCases {
+		res, err := fees.RefundPolicy(tc.resource).Breakdown(big.NewInt(tc.amount))
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("Got: %v Expected: %v", err, tc.err)
+		}
+		if res.Net.Int64() != tc.net {
+			t.Fatalf("Got: %d Expected: %d", res.Net, tc.net)
+		}
+	}
+}
@@ -6,43 +6,57 @@ package substrate
 import (
 	"errors"
 	"fmt"
-	"github.com/JFJun/go-substrate-crypto/ss58"
+	ctypes "github.com/centrifuge/go-substrate-rpc-client/v2/types"
+	"github.com/rjman-self/go-polkadot-rpc-client/expand"
 	"github.com/rjman-self/go-polkadot-rpc-client/expand/polkadot"
 	"github.com/rjman-self/go-polkadot-rpc-client/models"
-	"strconv"
 
 	"github.com/rjman-self/go-polkadot-rpc-client/client"
 
 	"github.com/rjmand/go-substrate-rpc-client/v2/types"
 	"math/big"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ChainSafe/log15"
 	"github.com/rjman-self/Platdot/chains"
+	utils "github.com/rjman-self/Platdot/shared/substrate"
 	"github.com/rjman-self/platdot-utils/blockstore"
 	metrics "github.com/rjman-self/platdot-utils/metrics/types"
 	"github.com/rjman-self/platdot-utils/msg"
 )
 
 type listener struct {
-	name          string
-	chainId       msg.ChainId
-	startBlock    uint64
-	blockStore    blockstore.Blockstorer
-	conn          *Connection
-	router        chains.Router
-	log           log15.Logger
-	stop          <-chan int
-	sysErr        chan<- error
-	latestBlock   metrics.LatestBlock
-	metrics       *metrics.ChainMetrics
-	client        client.Client
-	multiSignAddr types.AccountID
-	currentTx     MultiSignTx
-	msTxAsMulti   map[MultiSignTx]MultiSigAsMulti
-	resourceId    msg.ResourceId
-	destId        msg.ChainId
-	relayer       Relayer
+	name        string
+	chainId     msg.ChainId
+	startBlock  uint64
+	blockStore  blockstore.Blockstorer
+	msStore     MultisigStorer
+	conn        *Connection
+	router      chains.Router
+	log         log15.Logger
+	stop        <-chan int
+	sysErr      chan<- error
+	latestBlock metrics.LatestBlock
+	metrics     *metrics.ChainMetrics
+	client      client.Client
+	currentTx   MultiSignTx
+	msTxAsMulti map[ctypes.Hash]MultiSigAsMulti
+	resources   *ResourceRegistry
+	relayer     Relayer
+	useEvents   bool
+	fees        *FeeSchedule
+	decimals    *DecimalsRegistry
+	refunds     chan Refund
+	dests       *DestRegistry
+	ss58Prefix  uint16 // Prefix of the addresses of the chain
+	msLock      sync.RWMutex
+	paused      int32
+	// The migration of the funds to the multisig of the next relayer set, see migrate
+	migrationLock sync.Mutex
+	migration     ctypes.Hash  // Call hash of the current round of the migration
+	migrated      *MultiSignTx // The extrinsic that executed the migration, nil until it is executed
 }
 
 // Frequency of polling for a new block
@@ -51,29 +65,36 @@ const InitCapacity = 500
 var BlockRetryInterval = time.Second * 5
 var BlockRetryLimit = 10
 var KSM int64 = 1e12
-var FixedFee = KSM * 3 / 100
-var FeeRate int64 = 1000
 
-func NewListener(conn *Connection, name string, id msg.ChainId, startBlock uint64, log log15.Logger, bs blockstore.Blockstorer,
-	stop <-chan int, sysErr chan<- error, m *metrics.ChainMetrics, multiSignAddress types.AccountID, cli *client.Client,
-	resource msg.ResourceId, dest msg.ChainId, relayer Relayer) *listener {
+var ErrInvalidAmount = errors.New("invalid deposit amount")
+var ErrInvalidRecipient = errors.New("invalid deposit recipient")
+var ErrInvalidDest = errors.New("deposit destination is not bridged")
+
+func NewListener(conn *Connection, name string, id msg.ChainId, startBlock uint64, log log15.Logger, bs blockstore.Blockstorer, ms MultisigStorer,
+	stop <-chan int, sysErr chan<- error, m *metrics.ChainMetrics, cli *client.Client,
+	resources *ResourceRegistry, relayer Relayer, useEvents bool, fees *FeeSchedule, decimals *DecimalsRegistry, dests *DestRegistry, ss58Prefix uint16) *listener {
 	return &listener{
-		name:          name,
-		chainId:       id,
-		startBlock:    startBlock,
-		blockStore:    bs,
-		conn:          conn,
-		log:           log,
-		stop:          stop,
-		sysErr:        sysErr,
-		latestBlock:   metrics.LatestBlock{LastUpdated: time.Now()},
-		metrics:       m,
-		client:        *cli,
-		multiSignAddr: multiSignAddress,
-		msTxAsMulti:   make(map[MultiSignTx]MultiSigAsMulti, InitCapacity),
-		resourceId:    resource,
-		destId:        dest,
-		relayer:       relayer,
+		name:        name,
+		chainId:     id,
+		startBlock:  startBlock,
+		blockStore:  bs,
+		msStore:     ms,
+		conn:        conn,
+		log:         log,
+		stop:        stop,
+		sysErr:      sysErr,
+		latestBlock: metrics.LatestBlock{LastUpdated: time.Now()},
+		metrics:     m,
+		client:      *cli,
+		msTxAsMulti: make(map[ctypes.Hash]MultiSigAsMulti, InitCapacity),
+		resources:   resources,
+		relayer:     relayer,
+		useEvents:   useEvents,
+		fees:        fees,
+		decimals:    decimals,
+		refunds:     make(chan Refund, InitCapacity),
+		dests:       dests,
+		ss58Prefix:  ss58Prefix,
 	}
 }
 
@@ -83,6 +104,16 @@ func (l *listener) setRouter(r chains.Router) {
 
 // start creates the initial subscription for all events
 func (l *listener) start() error {
+	// Reload the multisig transactions seen before the last shutdown
+	err := l.loadMultisigs()
+	if err != nil {
+		return err
+	}
+	l.migrated, err = l.msStore.LoadMigration()
+	if err != nil {
+		return fmt.Errorf("failed to load the migration: %w", err)
+	}
+
 	// Check whether latest is less than starting block
 	header, err := l.client.Api.RPC.Chain.GetHeaderLatest()
 	if err != nil {
@@ -115,6 +146,11 @@ func (l *listener) pollBlocks() error {
 		case <-l.stop:
 			return errors.New("terminated")
 		default:
+			if l.isPaused() {
+				time.Sleep(BlockRetryInterval)
+				continue
+			}
+
 			// No more retries, goto next block
 			if retry == 0 {
 				l.sysErr <- fmt.Errorf("event polling retries exceeded (chain=%d, name=%s)", l.chainId, l.name)
@@ -191,6 +227,10 @@ func (l *listener) pollBlocks() error {
 }
 
 func (l *listener) processBlock(hash types.Hash) error {
+	if l.useEvents {
+		return l.processEvents(hash)
+	}
+
 	block, err := l.client.Api.RPC.Chain.GetBlock(hash)
 	if err != nil {
 		panic(err)
@@ -203,76 +243,566 @@ func (l *listener) processBlock(hash types.Hash) error {
 		panic(err)
 	}
 
+	var callHashes map[int]multisigCall
 	for _, e := range resp.Extrinsic {
 		// Current Extrinsic { Block, Index }
 		l.currentTx.BlockNumber = BlockNumber(currentBlock)
 		l.currentTx.MultiSignTxId = MultiSignTxId(e.ExtrinsicIndex)
-		msTx := MultiSigAsMulti{
-			DestAddress: e.MultiSigAsMulti.DestAddress,
-			DestAmount:  e.MultiSigAsMulti.DestAmount,
+
+		if e.Type == polkadot.AsMultiNew || e.Type == polkadot.AsMultiApprove || e.Type == polkadot.AsMultiExecuted {
+			// The multisig events carry the call hash of each as_multi extrinsic
+			if callHashes == nil {
+				callHashes, err = l.getCallHashes(hash)
+				if err != nil {
+					return err
+				}
+			}
+			call, ok := callHashes[e.ExtrinsicIndex]
+			if !ok {
+				l.log.Warn("No multisig event found for extrinsic", "Block", currentBlock, "Index", e.ExtrinsicIndex)
+				continue
+			}
+			// A new multisig of a relayer set replaces the one of the other set with the call hash
+			if _, ok := l.relayer.setOf(call.multisig); !ok || (e.Type != polkadot.AsMultiNew && !l.tracks(call.multisig, call.callHash)) {
+				continue
+			}
+			callHash := call.callHash
+
+			if e.Type == polkadot.AsMultiNew {
+				l.log.Info("Find a MultiSign New extrinsic", "Block", currentBlock, "CallHash", callHash.Hex())
+				/// Mark New a MultiSign Transfer
+				l.markNew(e, callHash, call.multisig)
+			}
+			if e.Type == polkadot.AsMultiApprove {
+				l.log.Info("Find a MultiSign Approve extrinsic", "Block", currentBlock, "CallHash", callHash.Hex())
+				/// Mark Vote(Approve)
+				l.markVote(callHash, e)
+			}
+			if e.Type == polkadot.AsMultiExecuted {
+				l.log.Info("Find a MultiSign Executed extrinsic", "Block", currentBlock, "CallHash", callHash.Hex())
+				// Find An existing multi-signed transaction in the record, and marks for executed status
+				l.markVote(callHash, e)
+				l.markExecution(callHash, MultiSignTx{BlockNumber: BlockNumber(currentBlock), MultiSignTxId: MultiSignTxId(e.ExtrinsicIndex)})
+			}
 		}
+		if e.Type == polkadot.UtilityBatch {
+			err = l.processDeposit(currentBlock, block, e)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-		if e.Type == polkadot.AsMultiNew {
-			l.log.Info("Find a MultiSign New extrinsic", "Block", currentBlock)
-			/// Mark New a MultiSign Transfer
-			l.markNew(e)
+// processDeposit submits the deposit of a batch extrinsic transferring into the multisig
+func (l *listener) processDeposit(currentBlock int64, block *types.SignedBlock, e *models.ExtrinsicResponse) error {
+	l.log.Info("Find a MultiSign Batch Extrinsic", "Block", currentBlock)
+	sender, amount, ok := l.parseDeposit(currentBlock, e)
+	if !ok {
+		return nil
+	}
+	return l.submitDeposit(currentBlock, deposit{
+		index:  e.ExtrinsicIndex,
+		sender: sender,
+		asset:  NativeAsset,
+		amount: amount,
+		remark: l.depositRemark(block, e.ExtrinsicIndex),
+	})
+}
+
+// parseDeposit returns the sender and amount of a batch extrinsic, ok is false if it is not a transfer into a
+// multisig receiving the deposits at the block
+func (l *listener) parseDeposit(block int64, e *models.ExtrinsicResponse) (sender types.AccountID, amount *big.Int, ok bool) {
+	/// Validate whether a cross-chain transaction
+	receivePubAddress, err := utils.DecodeAddress(e.ToAddress, l.ss58Prefix)
+	if err != nil || !l.receives(types.NewAccountID(receivePubAddress), block, e.ExtrinsicIndex) {
+		return sender, nil, false
+	}
+	senderPub, err := utils.DecodeAddress(e.FromAddress, l.ss58Prefix)
+	if err != nil {
+		l.log.Warn("Deposit with an invalid sender, ignored", "Index", e.ExtrinsicIndex, "err", err)
+		return sender, nil, false
+	}
+
+	// Construct parameters of message
+	amount, ok = big.NewInt(0).SetString(e.Amount, 10)
+	if !ok {
+		fmt.Printf("parse transfer amount %v, amount.string %v\n", amount, amount.String())
+	}
+	return types.NewAccountID(senderPub), amount, true
+}
+
+// address returns the account id in the address format of the chain
+func (l *listener) address(accountId []byte) string {
+	return utils.FormatAddress(accountId, l.ss58Prefix)
+}
+
+// requeue processes the deposit of the nonce again, so that it is routed to the destination chain once more
+func (l *listener) requeue(nonce msg.Nonce) error {
+	block, index, position := DecodeDepositNonce(nonce)
+	deposits, err := l.blockDeposits(block)
+	if err != nil {
+		return err
+	}
+	for _, d := range deposits {
+		if d.index == int(index) && d.position == int(position) {
+			l.log.Info("Requeue a deposit", "DepositNonce", nonce, "Block", block, "Index", index, "Position", position, "Asset", d.asset)
+			return l.submitDeposit(int64(block), d)
 		}
-		if e.Type == polkadot.AsMultiApprove {
-			l.log.Info("Find a MultiSign Approve extrinsic", "Block", currentBlock)
-			/// Mark Vote(Approve)
-			l.markVote(msTx, e)
+	}
+	return fmt.Errorf("no deposit found at position %d of extrinsic %d-%d", position, block, index)
+}
+
+// pause stops or resumes the processing of new blocks
+func (l *listener) pause(pause bool) {
+	var v int32
+	if pause {
+		v = 1
+	}
+	atomic.StoreInt32(&l.paused, v)
+	l.log.Warn("Listener pause changed", "Paused", pause)
+}
+
+func (l *listener) isPaused() bool {
+	return atomic.LoadInt32(&l.paused) == 1
+}
+
+// processEvents drives the multisig state machine from the System.Events of the block instead of
+// the decoded extrinsics, so that only dispatched calls are taken into account.
+func (l *listener) processEvents(hash types.Hash) error {
+	header, err := l.client.Api.RPC.Chain.GetHeader(hash)
+	if err != nil {
+		return err
+	}
+	currentBlock := int64(header.Number)
+
+	events, err := l.getEvents(hash)
+	if err != nil {
+		return err
+	}
+
+	for _, evt := range events.GetMultisigNewMultisig() {
+		set, ok := l.relayer.setOf(ctypes.AccountID(evt.ID))
+		if !evt.Phase.IsApplyExtrinsic || !ok {
+			continue
 		}
+		callHash := ctypes.Hash(evt.CallHash)
+		l.log.Info("Find a Multisig.NewMultisig event", "Block", currentBlock, "CallHash", callHash.Hex())
+		l.storeMultisig(MultiSigAsMulti{
+			CallHash: callHash,
+			OriginMsTx: MultiSignTx{
+				BlockNumber:   BlockNumber(currentBlock),
+				MultiSignTxId: MultiSignTxId(evt.Phase.AsApplyExtrinsic),
+			},
+			Threshold: set.multiSignThreshold,
+			YesVote:   []ctypes.AccountID{ctypes.AccountID(evt.Who)},
+			Multisig:  set.multisig,
+		})
+	}
 
-		if e.Type == polkadot.AsMultiExecuted {
-			l.log.Info("Find a MultiSign Executed extrinsic", "Block", currentBlock)
-			// Find An existing multi-signed transaction in the record, and marks for executed status
-			l.markVote(msTx, e)
-			l.markExecution(msTx)
+	for _, evt := range events.GetMultisigApproval() {
+		if !evt.Phase.IsApplyExtrinsic || !l.tracks(ctypes.AccountID(evt.ID), ctypes.Hash(evt.CallHash)) {
+			continue
 		}
-		if e.Type == polkadot.UtilityBatch {
-			l.log.Info("Find a MultiSign Batch Extrinsic", "Block", currentBlock)
-			// Construct parameters of message
-			amount, ok := big.NewInt(0).SetString(e.Amount, 10)
-			if !ok {
-				fmt.Printf("parse transfer amount %v, amount.string %v\n", amount, amount.String())
+		callHash := ctypes.Hash(evt.CallHash)
+		l.log.Info("Find a Multisig.MultisigApproval event", "Block", currentBlock, "CallHash", callHash.Hex())
+		l.markApproval(callHash, ctypes.AccountID(evt.Who))
+	}
+
+	for _, evt := range events.GetMultisigExecuted() {
+		if !evt.Phase.IsApplyExtrinsic || !l.tracks(ctypes.AccountID(evt.ID), ctypes.Hash(evt.CallHash)) {
+			continue
+		}
+		callHash := ctypes.Hash(evt.CallHash)
+		l.markApproval(callHash, ctypes.AccountID(evt.Who))
+		execution := MultiSignTx{BlockNumber: BlockNumber(currentBlock), MultiSignTxId: MultiSignTxId(evt.Phase.AsApplyExtrinsic)}
+		if evt.Result.Ok {
+			l.log.Info("Find a Multisig.MultisigExecuted event", "Block", currentBlock, "CallHash", callHash.Hex())
+			l.markExecution(callHash, execution)
+		} else {
+			l.log.Error("Multisig executed with a failed dispatch", "Block", currentBlock, "CallHash", callHash.Hex(), "err", evt.Result.Error)
+			l.markFailure(callHash, execution)
+		}
+	}
+
+	for _, evt := range events.GetMultisigCancelled() {
+		if !evt.Phase.IsApplyExtrinsic || !l.tracks(ctypes.AccountID(evt.ID), ctypes.Hash(evt.CallHash)) {
+			continue
+		}
+		callHash := ctypes.Hash(evt.CallHash)
+		l.log.Warn("Find a Multisig.MultisigCancelled event", "Block", currentBlock, "CallHash", callHash.Hex())
+		// The multisig is gone on chain, a new one has to be opened for the call
+		l.deleteMultisig(callHash)
+	}
+
+	deposits, err := l.eventDeposits(currentBlock, hash, events)
+	if err != nil {
+		return err
+	}
+	for _, d := range deposits {
+		err = l.submitDeposit(currentBlock, d)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deposit is a transfer of an asset into the multisig
+type deposit struct {
+	index    int
+	position int // Position among the deposits into the multisig made by the extrinsic
+	sender   types.AccountID
+	asset    Asset
+	amount   *big.Int // Nil for the instances of the Uniques pallet
+	instance *big.Int // Instance of the Uniques pallet, nil for fungible assets
+	metadata []byte   // Metadata of the instance
+	remark   []byte   // The memo of the deposit
+}
+
+// blockDeposits returns the deposits into the multisig made in the block
+func (l *listener) blockDeposits(block uint64) ([]deposit, error) {
+	hash, err := l.client.Api.RPC.Chain.GetBlockHash(block)
+	if err != nil {
+		return nil, err
+	}
+	if l.useEvents {
+		events, err := l.getEvents(hash)
+		if err != nil {
+			return nil, err
+		}
+		return l.eventDeposits(int64(block), hash, events)
+	}
+
+	signed, err := l.client.Api.RPC.Chain.GetBlock(hash)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := l.client.GetBlockByNumber(int64(block))
+	if err != nil {
+		return nil, err
+	}
+	var res []deposit
+	for _, e := range resp.Extrinsic {
+		if e.Type != polkadot.UtilityBatch {
+			continue
+		}
+		sender, amount, ok := l.parseDeposit(int64(block), e)
+		if !ok {
+			continue
+		}
+		res = append(res, deposit{index: e.ExtrinsicIndex, sender: sender, asset: NativeAsset, amount: amount, remark: l.depositRemark(signed, e.ExtrinsicIndex)})
+	}
+	return res, nil
+}
+
+// eventDeposits returns the deposits of the Balances.Transfer, Assets.Transferred and Uniques.Transferred
+// events into the multisig
+func (l *listener) eventDeposits(currentBlock int64, hash types.Hash, events expand.IEventRecords) ([]deposit, error) {
+	var res []deposit
+	for _, evt := range events.GetBalancesTransfer() {
+		if !evt.Phase.IsApplyExtrinsic || !l.receives(evt.To, currentBlock, int(evt.Phase.AsApplyExtrinsic)) {
+			continue
+		}
+		l.log.Info("Find a Balances.Transfer event into the multisig", "Block", currentBlock, "Index", evt.Phase.AsApplyExtrinsic)
+		res = append(res, deposit{index: int(evt.Phase.AsApplyExtrinsic), sender: evt.From, asset: NativeAsset, amount: evt.Value.Int})
+	}
+	if assets, ok := events.(assetEvents); ok {
+		for _, evt := range assets.GetAssetsTransferred() {
+			if !evt.Phase.IsApplyExtrinsic || !l.receives(evt.To, currentBlock, int(evt.Phase.AsApplyExtrinsic)) {
+				continue
 			}
-			receiveAmount := amount
-
-			fixedFee := big.NewInt(FixedFee)
-			additionalFee := big.NewInt(0).Div(amount, big.NewInt(FeeRate))
-			fee := big.NewInt(0).Add(fixedFee, additionalFee)
-
-			actualAmount := big.NewInt(0).Sub(amount, fee)
-			sendAmount := big.NewInt(0).Mul(actualAmount, big.NewInt(oneToken))
-
-			recipient := []byte(e.Recipient)
-			depositNonce, _ := strconv.ParseInt(strconv.FormatInt(currentBlock, 10)+strconv.FormatInt(int64(e.ExtrinsicIndex), 10), 10, 64)
-
-			m := msg.NewFungibleTransfer(
-				l.chainId,
-				l.destId,
-				msg.Nonce(depositNonce),
-				sendAmount,
-				l.resourceId,
-				recipient,
-			)
-			/// Validate whether a cross-chain transaction
-			receivePubAddress, _ := ss58.DecodeToPub(e.ToAddress)
-			receiveAddress := types.NewAddressFromAccountID(receivePubAddress)
-			if receiveAddress.AsAccountID == l.multiSignAddr {
-				fmt.Printf("KSM to AKSM, Amount is %v, Fee is %v, Actual_AKSM_Amount = %v\n", receiveAmount, fee, sendAmount)
-				l.log.Info("Ready to send AKSM...", "Amount", actualAmount, "Recipient", recipient)
-				l.submitMessage(m, err)
-				if err != nil {
-					l.log.Error("Submit message to Writer", "Error", err)
-					return err
-				}
+			asset := Asset{IsAsset: true, Id: uint32(evt.AssetId)}
+			l.log.Info("Find an Assets.Transferred event into the multisig", "Block", currentBlock, "Index", evt.Phase.AsApplyExtrinsic, "Asset", asset)
+			res = append(res, deposit{index: int(evt.Phase.AsApplyExtrinsic), sender: evt.From, asset: asset, amount: evt.Amount.Int})
+		}
+		for _, evt := range assets.GetUniquesTransferred() {
+			if !evt.Phase.IsApplyExtrinsic || !l.receives(evt.To, currentBlock, int(evt.Phase.AsApplyExtrinsic)) {
+				continue
+			}
+			asset := Asset{IsUnique: true, Id: uint32(evt.ClassId)}
+			l.log.Info("Find a Uniques.Transferred event into the multisig", "Block", currentBlock, "Index", evt.Phase.AsApplyExtrinsic, "Asset", asset, "Instance", evt.InstanceId)
+			metadata, err := l.instanceMetadata(hash, evt.ClassId, evt.InstanceId)
+			if err != nil {
+				return nil, err
 			}
+			res = append(res, deposit{
+				index:    int(evt.Phase.AsApplyExtrinsic),
+				sender:   evt.From,
+				asset:    asset,
+				instance: big.NewInt(int64(evt.InstanceId)),
+				metadata: metadata,
+			})
+		}
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+
+	// The memo is only carried by the extrinsic
+	block, err := l.client.Api.RPC.Chain.GetBlock(hash)
+	if err != nil {
+		return nil, err
+	}
+	for i := range res {
+		res[i].remark = l.depositRemark(block, res[i].index)
+	}
+	return l.positionDeposits(currentBlock, res), nil
+}
+
+// positionDeposits numbers the deposits made by the same extrinsic, e.g. a batch of two transfers, so that each
+// of them gets its own nonce. The deposits past the last position of a nonce are left out, their sender has to be
+// refunded by the operator.
+func (l *listener) positionDeposits(currentBlock int64, deposits []deposit) []deposit {
+	positions := make(map[int]int)
+	res := deposits[:0]
+	for _, d := range deposits {
+		d.position = positions[d.index]
+		positions[d.index]++
+		if d.position > maxNoncePosition {
+			l.log.Error("Too many deposits in one extrinsic, deposit not bridged", "Block", currentBlock, "Index", d.index,
+				"Sender", l.address(d.sender[:]), "Asset", d.asset, "Amount", d.amount, "Instance", d.instance)
+			continue
 		}
+		res = append(res, d)
+	}
+	return res
+}
+
+// instanceMetadata returns the metadata of an instance of the Uniques pallet at the block, nil if it has none
+func (l *listener) instanceMetadata(hash types.Hash, class, instance types.U32) ([]byte, error) {
+	classKey, err := types.EncodeToBytes(class)
+	if err != nil {
+		return nil, err
+	}
+	instanceKey, err := types.EncodeToBytes(instance)
+	if err != nil {
+		return nil, err
+	}
+	key, err := types.CreateStorageKey(l.client.Meta, "Uniques", "InstanceMetadataOf", classKey, instanceKey)
+	if err != nil {
+		return nil, err
+	}
+	var metadata instanceMetadata
+	ok, err := l.client.Api.RPC.State.GetStorage(key, &metadata, hash)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return metadata.Data, nil
+}
+
+// depositRemark returns the remark in the batch of a deposit, nil if it has none
+func (l *listener) depositRemark(block *types.SignedBlock, index int) []byte {
+	if index >= len(block.Block.Extrinsics) {
+		return nil
+	}
+	remark, err := batchRemark(l.client.Meta, block.Block.Extrinsics[index].Method)
+	if err != nil {
+		l.log.Warn("Failed to read the remark of a deposit", "Block", block.Block.Header.Number, "Index", index, "err", err)
+		return nil
+	}
+	return remark
+}
+
+// validateDeposit checks that a deposit can be bridged and returns its resource, memo and fee. The
+// destination of the returned memo is set, a version 0 memo goes to the chain of the recipient prefix.
+func (l *listener) validateDeposit(asset Asset, amount *big.Int, remark []byte) (Resource, utils.Memo, FeeBreakdown, error) {
+	if amount == nil || amount.Sign() <= 0 {
+		return Resource{}, utils.Memo{}, FeeBreakdown{}, ErrInvalidAmount
 	}
+	res, ok := l.resources.ByAsset(asset)
+	if !ok || res.Asset.IsUnique {
+		return Resource{}, utils.Memo{}, FeeBreakdown{}, fmt.Errorf("%w: %s", ErrUnknownAsset, asset)
+	}
+	memo, err := l.depositMemo(res, remark)
+	if err != nil {
+		return Resource{}, utils.Memo{}, FeeBreakdown{}, err
+	}
+	fee, err := l.fees.Breakdown(res.ResourceId, Deposit, amount)
+	return res, memo, fee, err
+}
+
+// validateUniqueDeposit checks that the deposit of an instance of the Uniques pallet can be bridged and
+// returns its resource and memo. No fee is charged for the instances.
+func (l *listener) validateUniqueDeposit(asset Asset, remark []byte) (Resource, utils.Memo, error) {
+	res, ok := l.resources.ByAsset(asset)
+	if !ok || !res.Asset.IsUnique {
+		return Resource{}, utils.Memo{}, fmt.Errorf("%w: %s", ErrUnknownAsset, asset)
+	}
+	memo, err := l.depositMemo(res, remark)
+	if err != nil {
+		return Resource{}, utils.Memo{}, err
+	}
+	return res, memo, nil
+}
+
+// depositMemo decodes the memo of a deposit of the resource and checks its destination and recipient
+func (l *listener) depositMemo(res Resource, remark []byte) (utils.Memo, error) {
+	memo, err := utils.DecodeMemo(remark)
+	if err != nil {
+		return utils.Memo{}, fmt.Errorf("%w: %s", ErrInvalidRecipient, err)
+	}
+	if memo.Version == utils.MemoVersion0 {
+		memo.Dest, err = l.recipientDest(res, memo)
+		if err != nil {
+			return utils.Memo{}, err
+		}
+	}
+	prefix, ok := l.dests.Prefix(memo.Dest)
+	if !ok {
+		return utils.Memo{}, fmt.Errorf("%w: %d", ErrInvalidDest, memo.Dest)
+	}
+	// The writers of the destinations decode the recipient as a bech32 address
+	err = memo.ValidateRecipient(prefix)
+	if err != nil {
+		return utils.Memo{}, fmt.Errorf("%w: %s", ErrInvalidRecipient, err)
+	}
+	return memo, nil
+}
+
+// recipientDest selects the destination of a memo without one by the prefix of its recipient. The chain
+// of the resource is preferred when other chains share its prefix.
+func (l *listener) recipientDest(res Resource, memo utils.Memo) (msg.ChainId, error) {
+	hrp, err := memo.RecipientPrefix()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidRecipient, err)
+	}
+	if prefix, ok := l.dests.Prefix(res.Dest); ok && prefix == hrp {
+		return res.Dest, nil
+	}
+	dest, ok := l.dests.ByPrefix(hrp)
+	if !ok {
+		return 0, fmt.Errorf("%w: no chain for prefix %s", ErrInvalidDest, hrp)
+	}
+	return dest, nil
+}
+
+// rejectDeposit records a deposit that can not be bridged and hands it to the writer to be refunded
+func (l *listener) rejectDeposit(r Refund) {
+	err := l.msStore.StoreRefund(r)
+	if err != nil {
+		l.log.Error("Failed to write refund to multisig store", "DepositNonce", r.DepositNonce, "err", err)
+	}
+	select {
+	case l.refunds <- r:
+	case <-l.stop:
+	}
+}
+
+// submitDeposit constructs the message of a deposit into the multisig and sends it to the router.
+// Deposits failing the validation are refunded to the sender instead.
+func (l *listener) submitDeposit(currentBlock int64, d deposit) error {
+	m, err := l.depositMessage(currentBlock, d)
+	if errors.Is(err, ErrInvalidAmount) {
+		l.log.Warn("Deposit without a valid amount, ignored", "Block", currentBlock, "Index", d.index)
+		return nil
+	}
+	var invalid *invalidDepositError
+	if errors.As(err, &invalid) {
+		l.log.Warn("Reject a deposit that can not be bridged", "Block", currentBlock, "Index", d.index, "Position", d.position, "Sender", l.address(d.sender[:]), "err", invalid.err)
+		refund := Refund{
+			DepositNonce: m.DepositNonce,
+			Sender:       types.HexEncodeToString(d.sender[:]),
+			Asset:        d.asset,
+			Reason:       invalid.err.Error(),
+		}
+		if d.instance != nil {
+			// No fee is charged for the instances
+			refund.Amount = d.instance.String()
+		} else {
+			fee, err := l.refundFee(d.asset, d.amount)
+			if err != nil {
+				l.log.Warn("Deposit too small to be refunded, kept by the multisig", "Block", currentBlock, "Index", d.index, "Sender", l.address(d.sender[:]), "Amount", d.amount, "err", err)
+				return nil
+			}
+			refund.Amount = fee.Net.String()
+			refund.Fee = fee.Fee.String()
+		}
+		l.rejectDeposit(refund)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	l.log.Info("Ready to send AKSM...", "Sender", l.address(d.sender[:]), "Amount", d.amount, "Instance", d.instance, "Asset", d.asset, "Dest", m.Destination, "Recipient", string(m.Payload[1].([]byte)))
+	l.submitMessage(m, nil)
 	return nil
 }
 
+// refundFee deducts the refund fee of the asset from a rejected deposit. An error is returned if the amount left is
+// below the minimum of the asset, the multisig could not transfer it and the refunds would cost more than they return.
+func (l *listener) refundFee(asset Asset, amount *big.Int) (FeeBreakdown, error) {
+	// The refunds of the assets that are not bridged use the policy of the chain
+	res, _ := l.resources.ByAsset(asset)
+	return l.fees.RefundPolicy(res.ResourceId).Breakdown(amount)
+}
+
+// invalidDepositError is returned by depositMessage for the deposits that are refunded
+type invalidDepositError struct {
+	err error
+}
+
+func (e *invalidDepositError) Error() string { return e.err.Error() }
+
+func (e *invalidDepositError) Unwrap() error { return e.err }
+
+// depositMessage validates a deposit into the multisig and constructs its message. The message
+// carries the deposit nonce even if the deposit is rejected with an invalidDepositError.
+func (l *listener) depositMessage(currentBlock int64, d deposit) (msg.Message, error) {
+	depositNonce, err := DepositNonce(uint64(currentBlock), uint64(d.index), uint64(d.position))
+	if err != nil {
+		return msg.Message{}, err
+	}
+	if d.instance != nil {
+		return l.uniqueDepositMessage(depositNonce, d)
+	}
+
+	res, memo, fee, err := l.validateDeposit(d.asset, d.amount, d.remark)
+	if errors.Is(err, ErrInvalidAmount) {
+		return msg.Message{DepositNonce: depositNonce}, err
+	}
+	if err != nil {
+		return msg.Message{DepositNonce: depositNonce}, &invalidDepositError{err}
+	}
+
+	actualAmount := fee.Net
+	sendAmount, dust := l.decimals.Get(res.ResourceId).ToDest(actualAmount)
+	if dust.Sign() != 0 {
+		l.log.Warn("Deposit amount has dust that can not be bridged", "Block", currentBlock, "Index", d.index, "Dust", dust)
+	}
+	fmt.Printf("KSM to AKSM, Amount is %v, Fee is %v, Actual_AKSM_Amount = %v\n", d.amount, fee.Fee, sendAmount)
+
+	return msg.NewFungibleTransfer(
+		l.chainId,
+		memo.Dest,
+		depositNonce,
+		sendAmount,
+		res.ResourceId,
+		[]byte(memo.Recipient),
+	), nil
+}
+
+// uniqueDepositMessage validates the deposit of an instance of the Uniques pallet and constructs its
+// message, the instance is bridged as the token id and its metadata is passed on
+func (l *listener) uniqueDepositMessage(depositNonce msg.Nonce, d deposit) (msg.Message, error) {
+	res, memo, err := l.validateUniqueDeposit(d.asset, d.remark)
+	if err != nil {
+		return msg.Message{DepositNonce: depositNonce}, &invalidDepositError{err}
+	}
+
+	return msg.NewNonFungibleTransfer(
+		l.chainId,
+		memo.Dest,
+		depositNonce,
+		res.ResourceId,
+		d.instance,
+		[]byte(memo.Recipient),
+		d.metadata,
+	), nil
+}
+
 // submitMessage inserts the chainId into the msg and sends it to the router
 func (l *listener) submitMessage(m msg.Message, err error) {
 	if err != nil {
@@ -286,29 +816,86 @@ func (l *listener) submitMessage(m msg.Message, err error) {
 	}
 }
 
-func (l *listener) markExecution(msTx MultiSigAsMulti) {
-	for k, ms := range l.msTxAsMulti {
-		if !ms.Executed && ms.DestAddress == msTx.DestAddress && ms.DestAmount == msTx.DestAmount {
-			exeMsTx := l.msTxAsMulti[k]
-			exeMsTx.Executed = true
-			l.msTxAsMulti[k] = exeMsTx
-		}
+// loadMultisigs restores the unexecuted multisig transactions from the multisig store
+func (l *listener) loadMultisigs() error {
+	msTxs, err := l.msStore.LoadMultisigs()
+	if err != nil {
+		return fmt.Errorf("failed to load multisig store: %w", err)
+	}
+	l.msLock.Lock()
+	for k, ms := range msTxs {
+		l.msTxAsMulti[k] = ms
 	}
+	l.msLock.Unlock()
+	l.log.Info("Loaded multisig transactions from store", "count", len(msTxs))
+	return nil
 }
 
-func (l *listener) markVote(msTx MultiSigAsMulti, e *models.ExtrinsicResponse) {
-	for k, ms := range l.msTxAsMulti {
-		if !ms.Executed && ms.DestAddress == msTx.DestAddress && ms.DestAmount == msTx.DestAmount {
-			//l.log.Info("relayer succeed vote", "Address", e.FromAddress)
-			voteMsTx := l.msTxAsMulti[k]
-			voteMsTx.Others = append(voteMsTx.Others, e.MultiSigAsMulti.OtherSignatories)
-			l.msTxAsMulti[k] = voteMsTx
-		}
+// storeMultisig records the multisig transaction in memory and in the multisig store
+func (l *listener) storeMultisig(ms MultiSigAsMulti) {
+	l.msLock.Lock()
+	l.msTxAsMulti[ms.CallHash] = ms
+	l.msLock.Unlock()
+	err := l.msStore.StoreMultisig(ms)
+	if err != nil {
+		l.log.Error("Failed to write to multisig store", "CallHash", ms.CallHash.Hex(), "err", err)
+	}
+}
+
+// deleteMultisig forgets the multisig transaction in memory and in the multisig store
+func (l *listener) deleteMultisig(callHash ctypes.Hash) {
+	l.msLock.Lock()
+	delete(l.msTxAsMulti, callHash)
+	l.msLock.Unlock()
+	err := l.msStore.DeleteMultisig(callHash)
+	if err != nil {
+		l.log.Error("Failed to delete from multisig store", "CallHash", callHash.Hex(), "err", err)
+	}
+}
+
+// getMultisig returns the multisig transaction of the call hash
+func (l *listener) getMultisig(callHash ctypes.Hash) (MultiSigAsMulti, bool) {
+	l.msLock.RLock()
+	defer l.msLock.RUnlock()
+	ms, ok := l.msTxAsMulti[callHash]
+	return ms, ok
+}
+
+// multisigs returns a copy of every multisig transaction being tracked
+func (l *listener) multisigs() []MultiSigAsMulti {
+	l.msLock.RLock()
+	defer l.msLock.RUnlock()
+	res := make([]MultiSigAsMulti, 0, len(l.msTxAsMulti))
+	for _, ms := range l.msTxAsMulti {
+		res = append(res, ms)
+	}
+	return res
+}
+
+func (l *listener) markExecution(callHash ctypes.Hash, execution MultiSignTx) {
+	l.migrationLock.Lock()
+	defer l.migrationLock.Unlock()
+	if ms, ok := l.getMultisig(callHash); ok && !ms.Executed {
+		ms.Executed = true
+		ms.Execution = execution
+		l.storeMultisig(ms)
+	}
+	if callHash == l.migration {
+		l.markMigration(execution)
 	}
 }
 
-func (l *listener) markNew(e *models.ExtrinsicResponse) {
+func (l *listener) markVote(callHash ctypes.Hash, e *models.ExtrinsicResponse) {
+	if ms, ok := l.getMultisig(callHash); ok && !ms.Executed {
+		//l.log.Info("relayer succeed vote", "Address", e.FromAddress)
+		ms.Others = append(ms.Others, e.MultiSigAsMulti.OtherSignatories)
+		l.storeMultisig(ms)
+	}
+}
+
+func (l *listener) markNew(e *models.ExtrinsicResponse, callHash ctypes.Hash, multisig ctypes.AccountID) {
 	msTx := MultiSigAsMulti{
+		CallHash:       callHash,
 		Executed:       false,
 		Threshold:      e.MultiSigAsMulti.Threshold,
 		MaybeTimePoint: e.MultiSigAsMulti.MaybeTimePoint,
@@ -318,8 +905,157 @@ func (l *listener) markNew(e *models.ExtrinsicResponse) {
 		StoreCall:      e.MultiSigAsMulti.StoreCall,
 		MaxWeight:      e.MultiSigAsMulti.MaxWeight,
 		OriginMsTx:     l.currentTx,
+		Multisig:       multisig,
 	}
 	/// Mark voted
 	msTx.Others = append(msTx.Others, e.MultiSigAsMulti.OtherSignatories)
-	l.msTxAsMulti[l.currentTx] = msTx
+	l.storeMultisig(msTx)
+}
+
+// markApproval records the approval of the voter in the multisig transaction
+func (l *listener) markApproval(callHash ctypes.Hash, voter ctypes.AccountID) {
+	if ms, ok := l.getMultisig(callHash); ok && !ms.Executed {
+		ms.YesVote = append(ms.YesVote, voter)
+		l.storeMultisig(ms)
+	}
+}
+
+// markFailure marks the multisig transaction as executed with a failed dispatch
+func (l *listener) markFailure(callHash ctypes.Hash, execution MultiSignTx) {
+	if ms, ok := l.getMultisig(callHash); ok && !ms.Executed {
+		ms.Failed = true
+		ms.Execution = execution
+		l.storeMultisig(ms)
+	}
+}
+
+// watchMigration makes the listener record the execution of the migration call
+func (l *listener) watchMigration(callHash ctypes.Hash) {
+	l.migrationLock.Lock()
+	defer l.migrationLock.Unlock()
+	l.migration = callHash
+	// The execution may have been processed before the call was known
+	if ms, ok := l.getMultisig(callHash); ok && ms.Executed {
+		l.markMigration(ms.Execution)
+	}
+}
+
+// markMigration records the first execution of the migration, migrationLock is held by the caller
+func (l *listener) markMigration(execution MultiSignTx) {
+	if l.migrated != nil {
+		return
+	}
+	l.log.Info("The funds are migrated to the multisig of the next relayer set", "Block", execution.BlockNumber, "Index", execution.MultiSignTxId)
+	l.migrated = &execution
+	err := l.msStore.StoreMigration(execution)
+	if err != nil {
+		l.log.Error("Failed to write the migration to multisig store", "err", err)
+	}
+}
+
+// markEmptyMigration records that the multisig held nothing to migrate at the block, the deposits up to the
+// block were moved by the previous rounds
+func (l *listener) markEmptyMigration(block uint64) {
+	l.migrationLock.Lock()
+	defer l.migrationLock.Unlock()
+	l.markMigration(MultiSignTx{BlockNumber: BlockNumber(block + 1)})
+}
+
+// migrationExecution returns the extrinsic that executed the migration, nil until it is executed
+func (l *listener) migrationExecution() *MultiSignTx {
+	l.migrationLock.Lock()
+	defer l.migrationLock.Unlock()
+	return l.migrated
+}
+
+// getEvents fetches and decodes the System.Events of the block using the chain metadata
+func (l *listener) getEvents(hash types.Hash) (expand.IEventRecords, error) {
+	key, err := types.CreateStorageKey(l.client.Meta, "System", "Events", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := l.client.Api.RPC.State.GetStorageRaw(key, hash)
+	if err != nil {
+		return nil, err
+	}
+	if l.resources.HasAssets() {
+		// The chain has the Assets pallet, its events are not known to the client
+		var events assetEventRecords
+		err = types.EventRecordsRaw(*raw).DecodeEventRecords(l.client.Meta, &events)
+		if err != nil {
+			return nil, fmt.Errorf("decode event data error: %w", err)
+		}
+		return &events, nil
+	}
+	events, err := expand.DecodeEventRecords(l.client.Meta, raw.Hex(), l.client.Name)
+	if err != nil {
+		return nil, fmt.Errorf("decode event data error: %w", err)
+	}
+	return events, nil
+}
+
+// getCallHashes decodes the multisig events of the block and returns the multisig account and the call hash
+// of each multisig extrinsic
+func (l *listener) getCallHashes(hash types.Hash) (map[int]multisigCall, error) {
+	events, err := l.getEvents(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	callHashes := make(map[int]multisigCall)
+	for _, evt := range events.GetMultisigNewMultisig() {
+		if evt.Phase.IsApplyExtrinsic {
+			callHashes[int(evt.Phase.AsApplyExtrinsic)] = multisigCall{ctypes.AccountID(evt.ID), ctypes.Hash(evt.CallHash)}
+		}
+	}
+	for _, evt := range events.GetMultisigApproval() {
+		if evt.Phase.IsApplyExtrinsic {
+			callHashes[int(evt.Phase.AsApplyExtrinsic)] = multisigCall{ctypes.AccountID(evt.ID), ctypes.Hash(evt.CallHash)}
+		}
+	}
+	for _, evt := range events.GetMultisigExecuted() {
+		if evt.Phase.IsApplyExtrinsic {
+			callHashes[int(evt.Phase.AsApplyExtrinsic)] = multisigCall{ctypes.AccountID(evt.ID), ctypes.Hash(evt.CallHash)}
+		}
+	}
+	return callHashes, nil
+}
+
+// multisigCall is the multisig account and the call hash of a multisig event
+type multisigCall struct {
+	multisig ctypes.AccountID
+	callHash ctypes.Hash
+}
+
+// multisigAt returns the multisig account of the relayer set at the block, the deposits are transfers into it
+func (l *listener) multisigAt(block int64) types.AccountID {
+	return types.AccountID(l.relayer.at(uint64(block)).multisig)
+}
+
+// receives is true if a transfer into the account by the extrinsic at index in the block is a deposit. After the
+// rotation the multisig of the previous set still receives the deposits until its funds are migrated, the later
+// ones are not bridged and are left to the operator.
+func (l *listener) receives(account types.AccountID, block int64, index int) bool {
+	if account == l.multisigAt(block) {
+		return true
+	}
+	if l.relayer.rotation == nil || account != types.AccountID(l.relayer.multisig) {
+		return false
+	}
+	migrated := l.migrationExecution()
+	if migrated == nil || block < int64(migrated.BlockNumber) || (block == int64(migrated.BlockNumber) && index < int(migrated.MultiSignTxId)) {
+		return true
+	}
+	l.log.Error("Transfer into the multisig of the previous relayer set after the migration, it is not bridged", "Block", block, "Index", index)
+	return false
+}
+
+// tracks is true if the multisig account is controlled by a relayer set and the multisig transaction of the
+// call hash, if it is known, belongs to the account. The sets may open multisigs with the same call hash.
+func (l *listener) tracks(multisig ctypes.AccountID, callHash ctypes.Hash) bool {
+	if _, ok := l.relayer.setOf(multisig); !ok {
+		return false
+	}
+	ms, ok := l.getMultisig(callHash)
+	return !ok || ms.isOf(multisig)
 }
@@ -0,0 +1,203 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ChainSafe/log15"
+	utils "github.com/rjman-self/Platdot/shared/substrate"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+func TestValidateDeposit(t *testing.T) {
+	resources := NewResourceRegistry()
+	native := Resource{ResourceId: msg.ResourceId{1}, Asset: NativeAsset, Dest: 1}
+	usdt := Resource{ResourceId: msg.ResourceId{2}, Asset: Asset{IsAsset: true, Id: 1984}, Dest: 2}
+	for _, res := range []Resource{native, usdt} {
+		if err := resources.Add(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dests := NewDestRegistry()
+	for id, prefix := range map[msg.ChainId]string{1: "atp", 2: "atp", 3: "lat"} {
+		if err := dests.Add(id, prefix); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l := &listener{fees: NewFeeSchedule(FeePolicy{Fixed: big.NewInt(100)}), resources: resources, dests: dests}
+	recipient := []byte("atp1l7fmg5cgl4qhmucr6eg44vzdn6y6w5x2q695d9")
+	latRecipient := []byte("lat1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5c5xamu")
+
+	testCases := []struct {
+		name      string
+		asset     Asset
+		amount    *big.Int
+		recipient []byte
+		resource  Resource
+		dest      msg.ChainId
+		err       error
+	}{
+		{"valid", NativeAsset, big.NewInt(1000), recipient, native, 1, nil},
+		{"valid asset", usdt.Asset, big.NewInt(1000), recipient, usdt, 2, nil},
+		{"recipient prefix", NativeAsset, big.NewInt(1000), latRecipient, native, 3, nil},
+		{"valid memo", NativeAsset, big.NewInt(1000), utils.NewMemo(1, string(recipient), []byte{1}).Encode(), native, 1, nil},
+		{"memo to another chain", NativeAsset, big.NewInt(1000), utils.NewMemo(2, string(recipient), nil).Encode(), native, 2, nil},
+		{"memo to unknown chain", NativeAsset, big.NewInt(1000), utils.NewMemo(4, string(recipient), nil).Encode(), Resource{}, 0, ErrInvalidDest},
+		{"memo prefix", NativeAsset, big.NewInt(1000), utils.NewMemo(3, string(recipient), nil).Encode(), Resource{}, 0, ErrInvalidRecipient},
+		{"memo version", NativeAsset, big.NewInt(1000), []byte("platdot:2:1:" + string(recipient)), Resource{}, 0, ErrInvalidRecipient},
+		{"testnet recipient", NativeAsset, big.NewInt(1000), []byte("atx1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq89qwkc"), Resource{}, 0, ErrInvalidDest},
+		{"no amount", NativeAsset, nil, recipient, Resource{}, 0, ErrInvalidAmount},
+		{"zero amount", NativeAsset, big.NewInt(0), recipient, Resource{}, 0, ErrInvalidAmount},
+		{"unknown asset", Asset{IsAsset: true, Id: 8}, big.NewInt(1000), recipient, Resource{}, 0, ErrUnknownAsset},
+		{"no recipient", NativeAsset, big.NewInt(1000), nil, Resource{}, 0, ErrInvalidRecipient},
+		{"malformed recipient", NativeAsset, big.NewInt(1000), []byte("atp1l7fmg5cgl4qhmucr6eg44vzdn6y6w5x2q695d8"), Resource{}, 0, ErrInvalidRecipient},
+		{"hex recipient", NativeAsset, big.NewInt(1000), []byte("0xff93B45308FD417dF303D6515aB04D9e89a750Ca"), Resource{}, 0, ErrInvalidRecipient},
+		{"below fee", NativeAsset, big.NewInt(100), recipient, native, 1, ErrAmountBelowFee},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, memo, _, err := l.validateDeposit(tc.asset, tc.amount, tc.recipient)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Got: %v Expected: %v", err, tc.err)
+			}
+			if res != tc.resource {
+				t.Fatalf("Got: %v Expected: %v", res, tc.resource)
+			}
+			if memo.Dest != tc.dest {
+				t.Fatalf("Got: %d Expected: %d", memo.Dest, tc.dest)
+			}
+		})
+	}
+}
+
+func TestValidateUniqueDeposit(t *testing.T) {
+	resources := NewResourceRegistry()
+	native := Resource{ResourceId: msg.ResourceId{1}, Asset: NativeAsset, Dest: 1}
+	nft := Resource{ResourceId: msg.ResourceId{3}, Asset: Asset{IsUnique: true, Id: 7}, Dest: 1}
+	for _, res := range []Resource{native, nft} {
+		if err := resources.Add(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dests := NewDestRegistry()
+	if err := dests.Add(1, "atp"); err != nil {
+		t.Fatal(err)
+	}
+	l := &listener{fees: NewFeeSchedule(FeePolicy{Fixed: big.NewInt(100)}), resources: resources, dests: dests}
+	recipient := []byte("atp1l7fmg5cgl4qhmucr6eg44vzdn6y6w5x2q695d9")
+
+	testCases := []struct {
+		name      string
+		asset     Asset
+		recipient []byte
+		resource  Resource
+		err       error
+	}{
+		{"valid", nft.Asset, recipient, nft, nil},
+		{"valid memo", nft.Asset, utils.NewMemo(1, string(recipient), nil).Encode(), nft, nil},
+		{"unknown class", Asset{IsUnique: true, Id: 8}, recipient, Resource{}, ErrUnknownAsset},
+		{"fungible asset", NativeAsset, recipient, Resource{}, ErrUnknownAsset},
+		{"memo to unknown chain", nft.Asset, utils.NewMemo(2, string(recipient), nil).Encode(), Resource{}, ErrInvalidDest},
+		{"no recipient", nft.Asset, nil, Resource{}, ErrInvalidRecipient},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, _, err := l.validateUniqueDeposit(tc.asset, tc.recipient)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Got: %v Expected: %v", err, tc.err)
+			}
+			if res != tc.resource {
+				t.Fatalf("Got: %v Expected: %v", res, tc.resource)
+			}
+		})
+	}
+
+	// An instance is not a fungible deposit
+	_, _, _, err := l.validateDeposit(nft.Asset, big.NewInt(1000), recipient)
+	if !errors.Is(err, ErrUnknownAsset) {
+		t.Fatalf("Got: %v Expected: %v", err, ErrUnknownAsset)
+	}
+}
+
+func TestDepositsOfOneExtrinsic(t *testing.T) {
+	resources := NewResourceRegistry()
+	native := Resource{ResourceId: msg.ResourceId{1}, Asset: NativeAsset, Dest: 1}
+	usdt := Resource{ResourceId: msg.ResourceId{2}, Asset: Asset{IsAsset: true, Id: 1984}, Dest: 1}
+	for _, res := range []Resource{native, usdt} {
+		if err := resources.Add(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dests := NewDestRegistry()
+	if err := dests.Add(1, "atp"); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir(os.TempDir(), "multisig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewMultisigStore(dir, 1, "relayer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &listener{
+		fees:      NewFeeSchedule(FeePolicy{Fixed: big.NewInt(100)}),
+		decimals:  NewDecimalsRegistry(DefaultDecimals),
+		resources: resources,
+		dests:     dests,
+		msStore:   store,
+		refunds:   make(chan Refund, 2),
+		log:       log15.New(),
+	}
+	recipient := []byte("atp1l7fmg5cgl4qhmucr6eg44vzdn6y6w5x2q695d9")
+
+	// A batch of a native and an asset transfer into the multisig, followed by a transfer of another extrinsic
+	deposits := l.positionDeposits(10, []deposit{
+		{index: 2, asset: NativeAsset, amount: big.NewInt(1000), remark: recipient},
+		{index: 2, asset: usdt.Asset, amount: big.NewInt(1000), remark: recipient},
+		{index: 3, asset: NativeAsset, amount: big.NewInt(1000), remark: recipient},
+	})
+	nonces := make(map[msg.Nonce]bool)
+	for i, expected := range []uint64{0, 1, 0} {
+		m, err := l.depositMessage(10, deposits[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nonces[m.DepositNonce] {
+			t.Fatalf("Deposit %d reuses nonce %d", i, m.DepositNonce)
+		}
+		nonces[m.DepositNonce] = true
+		block, index, position := DecodeDepositNonce(m.DepositNonce)
+		if block != 10 || index != uint64(deposits[i].index) || position != expected {
+			t.Fatalf("Got: %d-%d-%d Expected: %d-%d-%d", block, index, position, 10, deposits[i].index, expected)
+		}
+	}
+
+	// Both deposits of an extrinsic without a memo are refunded
+	refunded := l.positionDeposits(10, []deposit{
+		{index: 2, asset: NativeAsset, amount: big.NewInt(KSM)},
+		{index: 2, asset: NativeAsset, amount: big.NewInt(KSM)},
+	})
+	for _, d := range refunded {
+		err = l.submitDeposit(10, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	refunds, err := store.LoadRefunds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refunds) != 2 {
+		t.Fatalf("Got: %d Expected: %d", len(refunds), 2)
+	}
+}
@@ -16,8 +16,10 @@ type MultiSignTx struct {
 }
 
 type MultiSigAsMulti struct {
+	CallHash         types.Hash
 	OriginMsTx       MultiSignTx
 	Executed         bool
+	Failed           bool
 	Threshold        uint16
 	Others  		 []OtherSignatories
 	MaybeTimePoint   expand.TimePointSafe32
@@ -27,4 +29,11 @@ type MultiSigAsMulti struct {
 	MaxWeight        uint64
 	DepositNonce     msg.Nonce
 	YesVote          []types.AccountID
+	Multisig         types.AccountID // The multisig account the transaction is made with
+	Execution        MultiSignTx     // The extrinsic that executed the call
+}
+
+// isOf is true if the transaction belongs to the multisig account
+func (ms MultiSigAsMulti) isOf(multisig types.AccountID) bool {
+	return ms.Multisig == multisig
 }
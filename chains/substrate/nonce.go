@@ -0,0 +1,51 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"fmt"
+
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+// The deposit nonce of a substrate-originated transfer is the block number of the deposit shifted left
+// by NonceIndexBits+NoncePositionBits, or-ed with the index of the extrinsic in the block and the position
+// of the deposit among the deposits into the multisig made by the extrinsic, e.g. a batch of two transfers:
+//
+//	nonce = block<<24 | index<<8 | position
+//
+// It is unique per deposit as long as the block number fits in 40 bits, the index in 16 bits and the
+// position in 8 bits.
+//
+// Nonces made before this scheme concatenated the decimal strings of the block number and the index.
+// For a block B they are below B*1000+999, which is far below B<<24, so the nonces of the blocks
+// processed after an upgrade never collide with the legacy nonces of the proposals still in flight.
+// Legacy nonces can not be decoded with DecodeDepositNonce.
+const NonceIndexBits = 16
+const NoncePositionBits = 8
+
+const maxNoncePosition = 1<<NoncePositionBits - 1
+const maxNonceIndex = 1<<NonceIndexBits - 1
+const maxNonceBlock = 1<<(64-NonceIndexBits-NoncePositionBits) - 1
+
+// DepositNonce returns the nonce of the deposit at position of the deposits made by the extrinsic at index of block
+func DepositNonce(block, index, position uint64) (msg.Nonce, error) {
+	if position > maxNoncePosition {
+		return 0, fmt.Errorf("deposit position %d does not fit in a deposit nonce", position)
+	}
+	if index > maxNonceIndex {
+		return 0, fmt.Errorf("extrinsic index %d does not fit in a deposit nonce", index)
+	}
+	if block > maxNonceBlock {
+		return 0, fmt.Errorf("block %d does not fit in a deposit nonce", block)
+	}
+	return msg.Nonce(block<<(NonceIndexBits+NoncePositionBits) | index<<NoncePositionBits | position), nil
+}
+
+// DecodeDepositNonce returns the block number, the extrinsic index and the position of the deposit the nonce was
+// made for. It maps the nonce of a proposal on the EVM chain back to the substrate extrinsic that originated it.
+func DecodeDepositNonce(nonce msg.Nonce) (block, index, position uint64) {
+	n := uint64(nonce)
+	return n >> (NonceIndexBits + NoncePositionBits), n >> NoncePositionBits & maxNonceIndex, n & maxNoncePosition
+}
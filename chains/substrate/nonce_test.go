@@ -0,0 +1,52 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"testing"
+
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+func TestDepositNonce(t *testing.T) {
+	testCases := []struct {
+		block    uint64
+		index    uint64
+		position uint64
+		nonce    msg.Nonce
+	}{
+		{0, 0, 0, 0},
+		{12, 34, 0, 12<<24 | 34<<8},
+		{123, 4, 0, 123<<24 | 4<<8},
+		{8000000, 2, 1, 8000000<<24 | 2<<8 | 1},
+		{maxNonceBlock, maxNonceIndex, maxNoncePosition, 1<<64 - 1},
+	}
+
+	for _, tc := range testCases {
+		nonce, err := DepositNonce(tc.block, tc.index, tc.position)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nonce != tc.nonce {
+			t.Fatalf("Got: %d Expected: %d", nonce, tc.nonce)
+		}
+		block, index, position := DecodeDepositNonce(nonce)
+		if block != tc.block || index != tc.index || position != tc.position {
+			t.Fatalf("Got: %d-%d-%d Expected: %d-%d-%d", block, index, position, tc.block, tc.index, tc.position)
+		}
+	}
+
+	_, err := DepositNonce(1, 0, maxNoncePosition+1)
+	if err == nil {
+		t.Fatal("Expected error for position overflow")
+	}
+	_, err = DepositNonce(1, maxNonceIndex+1, 0)
+	if err == nil {
+		t.Fatal("Expected error for index overflow")
+	}
+	_, err = DepositNonce(maxNonceBlock+1, 0, 0)
+	if err == nil {
+		t.Fatal("Expected error for block overflow")
+	}
+}
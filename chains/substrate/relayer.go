@@ -1,16 +1,31 @@
 package substrate
 
 import (
+	"fmt"
+
 	"github.com/centrifuge/go-substrate-rpc-client/v2/signature"
 	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
+	"github.com/rjman-self/Platdot/chains"
+	utils "github.com/rjman-self/Platdot/shared/substrate"
 )
 
+var _ chains.MultisigRelayers = &Chain{}
+
 type Relayer struct {
 	kr                 signature.KeyringPair
 	otherSignatories   []types.AccountID
 	totalRelayers      uint64
 	multiSignThreshold uint16
-	currentRelayer     uint64
+	currentRelayer     uint64          // Zero if the relayer is not a signatory of the set
+	multisig           types.AccountID // The multisig account of the relayer set
+	rotation           *Rotation       // The relayer set taking over the multisig, nil without a rotation
+}
+
+// Rotation is the relayer set the relayers agreed on, it takes over from the block on and the funds
+// of the multisig are moved to the multisig of the set
+type Rotation struct {
+	Block uint64
+	Next  Relayer
 }
 
 func NewRelayer(kr signature.KeyringPair, otherSignatories []types.AccountID, totalRelayers uint64,
@@ -23,3 +38,71 @@ func NewRelayer(kr signature.KeyringPair, otherSignatories []types.AccountID, to
 		currentRelayer:     currentRelayer,
 	}
 }
+
+// isMember is true if the relayer is a signatory of the set and votes for its multisig
+func (r Relayer) isMember() bool {
+	return r.currentRelayer != 0
+}
+
+// signatories returns every signatory of the set
+func (r Relayer) signatories() []types.AccountID {
+	if !r.isMember() {
+		return r.otherSignatories
+	}
+	return append([]types.AccountID{types.NewAccountID(r.kr.PublicKey)}, r.otherSignatories...)
+}
+
+// deriveMultisig returns the account of the Multisig pallet of the signatories and the threshold of the set
+func (r Relayer) deriveMultisig() (types.AccountID, error) {
+	return utils.MultisigAccountID(r.signatories(), r.multiSignThreshold)
+}
+
+// checkMultisig returns an error if the multisig of the set is not the account of its signatories and threshold
+func (r Relayer) checkMultisig(ss58Prefix uint16) error {
+	derived, err := r.deriveMultisig()
+	if err != nil {
+		return fmt.Errorf("failed to derive the multisig of the relayers: %w", err)
+	}
+	if derived != r.multisig {
+		return fmt.Errorf("MultiSignAddress %s is not the multisig %s of the relayers and the threshold",
+			utils.FormatAddress(r.multisig[:], ss58Prefix), utils.FormatAddress(derived[:], ss58Prefix))
+	}
+	return nil
+}
+
+// at returns the relayer set that controls the multisig at the block height
+func (r Relayer) at(block uint64) Relayer {
+	if r.rotation != nil && block >= r.rotation.Block {
+		return r.rotation.Next
+	}
+	return r
+}
+
+// setOf returns the relayer set of the multisig account
+func (r Relayer) setOf(multisig types.AccountID) (Relayer, bool) {
+	if r.multisig == multisig {
+		return r, true
+	}
+	if r.rotation != nil && r.rotation.Next.multisig == multisig {
+		return r.rotation.Next, true
+	}
+	return Relayer{}, false
+}
+
+// relayerSet returns the signatories and the threshold of the set
+func (r Relayer) relayerSet() chains.RelayerSet {
+	set := chains.RelayerSet{Threshold: r.multiSignThreshold}
+	for _, s := range r.signatories() {
+		set.Signatories = append(set.Signatories, s)
+	}
+	return set
+}
+
+// RelayerSets returns the relayer set of the config, followed by the next set if a rotation is configured
+func (c *Chain) RelayerSets() []chains.RelayerSet {
+	sets := []chains.RelayerSet{c.writer.relayer.relayerSet()}
+	if rotation := c.writer.relayer.rotation; rotation != nil {
+		sets = append(sets, rotation.Next.relayerSet())
+	}
+	return sets
+}
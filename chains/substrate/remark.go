@@ -0,0 +1,96 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"bytes"
+	"fmt"
+
+	utils "github.com/rjman-self/Platdot/shared/substrate"
+	"github.com/rjmand/go-substrate-rpc-client/v2/scale"
+	"github.com/rjmand/go-substrate-rpc-client/v2/types"
+)
+
+// batchRemark returns the remark of the System.remark call in the Utility.batch of a deposit. The calls of
+// the batch are decoded with the metadata, a deposit batch only holds the transfer and the remark.
+func batchRemark(meta *types.Metadata, call types.Call) ([]byte, error) {
+	if !isCall(meta, call.CallIndex, string(utils.UtilityBatch)) && !isCall(meta, call.CallIndex, string(utils.UtilityBatchAll)) {
+		return nil, fmt.Errorf("extrinsic is not a batch")
+	}
+
+	decoder := scale.NewDecoder(bytes.NewReader(call.Args))
+	n, err := decoder.DecodeUintCompact()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < n.Uint64(); i++ {
+		var index types.CallIndex
+		err = decoder.Decode(&index)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case isCall(meta, index, string(utils.SystemRemark)):
+			var remark types.Bytes
+			err = decoder.Decode(&remark)
+			return remark, err
+		case isCall(meta, index, string(utils.BalancesTransferMethod)), isCall(meta, index, string(utils.BalancesTransferKeepAliveMethod)):
+			err = skipMultiAddress(decoder)
+			if err == nil {
+				_, err = decoder.DecodeUintCompact()
+			}
+		case isCall(meta, index, string(utils.AssetsTransferMethod)):
+			_, err = decoder.DecodeUintCompact()
+			if err == nil {
+				err = skipMultiAddress(decoder)
+			}
+			if err == nil {
+				_, err = decoder.DecodeUintCompact()
+			}
+		case isCall(meta, index, string(utils.UniquesTransferMethod)):
+			_, err = decoder.DecodeUintCompact()
+			if err == nil {
+				_, err = decoder.DecodeUintCompact()
+			}
+			if err == nil {
+				err = skipMultiAddress(decoder)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected call %d.%d in batch", index.SectionIndex, index.MethodIndex)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("no remark in batch")
+}
+
+func isCall(meta *types.Metadata, index types.CallIndex, call string) bool {
+	expected, err := meta.FindCallIndex(call)
+	return err == nil && expected == index
+}
+
+// skipMultiAddress reads a MultiAddress, the destination of the transfers
+func skipMultiAddress(decoder *scale.Decoder) error {
+	tag, err := decoder.ReadOneByte()
+	if err != nil {
+		return err
+	}
+	switch tag {
+	case 0, 3:
+		var id [32]byte
+		return decoder.Decode(&id)
+	case 1:
+		_, err = decoder.DecodeUintCompact()
+		return err
+	case 2:
+		var raw []byte
+		return decoder.Decode(&raw)
+	case 4:
+		var addr [20]byte
+		return decoder.Decode(&addr)
+	}
+	return fmt.Errorf("invalid MultiAddress variant %d", tag)
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"errors"
+
+	"github.com/rjman-self/Platdot/chains"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+var _ chains.Replayer = &Chain{}
+
+func (c *Chain) Replay(block uint64) ([]msg.Message, error) {
+	return c.listener.replay(block)
+}
+
+// ProposalHash is the hash of the redeem call the multisig executes for the message
+func (c *Chain) ProposalHash(m msg.Message) ([32]byte, error) {
+	call, err := c.writer.redeemCall(m)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return CallHash(call), nil
+}
+
+// replay returns the messages of the deposits into the multisig made in block. Deposits the
+// listener would refund are left out, their refund is driven by the writer.
+func (l *listener) replay(block uint64) ([]msg.Message, error) {
+	deposits, err := l.blockDeposits(block)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []msg.Message
+	for _, d := range deposits {
+		m, err := l.depositMessage(int64(block), d)
+		var invalid *invalidDepositError
+		if errors.Is(err, ErrInvalidAmount) || errors.As(err, &invalid) {
+			l.log.Warn("Deposit can not be bridged, not replayed", "Block", block, "Index", d.index, "err", err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.Source = l.chainId
+		res = append(res, m)
+	}
+	return res, nil
+}
@@ -0,0 +1,250 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rjman-self/Platdot/chains"
+	utils "github.com/rjman-self/Platdot/shared/substrate"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+var ErrUnknownAsset = errors.New("asset is not bridged")
+
+var _ chains.BridgedResources = &Chain{}
+
+// GenericCalls are the calls a generic resource can execute. The multisig executes the call with the metadata of the
+// deposit as its only argument, so only calls that can not move the funds of the multisig are allowed.
+var GenericCalls = map[string]bool{
+	string(utils.SystemRemark):          true,
+	string(utils.SystemRemarkWithEvent): true,
+}
+
+// Resource binds an asset of the substrate chain to a resource id, the chain its deposits are
+// bridged to and the handler of the resource on that chain, checked against the bridge of the chain
+// at startup. A generic resource has no asset, its transfers are executed as the call.
+type Resource struct {
+	ResourceId msg.ResourceId
+	Asset      Asset
+	Dest       msg.ChainId
+	Handler    common.Address
+	Call       string // Pallet.method of a generic resource
+}
+
+// IsGeneric returns whether the resource executes the generic transfers
+func (r Resource) IsGeneric() bool {
+	return r.Call != ""
+}
+
+// ResourceRegistry is the table of the resources bridged by the chain
+type ResourceRegistry struct {
+	resources map[msg.ResourceId]Resource
+	assets    map[Asset]msg.ResourceId // The resources of the assets, without the generic resources
+}
+
+func NewResourceRegistry() *ResourceRegistry {
+	return &ResourceRegistry{
+		resources: make(map[msg.ResourceId]Resource),
+		assets:    make(map[Asset]msg.ResourceId),
+	}
+}
+
+// Add registers a resource, a resource id and an asset can only be bridged once
+func (r *ResourceRegistry) Add(res Resource) error {
+	if _, ok := r.resources[res.ResourceId]; ok {
+		return fmt.Errorf("resource %s is registered twice", res.ResourceId.Hex())
+	}
+	if res.IsGeneric() {
+		r.resources[res.ResourceId] = res
+		return nil
+	}
+	if other, ok := r.assets[res.Asset]; ok {
+		return fmt.Errorf("%s is bridged by both resource %s and %s", res.Asset, other.Hex(), res.ResourceId.Hex())
+	}
+	r.resources[res.ResourceId] = res
+	r.assets[res.Asset] = res.ResourceId
+	return nil
+}
+
+func (r *ResourceRegistry) ByResource(id msg.ResourceId) (Resource, bool) {
+	res, ok := r.resources[id]
+	return res, ok
+}
+
+func (r *ResourceRegistry) ByAsset(asset Asset) (Resource, bool) {
+	id, ok := r.assets[asset]
+	if !ok {
+		return Resource{}, false
+	}
+	return r.resources[id], true
+}
+
+// HasDest returns whether the deposits of a resource are bridged to the chain
+func (r *ResourceRegistry) HasDest(dest msg.ChainId) bool {
+	for _, res := range r.resources {
+		if res.Dest == dest {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAssets returns whether an asset of the Assets pallet or a class of the Uniques pallet is bridged
+func (r *ResourceRegistry) HasAssets() bool {
+	for asset := range r.assets {
+		if asset.IsAsset || asset.IsUnique {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns the resources ordered by resource id
+func (r *ResourceRegistry) List() []Resource {
+	res := make([]Resource, 0, len(r.resources))
+	for _, v := range r.resources {
+		res = append(res, v)
+	}
+	sort.Slice(res, func(i, j int) bool { return bytes.Compare(res[i].ResourceId[:], res[j].ResourceId[:]) < 0 })
+	return res
+}
+
+// ResourceHandlers returns the resources of the config with a handler on their destination
+func (c *Chain) ResourceHandlers() []chains.ResourceHandler {
+	var handlers []chains.ResourceHandler
+	for _, res := range c.listener.resources.List() {
+		if res.Handler == (common.Address{}) {
+			continue
+		}
+		handlers = append(handlers, chains.ResourceHandler{ResourceId: res.ResourceId, Dest: res.Dest, Handler: res.Handler})
+	}
+	return handlers
+}
+
+// parseResourceOpts reads the resource table from the options Resource.<resourceId>.asset, .dest, .handler and .call.
+// The asset is "native", the id of an asset of the Assets pallet or uniques:<class> for the instances of a
+// class of the Uniques pallet, the dest defaults to DestId. A resource with a Pallet.method call of GenericCalls has
+// no asset, its generic transfers are executed as the call. Without assets in the table the native token is bridged
+// with the ResourceId and DestId options.
+func parseResourceOpts(opts map[string]string) (*ResourceRegistry, error) {
+	var defaultDest *msg.ChainId
+	if id, ok := opts["DestId"]; ok {
+		d, err := strconv.ParseUint(id, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse DestId: %w", err)
+		}
+		dest := msg.ChainId(d)
+		defaultDest = &dest
+	}
+
+	entries := make(map[msg.ResourceId]*Resource)
+	var order []msg.ResourceId
+	hasDest := make(map[msg.ResourceId]bool)
+	hasAsset := make(map[msg.ResourceId]bool)
+	for k, v := range opts {
+		if !strings.HasPrefix(k, "Resource.") {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(k, "Resource."), ".")
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], "0x") || len(parts[0]) != 66 {
+			return nil, fmt.Errorf("invalid resource option %s", k)
+		}
+		rId := msg.ResourceIdFromSlice(common.FromHex(parts[0]))
+		e, ok := entries[rId]
+		if !ok {
+			e = &Resource{ResourceId: rId}
+			entries[rId] = e
+			order = append(order, rId)
+		}
+
+		switch parts[1] {
+		case "asset":
+			hasAsset[rId] = true
+			if v == "native" {
+				e.Asset = NativeAsset
+				continue
+			}
+			if strings.HasPrefix(v, "uniques:") {
+				class, err := strconv.ParseUint(strings.TrimPrefix(v, "uniques:"), 10, 32)
+				if err != nil {
+					return nil, fmt.Errorf("invalid class %s in option %s", v, k)
+				}
+				e.Asset = Asset{IsUnique: true, Id: uint32(class)}
+				continue
+			}
+			id, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid asset %s in option %s", v, k)
+			}
+			e.Asset = Asset{IsAsset: true, Id: uint32(id)}
+		case "dest":
+			d, err := strconv.ParseUint(v, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid dest %s in option %s", v, k)
+			}
+			e.Dest = msg.ChainId(d)
+			hasDest[rId] = true
+		case "handler":
+			if !common.IsHexAddress(v) {
+				return nil, fmt.Errorf("invalid handler %s in option %s", v, k)
+			}
+			e.Handler = common.HexToAddress(v)
+		case "call":
+			if !GenericCalls[v] {
+				return nil, fmt.Errorf("call %s in option %s is not one of the generic calls", v, k)
+			}
+			e.Call = v
+		default:
+			return nil, fmt.Errorf("invalid resource option %s", k)
+		}
+	}
+
+	assets := 0
+	for _, e := range entries {
+		if !e.IsGeneric() {
+			assets++
+		}
+	}
+	r := NewResourceRegistry()
+	if assets == 0 {
+		rId := msg.ResourceId{}
+		if resource, ok := opts["ResourceId"]; ok {
+			rId = msg.ResourceIdFromSlice(common.FromHex(resource))
+		}
+		res := Resource{ResourceId: rId, Asset: NativeAsset}
+		if defaultDest != nil {
+			res.Dest = *defaultDest
+		}
+		err := r.Add(res)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(order, func(i, j int) bool { return bytes.Compare(order[i][:], order[j][:]) < 0 })
+	for _, rId := range order {
+		e := entries[rId]
+		if e.IsGeneric() && hasAsset[rId] {
+			return nil, fmt.Errorf("generic resource %s has an asset", rId.Hex())
+		}
+		if !hasDest[rId] {
+			if defaultDest == nil {
+				return nil, fmt.Errorf("no dest for resource %s", rId.Hex())
+			}
+			e.Dest = *defaultDest
+		}
+		err := r.Add(*e)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+const (
+	ksmResource  = "0x0000000000000000000000000000000000000000000000000000000000000001"
+	usdtResource = "0x0000000000000000000000000000000000000000000000000000000000000002"
+	nftResource  = "0x0000000000000000000000000000000000000000000000000000000000000003"
+	callResource = "0x0000000000000000000000000000000000000000000000000000000000000004"
+)
+
+func TestParseResourceOpts(t *testing.T) {
+	r, err := parseResourceOpts(map[string]string{
+		"DestId":                               "1",
+		"Resource." + ksmResource + ".asset":   "native",
+		"Resource." + ksmResource + ".handler": "0x3f709398808af36ADBA86ACC617FeB7F5B7B193E",
+		"Resource." + usdtResource + ".asset":  "1984",
+		"Resource." + usdtResource + ".dest":   "3",
+		"Resource." + nftResource + ".asset":   "uniques:7",
+		"Resource." + callResource + ".call":   "System.remark",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Resource{
+		{
+			ResourceId: msg.ResourceIdFromSlice(common.FromHex(ksmResource)),
+			Asset:      NativeAsset,
+			Dest:       1,
+			Handler:    common.HexToAddress("0x3f709398808af36ADBA86ACC617FeB7F5B7B193E"),
+		},
+		{
+			ResourceId: msg.ResourceIdFromSlice(common.FromHex(usdtResource)),
+			Asset:      Asset{IsAsset: true, Id: 1984},
+			Dest:       3,
+		},
+		{
+			ResourceId: msg.ResourceIdFromSlice(common.FromHex(nftResource)),
+			Asset:      Asset{IsUnique: true, Id: 7},
+			Dest:       1,
+		},
+		{
+			ResourceId: msg.ResourceIdFromSlice(common.FromHex(callResource)),
+			Dest:       1,
+			Call:       "System.remark",
+		},
+	}
+	list := r.List()
+	if len(list) != len(expected) {
+		t.Fatalf("Got: %d Expected: %d", len(list), len(expected))
+	}
+	for i := range expected {
+		if list[i] != expected[i] {
+			t.Fatalf("Got: %v Expected: %v", list[i], expected[i])
+		}
+		if expected[i].IsGeneric() {
+			continue
+		}
+		res, ok := r.ByAsset(expected[i].Asset)
+		if !ok || res != expected[i] {
+			t.Fatalf("Got: %v Expected: %v", res, expected[i])
+		}
+	}
+	if !r.HasAssets() || !r.HasDest(3) || r.HasDest(2) {
+		t.Fatal("Unexpected assets or destinations")
+	}
+}
+
+func TestParseResourceOpts_Legacy(t *testing.T) {
+	r, err := parseResourceOpts(map[string]string{"DestId": "2", "ResourceId": ksmResource})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, ok := r.ByAsset(NativeAsset)
+	if !ok || res.Dest != 2 || res.ResourceId.Hex() != ksmResource[2:] {
+		t.Fatalf("Got: %v Expected the native token bridged to 2 with %s", res, ksmResource)
+	}
+	if r.HasAssets() {
+		t.Fatal("Expected no assets")
+	}
+}
+
+func TestParseResourceOpts_Generic(t *testing.T) {
+	r, err := parseResourceOpts(map[string]string{"DestId": "2", "ResourceId": ksmResource, "Resource." + callResource + ".call": "System.remark_with_event"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The native token is still bridged with the legacy options
+	res, ok := r.ByAsset(NativeAsset)
+	if !ok || res.ResourceId.Hex() != ksmResource[2:] || res.IsGeneric() {
+		t.Fatalf("Got: %v Expected the native token bridged with %s", res, ksmResource)
+	}
+	res, ok = r.ByResource(msg.ResourceIdFromSlice(common.FromHex(callResource)))
+	if !ok || res.Call != "System.remark_with_event" {
+		t.Fatalf("Got: %v Expected the generic resource %s", res, callResource)
+	}
+}
+
+func TestParseResourceOpts_Invalid(t *testing.T) {
+	for _, opts := range []map[string]string{
+		{"Resource." + ksmResource + ".asset": "native"},
+		{"DestId": "1", "Resource." + ksmResource + ".asset": "ksm"},
+		{"DestId": "1", "Resource." + ksmResource + ".asset": "uniques:"},
+		{"DestId": "1", "Resource." + callResource + ".call": "remark"},
+		// Calls that can move the funds of the multisig
+		{"DestId": "1", "Resource." + callResource + ".call": "Example.remark"},
+		{"DestId": "1", "Resource." + callResource + ".call": "Balances.transfer_all"},
+		{"DestId": "1", "Resource." + callResource + ".call": "System.remark", "Resource." + callResource + ".asset": "native"},
+		{"DestId": "1", "Resource.0x01.asset": "native"},
+		{"DestId": "1", "Resource." + ksmResource + ".decimals": "12"},
+		{"DestId": "1", "Resource." + ksmResource + ".handler": "0x01"},
+		{"DestId": "1", "Resource." + ksmResource + ".asset": "native", "Resource." + usdtResource + ".asset": "native"},
+	} {
+		_, err := parseResourceOpts(opts)
+		if err == nil {
+			t.Fatalf("Expected an error for %v", opts)
+		}
+	}
+}
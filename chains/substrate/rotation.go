@@ -0,0 +1,314 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v2/signature"
+	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
+	"github.com/centrifuge/go-substrate-rpc-client/v2/xxhash"
+	utils "github.com/rjman-self/Platdot/shared/substrate"
+	"github.com/rjman-self/platdot-utils/msg"
+	"golang.org/x/crypto/blake2b"
+)
+
+// parseRotationOpts reads the relayer set taking over the multisig at RotationBlock. The set is configured like
+// the current one with the NextTotalRelayer, NextMultiSignThreshold, NextCurrentRelayerNumber and
+// NextOtherRelayer<N> options, NextCurrentRelayerNumber is 0 if the relayer leaves the set.
+func parseRotationOpts(opts map[string]string, kr signature.KeyringPair, ss58Prefix uint16) (*Rotation, error) {
+	rotationBlock, ok := opts["RotationBlock"]
+	if !ok {
+		return nil, nil
+	}
+	block, err := strconv.ParseUint(rotationBlock, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RotationBlock %s: %w", rotationBlock, err)
+	}
+
+	var values [3]uint64
+	for i, key := range []string{"NextTotalRelayer", "NextMultiSignThreshold", "NextCurrentRelayerNumber"} {
+		value, ok := opts[key]
+		if !ok {
+			return nil, fmt.Errorf("%s is required by the rotation of the relayer set", key)
+		}
+		values[i], err = strconv.ParseUint(value, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %s: %w", key, value, err)
+		}
+	}
+	total, threshold, current := values[0], values[1], values[2]
+	if current > total {
+		return nil, fmt.Errorf("NextCurrentRelayerNumber %d is above NextTotalRelayer %d", current, total)
+	}
+
+	signatories, err := parseSignatories(opts, "Next", total, current, ss58Prefix)
+	if err != nil {
+		return nil, err
+	}
+	next := NewRelayer(kr, signatories, total, uint16(threshold), current)
+	if uint64(len(next.signatories())) != total {
+		return nil, fmt.Errorf("the next relayer set has %d of %d signatories", len(next.signatories()), total)
+	}
+	next.multisig, err = next.deriveMultisig()
+	if err != nil {
+		return nil, err
+	}
+	return &Rotation{Block: block, Next: next}, nil
+}
+
+// rotationRemark is bound into the migration of the funds to the multisig of the next relayer set, the funds
+// are queried at the snapshot block
+func rotationRemark(block, snapshot uint64) []byte {
+	if snapshot == block {
+		return []byte(fmt.Sprintf("platdot:rotation:%d", block))
+	}
+	return []byte(fmt.Sprintf("platdot:rotation:%d:%d", block, snapshot))
+}
+
+// migrate moves the funds of the multisig to the multisig of the next relayer set once the rotation block is
+// finalized. The migration runs in rounds, each call is built from the state of a block every relayer agrees on
+// so every relayer of the set votes for the same call hash. The first round queries the rotation block. A round
+// that fails, because the balances changed before its execution, is followed by one querying the block of the
+// failure, as is a round leaving funds deposited after its query. It returns when the multisig is empty.
+func (w *writer) migrate() {
+	rotation := w.relayer.rotation
+	for {
+		round, err := w.currentRound()
+		if err != nil {
+			w.log.Error("Failed to get the finalized block of the rotation", "err", err)
+		} else if round.blockHeight.Uint64() >= rotation.Block {
+			break
+		}
+		select {
+		case <-w.conn.stop:
+			return
+		case <-time.After(RoundInterval):
+		}
+	}
+
+	snapshot := rotation.Block
+	if migrated := w.listener.migrationExecution(); migrated != nil {
+		snapshot = uint64(migrated.BlockNumber)
+	}
+	for {
+		next, ok := w.migrationRound(snapshot)
+		if !ok {
+			return
+		}
+		if next == 0 {
+			w.log.Info("Finished the migration to the next relayer set", "RotationBlock", rotation.Block)
+			return
+		}
+		snapshot = next
+	}
+}
+
+// migrationRound votes for the migration of the funds queried at the snapshot block. It returns the snapshot
+// block of the next round, zero if the multisig held nothing, and false if the relayer stopped.
+func (w *writer) migrationRound(snapshot uint64) (uint64, bool) {
+	rotation := w.relayer.rotation
+	var call types.Call
+	var pending bool
+	for {
+		hash, err := w.msApi.RPC.Chain.GetBlockHash(snapshot)
+		if err == nil {
+			call, pending, err = w.migrationCall(&hash, snapshot)
+		}
+		if err == nil {
+			break
+		}
+		w.log.Error("Failed to construct the migration call", "RotationBlock", rotation.Block, "Snapshot", snapshot, "err", err)
+		select {
+		case <-w.conn.stop:
+			return 0, false
+		case <-time.After(RoundInterval):
+		}
+	}
+	if !pending {
+		// Nothing to move, the deposits of the later blocks go to the next relayer set
+		w.listener.markEmptyMigration(snapshot)
+		return 0, true
+	}
+
+	callHash := CallHash(call)
+	w.listener.watchMigration(callHash)
+	w.log.Info("Move the funds to the next relayer set", "RotationBlock", rotation.Block, "Snapshot", snapshot,
+		"Multisig", w.listener.address(rotation.Next.multisig[:]), "CallHash", callHash.Hex())
+
+	// The current relayer set votes for the migration after the rotation
+	current := w.relayer
+	current.rotation = nil
+	for {
+		select {
+		case <-w.conn.stop:
+			return 0, false
+		default:
+		}
+		var currentTx MultiSignTx
+		if current.isMember() {
+			_, currentTx = w.redeemTx(current, msg.Nonce(snapshot), call, callHash)
+		}
+		ms, ok := w.listener.getMultisig(callHash)
+		migrated := w.listener.migrationExecution()
+		switch {
+		case ok && ms.Failed:
+			w.log.Error("Migration executed with a failed call, query the funds again", "Snapshot", snapshot, "Block", ms.Execution.BlockNumber)
+			w.listener.deleteMultisig(callHash)
+			return uint64(ms.Execution.BlockNumber), true
+		case ok && ms.Executed:
+			w.log.Info("Migration executed", "Snapshot", snapshot, "Block", ms.Execution.BlockNumber)
+			w.listener.deleteMultisig(callHash)
+			return uint64(ms.Execution.BlockNumber), true
+		case !ok && migrated != nil && uint64(migrated.BlockNumber) > snapshot:
+			// Executed before the relayer saw the multisig
+			return uint64(migrated.BlockNumber), true
+		}
+		if currentTx == YesVoted || !current.isMember() {
+			time.Sleep(RoundInterval)
+		}
+	}
+}
+
+// migrationCall creates the call transferring the native token, the balances of the Assets pallet and the
+// instances of the Uniques pallet of the resources to the multisig of the next relayer set. The funds are
+// queried at the block, or at the latest block if it is nil, the native token is transferred with transfer_all
+// and the assets with their balance at the block. pending is false if the multisig holds nothing.
+func (w *writer) migrationCall(hash *types.Hash, snapshot uint64) (c types.Call, pending bool, err error) {
+	w.UpdateMetadate()
+	types.SetSerDeOptions(types.SerDeOptions{NoPalletIndices: true})
+
+	multisig := w.relayer.multisig
+	next := types.NewMultiAddressFromAccountID(w.relayer.rotation.Next.multisig[:])
+	var calls []types.Call
+
+	key, err := types.CreateStorageKey(w.meta, "System", "Account", multisig[:], nil)
+	if err != nil {
+		return types.Call{}, false, err
+	}
+	var account types.AccountInfo
+	if _, err = w.getStorage(key, &account, hash); err != nil {
+		return types.Call{}, false, err
+	}
+	if account.Data.Free.Int != nil && account.Data.Free.Sign() > 0 {
+		transfer, err := types.NewCall(w.meta, string(utils.BalancesTransferAllMethod), next, types.NewBool(false))
+		if err != nil {
+			return types.Call{}, false, err
+		}
+		calls = append(calls, transfer)
+	}
+
+	for _, res := range w.listener.resources.List() {
+		switch {
+		case res.Asset.IsAsset:
+			balance, err := w.assetBalance(res.Asset, multisig, hash)
+			if err != nil {
+				return types.Call{}, false, err
+			}
+			if balance.Sign() == 0 {
+				continue
+			}
+			transfer, err := w.transferCall(res.Asset, next, balance)
+			if err != nil {
+				return types.Call{}, false, err
+			}
+			calls = append(calls, transfer)
+		case res.Asset.IsUnique:
+			instances, err := w.uniqueInstances(res.Asset, multisig, hash)
+			if err != nil {
+				return types.Call{}, false, err
+			}
+			for _, instance := range instances {
+				transfer, err := w.transferCall(res.Asset, next, big.NewInt(int64(instance)))
+				if err != nil {
+					return types.Call{}, false, err
+				}
+				calls = append(calls, transfer)
+			}
+		}
+	}
+	if len(calls) == 0 {
+		return types.Call{}, false, nil
+	}
+
+	remark, err := types.NewCall(w.meta, string(utils.SystemRemark), types.NewBytes(rotationRemark(w.relayer.rotation.Block, snapshot)))
+	if err != nil {
+		return types.Call{}, false, err
+	}
+	c, err = types.NewCall(w.meta, string(utils.UtilityBatchAll), append(calls, remark))
+	return c, true, err
+}
+
+// assetBalance returns the balance of the account in the Assets pallet, the balance is the first field of the
+// AssetBalance of the Account storage
+func (w *writer) assetBalance(asset Asset, account types.AccountID, hash *types.Hash) (*big.Int, error) {
+	id, err := types.EncodeToBytes(types.U32(asset.Id))
+	if err != nil {
+		return nil, err
+	}
+	key, err := types.CreateStorageKey(w.meta, "Assets", "Account", id, account[:])
+	if err != nil {
+		return nil, err
+	}
+	var raw *types.StorageDataRaw
+	if hash != nil {
+		raw, err = w.msApi.RPC.State.GetStorageRaw(key, *hash)
+	} else {
+		raw, err = w.msApi.RPC.State.GetStorageRawLatest(key)
+	}
+	if err != nil || len(*raw) == 0 {
+		return big.NewInt(0), err
+	}
+	var balance types.U128
+	err = types.DecodeFromBytes(*raw, &balance)
+	if err != nil {
+		return nil, err
+	}
+	return balance.Int, nil
+}
+
+// uniqueInstances returns the instances of the class owned by the account. The keys of the Account storage of
+// the Uniques pallet are the Blake2_128Concat hashes of the account, the class and the instance.
+func (w *writer) uniqueInstances(asset Asset, account types.AccountID, hash *types.Hash) ([]uint32, error) {
+	class := make([]byte, 4)
+	binary.LittleEndian.PutUint32(class, asset.Id)
+	prefix := append(append(xxhash.New128([]byte("Uniques")).Sum(nil), xxhash.New128([]byte("Account")).Sum(nil)...),
+		append(blake2128Concat(account[:]), blake2128Concat(class)...)...)
+
+	var keys []types.StorageKey
+	var err error
+	if hash != nil {
+		keys, err = w.msApi.RPC.State.GetKeys(prefix, *hash)
+	} else {
+		keys, err = w.msApi.RPC.State.GetKeysLatest(prefix)
+	}
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]uint32, 0, len(keys))
+	for _, key := range keys {
+		if len(key) != len(prefix)+20 {
+			return nil, fmt.Errorf("invalid key %s of %s", key.Hex(), asset)
+		}
+		instances = append(instances, binary.LittleEndian.Uint32(key[len(key)-4:]))
+	}
+	return instances, nil
+}
+
+func (w *writer) getStorage(key types.StorageKey, target interface{}, hash *types.Hash) (bool, error) {
+	if hash != nil {
+		return w.msApi.RPC.State.GetStorage(key, target, *hash)
+	}
+	return w.msApi.RPC.State.GetStorageLatest(key, target)
+}
+
+func blake2128Concat(data []byte) []byte {
+	h, _ := blake2b.New(16, nil)
+	h.Write(data)
+	return append(h.Sum(nil), data...)
+}
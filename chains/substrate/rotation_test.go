@@ -0,0 +1,180 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"testing"
+
+	"github.com/ChainSafe/log15"
+	"github.com/centrifuge/go-substrate-rpc-client/v2/signature"
+	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
+	utils "github.com/rjman-self/Platdot/shared/substrate"
+	rtypes "github.com/rjmand/go-substrate-rpc-client/v2/types"
+)
+
+const (
+	bobAddress     = "5FHneW46xGXgs5mUiveU4sbTyGBzmstUspZC92UhjJM694ty"
+	charlieAddress = "5FLSigC9HGRKVhB9FiEo4Y3koPsNmBmLJbpXg2mp1hXcS59Y"
+	// The 2 of 3 multisig of Alice, Bob and Charlie
+	multisigAddress = "5DjYJStmdZ2rcqXbXGX7TW85JsrW6uG4y9MUcLq2BoPMpRA7"
+)
+
+func TestParseRotationOpts(t *testing.T) {
+	opts := map[string]string{
+		"RotationBlock":            "1000",
+		"NextTotalRelayer":         "3",
+		"NextMultiSignThreshold":   "2",
+		"NextCurrentRelayerNumber": "1",
+		"NextOtherRelayer1":        bobAddress,
+		"NextOtherRelayer2":        charlieAddress,
+	}
+	rotation, err := parseRotationOpts(opts, signature.TestKeyringPairAlice, utils.SubstrateSS58Prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotation.Block != 1000 || rotation.Next.totalRelayers != 3 || rotation.Next.multiSignThreshold != 2 || rotation.Next.currentRelayer != 1 {
+		t.Fatalf("Got: %v Expected the relayer set of: %v", rotation, opts)
+	}
+	multisig := utils.FormatAddress(rotation.Next.multisig[:], utils.SubstrateSS58Prefix)
+	if multisig != multisigAddress {
+		t.Fatalf("Got: %s Expected: %s", multisig, multisigAddress)
+	}
+
+	// Alice leaves, the set is Bob and Charlie
+	opts = map[string]string{
+		"RotationBlock":            "1000",
+		"NextTotalRelayer":         "2",
+		"NextMultiSignThreshold":   "2",
+		"NextCurrentRelayerNumber": "0",
+		"NextOtherRelayer1":        bobAddress,
+		"NextOtherRelayer2":        charlieAddress,
+	}
+	rotation, err = parseRotationOpts(opts, signature.TestKeyringPairAlice, utils.SubstrateSS58Prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotation.Next.isMember() || len(rotation.Next.signatories()) != 2 {
+		t.Fatalf("Got: %v Expected a set without the relayer", rotation.Next)
+	}
+
+	// No rotation
+	rotation, err = parseRotationOpts(map[string]string{}, signature.TestKeyringPairAlice, utils.SubstrateSS58Prefix)
+	if err != nil || rotation != nil {
+		t.Fatalf("Got: %v %v Expected no rotation", rotation, err)
+	}
+}
+
+func TestParseRotationOpts_Invalid(t *testing.T) {
+	testCases := []map[string]string{
+		{"RotationBlock": "x"},
+		// Missing set
+		{"RotationBlock": "1000"},
+		// Invalid signatory
+		{"RotationBlock": "1000", "NextTotalRelayer": "2", "NextMultiSignThreshold": "2", "NextCurrentRelayerNumber": "1", "NextOtherRelayer1": bobAddress[1:]},
+		// Missing signatory
+		{"RotationBlock": "1000", "NextTotalRelayer": "3", "NextMultiSignThreshold": "2", "NextCurrentRelayerNumber": "1", "NextOtherRelayer1": bobAddress},
+		// Threshold above the signatories
+		{"RotationBlock": "1000", "NextTotalRelayer": "2", "NextMultiSignThreshold": "3", "NextCurrentRelayerNumber": "1", "NextOtherRelayer1": bobAddress},
+		{"RotationBlock": "1000", "NextTotalRelayer": "2", "NextMultiSignThreshold": "2", "NextCurrentRelayerNumber": "3", "NextOtherRelayer1": bobAddress},
+	}
+
+	for _, opts := range testCases {
+		_, err := parseRotationOpts(opts, signature.TestKeyringPairAlice, utils.SubstrateSS58Prefix)
+		if err == nil {
+			t.Fatalf("%v. Expected an error", opts)
+		}
+	}
+}
+
+func TestRelayerAt(t *testing.T) {
+	current := NewRelayer(signature.TestKeyringPairAlice, nil, 3, 2, 1)
+	current.multisig = types.AccountID{1}
+	next := NewRelayer(signature.TestKeyringPairAlice, nil, 4, 3, 2)
+	next.multisig = types.AccountID{2}
+	current.rotation = &Rotation{Block: 1000, Next: next}
+
+	if r := current.at(999); r.multisig != current.multisig {
+		t.Fatalf("Got: %v Expected: %v", r.multisig, current.multisig)
+	}
+	if r := current.at(1000); r.multisig != next.multisig || r.currentRelayer != 2 {
+		t.Fatalf("Got: %v Expected: %v", r.multisig, next.multisig)
+	}
+	if r, ok := current.setOf(next.multisig); !ok || r.totalRelayers != 4 {
+		t.Fatalf("Got: %v Expected: %v", r, next)
+	}
+	if _, ok := current.setOf(types.AccountID{3}); ok {
+		t.Fatal("unknown multisig has a relayer set")
+	}
+
+	ms := MultiSigAsMulti{Multisig: next.multisig}
+	if !ms.isOf(next.multisig) {
+		t.Fatal("multisig transaction of the next set does not belong to the next multisig")
+	}
+	if ms.isOf(current.multisig) {
+		t.Fatal("multisig transaction of the next set belongs to the current multisig")
+	}
+}
+
+func TestRelayerCheckMultisig(t *testing.T) {
+	bob, err := utils.ParseAccountID(bobAddress, utils.SubstrateSS58Prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	charlie, err := utils.ParseAccountID(charlieAddress, utils.SubstrateSS58Prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	multisig, err := utils.ParseAccountID(multisigAddress, utils.SubstrateSS58Prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	relayer := NewRelayer(signature.TestKeyringPairAlice, []types.AccountID{types.NewAccountID(bob), types.NewAccountID(charlie)}, 3, 2, 1)
+	relayer.multisig = types.NewAccountID(multisig)
+	err = relayer.checkMultisig(utils.SubstrateSS58Prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A typo in an OtherRelayer gives another multisig
+	relayer.otherSignatories[1] = types.AccountID{1}
+	err = relayer.checkMultisig(utils.SubstrateSS58Prefix)
+	if err == nil {
+		t.Fatal("Expected error for a multisig that is not the one of the relayers")
+	}
+}
+
+func TestListenerReceives(t *testing.T) {
+	current := NewRelayer(signature.TestKeyringPairAlice, nil, 3, 2, 1)
+	current.multisig = types.AccountID{1}
+	next := NewRelayer(signature.TestKeyringPairAlice, nil, 4, 3, 2)
+	next.multisig = types.AccountID{2}
+	current.rotation = &Rotation{Block: 1000, Next: next}
+	l := &listener{relayer: current, msStore: &EmptyMultisigStore{}, msTxAsMulti: make(map[types.Hash]MultiSigAsMulti), log: log15.New()}
+	old, other := rtypes.AccountID(current.multisig), rtypes.AccountID(next.multisig)
+
+	if !l.receives(old, 999, 0) || l.receives(other, 999, 0) {
+		t.Fatal("Expected the deposits into the current multisig only before the rotation")
+	}
+	// The previous multisig receives the deposits until the migration is executed
+	if !l.receives(old, 1000, 0) || !l.receives(other, 1000, 0) {
+		t.Fatal("Expected the deposits into both multisigs before the migration")
+	}
+
+	migration := types.Hash{1}
+	l.storeMultisig(MultiSigAsMulti{CallHash: migration, Multisig: current.multisig})
+	l.watchMigration(migration)
+	l.markExecution(migration, MultiSignTx{BlockNumber: 1005, MultiSignTxId: 2})
+	if !l.receives(old, 1005, 1) || l.receives(old, 1005, 3) || l.receives(old, 1006, 0) {
+		t.Fatal("Expected the deposits into the previous multisig until the migration")
+	}
+	if !l.receives(other, 1006, 0) {
+		t.Fatal("Expected the deposits into the next multisig after the migration")
+	}
+
+	// Only the first execution is the migration
+	l.markEmptyMigration(1010)
+	if migrated := l.migrationExecution(); migrated.BlockNumber != 1005 {
+		t.Fatalf("Got: %v Expected: %v", migrated.BlockNumber, 1005)
+	}
+}
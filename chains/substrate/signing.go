@@ -0,0 +1,146 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+	"strconv"
+
+	ctypes "github.com/centrifuge/go-substrate-rpc-client/v2/types"
+	"github.com/rjmand/go-substrate-rpc-client/v2/types"
+)
+
+// Number of blocks an extrinsic stays valid for, from the finalized block it is anchored on
+const DefaultEraPeriod = 64
+
+// Bounds of the era period, substrate rounds the period up to a power of two within them
+const (
+	MinEraPeriod = 4
+	MaxEraPeriod = 1 << 16
+)
+
+// SigningConfig is the mortality and tip of the extrinsics signed by the relayer
+type SigningConfig struct {
+	EraPeriod uint64
+	Tip       *big.Int
+}
+
+// signingOptions are the signature options of an extrinsic. The writer and the connection sign with
+// different client libraries, the options are converted to the types of each.
+type signingOptions struct {
+	blockHash          [32]byte
+	genesisHash        [32]byte
+	era                [2]byte
+	nonce              uint64
+	tip                *big.Int
+	specVersion        uint32
+	transactionVersion uint32
+}
+
+// parseSigningOpts reads the eraPeriod and tip options
+func parseSigningOpts(opts map[string]string) (SigningConfig, error) {
+	cfg := SigningConfig{EraPeriod: DefaultEraPeriod, Tip: big.NewInt(0)}
+	if period, ok := opts["eraPeriod"]; ok {
+		p, err := strconv.ParseUint(period, 10, 64)
+		if err != nil {
+			return SigningConfig{}, fmt.Errorf("unable to parse eraPeriod: %w", err)
+		}
+		if p < MinEraPeriod || p > MaxEraPeriod {
+			return SigningConfig{}, fmt.Errorf("eraPeriod must be between %d and %d, got %d", MinEraPeriod, MaxEraPeriod, p)
+		}
+		cfg.EraPeriod = p
+	}
+	if tip, ok := opts["tip"]; ok {
+		t, ok := new(big.Int).SetString(tip, 10)
+		if !ok || t.Sign() < 0 {
+			return SigningConfig{}, fmt.Errorf("unable to parse tip %s", tip)
+		}
+		cfg.Tip = t
+	}
+	return cfg, nil
+}
+
+// mortalEra encodes the era of period blocks starting at the block current, as substrate's Era::mortal does
+func mortalEra(period, current uint64) [2]byte {
+	// The period is a power of two between the bounds
+	if period < MinEraPeriod {
+		period = MinEraPeriod
+	}
+	if period > MaxEraPeriod {
+		period = MaxEraPeriod
+	}
+	if period&(period-1) != 0 {
+		period = 1 << bits.Len64(period)
+	}
+
+	phase := current % period
+	quantizeFactor := period >> 12
+	if quantizeFactor < 1 {
+		quantizeFactor = 1
+	}
+	low := uint64(bits.TrailingZeros64(period)) - 1
+	if low < 1 {
+		low = 1
+	}
+	if low > 15 {
+		low = 15
+	}
+	encoded := low | (phase/quantizeFactor)<<4
+	return [2]byte{byte(encoded), byte(encoded >> 8)}
+}
+
+// signingOptions returns the options of an extrinsic signed with nonce. The extrinsic is mortal, anchored on
+// the latest finalized block, so it expires from the pool and can not be replayed once its era is over.
+func (c *Connection) signingOptions(nonce uint64) (signingOptions, error) {
+	finalizedHash, err := c.api.RPC.Chain.GetFinalizedHead()
+	if err != nil {
+		return signingOptions{}, err
+	}
+	finalizedHeader, err := c.api.RPC.Chain.GetHeader(finalizedHash)
+	if err != nil {
+		return signingOptions{}, err
+	}
+	rv, err := c.api.RPC.State.GetRuntimeVersionLatest()
+	if err != nil {
+		return signingOptions{}, err
+	}
+
+	return signingOptions{
+		blockHash:          finalizedHash,
+		genesisHash:        c.genesisHash,
+		era:                mortalEra(c.signing.EraPeriod, uint64(finalizedHeader.Number)),
+		nonce:              nonce,
+		tip:                c.signing.Tip,
+		specVersion:        uint32(rv.SpecVersion),
+		transactionVersion: uint32(rv.TransactionVersion),
+	}, nil
+}
+
+// rjmand returns the options for the extrinsics of the connection
+func (o signingOptions) rjmand() types.SignatureOptions {
+	return types.SignatureOptions{
+		BlockHash:          o.blockHash,
+		Era:                types.ExtrinsicEra{IsMortalEra: true, AsMortalEra: types.MortalEra{First: o.era[0], Second: o.era[1]}},
+		GenesisHash:        o.genesisHash,
+		Nonce:              types.NewUCompactFromUInt(o.nonce),
+		SpecVersion:        types.U32(o.specVersion),
+		Tip:                types.NewUCompact(o.tip),
+		TransactionVersion: types.U32(o.transactionVersion),
+	}
+}
+
+// centrifuge returns the options for the multisig extrinsics of the writer
+func (o signingOptions) centrifuge() ctypes.SignatureOptions {
+	return ctypes.SignatureOptions{
+		BlockHash:          o.blockHash,
+		Era:                ctypes.ExtrinsicEra{IsMortalEra: true, AsMortalEra: ctypes.MortalEra{First: o.era[0], Second: o.era[1]}},
+		GenesisHash:        o.genesisHash,
+		Nonce:              ctypes.NewUCompactFromUInt(o.nonce),
+		SpecVersion:        ctypes.U32(o.specVersion),
+		Tip:                ctypes.NewUCompact(o.tip),
+		TransactionVersion: ctypes.U32(o.transactionVersion),
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestMortalEra(t *testing.T) {
+	testCases := []struct {
+		period   uint64
+		current  uint64
+		expected [2]byte
+	}{
+		// Vectors of substrate's Era::mortal
+		{64, 42, [2]byte{0xa5, 0x02}},
+		{1000000, 1000000, [2]byte{0x4f, 0x42}},
+		// The period is rounded up to a power of two
+		{10, 0, [2]byte{0x03, 0x00}},
+		{5, 13, [2]byte{0x52, 0x00}},
+		{1, 0, [2]byte{0x01, 0x00}},
+	}
+
+	for _, tc := range testCases {
+		era := mortalEra(tc.period, tc.current)
+		if era != tc.expected {
+			t.Fatalf("Period %d at %d. Got: %x Expected: %x", tc.period, tc.current, era, tc.expected)
+		}
+	}
+}
+
+func TestParseSigningOpts(t *testing.T) {
+	cfg, err := parseSigningOpts(map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.EraPeriod != DefaultEraPeriod || cfg.Tip.Cmp(big.NewInt(0)) != 0 {
+		t.Fatalf("Got: %d %s Expected: %d %d", cfg.EraPeriod, cfg.Tip, DefaultEraPeriod, 0)
+	}
+
+	cfg, err = parseSigningOpts(map[string]string{"eraPeriod": "128", "tip": "1000000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.EraPeriod != 128 || cfg.Tip.Cmp(big.NewInt(1000000000)) != 0 {
+		t.Fatalf("Got: %d %s Expected: %d %d", cfg.EraPeriod, cfg.Tip, 128, 1000000000)
+	}
+
+	for _, opts := range []map[string]string{
+		{"eraPeriod": "2"},
+		{"eraPeriod": "100000"},
+		{"eraPeriod": "abc"},
+		{"tip": "-1"},
+		{"tip": "1.5"},
+	} {
+		_, err = parseSigningOpts(opts)
+		if err == nil {
+			t.Fatalf("Expected an error for %v", opts)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"fmt"
+
+	gsrpc "github.com/centrifuge/go-substrate-rpc-client/v2"
+	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
+)
+
+// Timepoint is the block height and extrinsic index a multisig was opened at
+type Timepoint struct {
+	Height types.U32
+	Index  types.U32
+}
+
+// OpenMultisig is an entry of Multisig.Multisigs, a multisig operation waiting for approvals
+type OpenMultisig struct {
+	CallHash  types.Hash
+	When      Timepoint
+	Deposit   types.U128
+	Depositor types.AccountID
+	Approvals []types.AccountID
+}
+
+type multisigInfo struct {
+	When      Timepoint
+	Deposit   types.U128
+	Depositor types.AccountID
+	Approvals []types.AccountID
+}
+
+// FinalizedBlock returns the number of the latest finalized block
+func FinalizedBlock(api *gsrpc.SubstrateAPI) (uint64, error) {
+	hash, err := api.RPC.Chain.GetFinalizedHead()
+	if err != nil {
+		return 0, err
+	}
+	header, err := api.RPC.Chain.GetHeader(hash)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(header.Number), nil
+}
+
+// QueryMultisigs returns the multisig operations of the account that are still open on chain
+func QueryMultisigs(api *gsrpc.SubstrateAPI, meta *types.Metadata, account types.AccountID) ([]OpenMultisig, error) {
+	// Multisigs is a double map by account and call hash, the key of a zero call hash is cut to the account prefix
+	var zero types.Hash
+	key, err := types.CreateStorageKey(meta, "Multisig", "Multisigs", account[:], zero[:])
+	if err != nil {
+		return nil, err
+	}
+	prefix := key[:len(key)-len(zero)-16]
+
+	keys, err := api.RPC.State.GetKeysLatest(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []OpenMultisig
+	for _, k := range keys {
+		var info multisigInfo
+		ok, err := api.RPC.State.GetStorageLatest(k, &info)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read multisig %s: %w", k.Hex(), err)
+		}
+		if !ok {
+			continue
+		}
+		res = append(res, OpenMultisig{
+			// Blake2_128Concat keeps the call hash at the end of the key
+			CallHash:  types.NewHash(k[len(k)-len(zero):]),
+			When:      info.When,
+			Deposit:   info.Deposit,
+			Depositor: info.Depositor,
+			Approvals: info.Approvals,
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,275 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
+	"github.com/rjman-self/platdot-utils/blockstore"
+	"github.com/rjman-self/platdot-utils/msg"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+var (
+	multisigPrefix = []byte("ms-")
+	messagePrefix  = []byte("msg-")
+	refundPrefix   = []byte("refund-")
+	rejectedPrefix = []byte("rejected-")
+	migrationKey   = []byte("migration")
+)
+
+// MultisigStorer persists the multisig tracking state of a relayer, so that a restarted relayer
+// can keep approving the as_multi it has already seen instead of opening a duplicate one.
+type MultisigStorer interface {
+	StoreMultisig(ms MultiSigAsMulti) error
+	DeleteMultisig(callHash types.Hash) error
+	LoadMultisigs() (map[types.Hash]MultiSigAsMulti, error)
+	StoreMessage(dest Dest) error
+	DeleteMessage(dest Dest) error
+	LoadMessages() ([]Dest, error)
+	StoreRefund(r Refund) error
+	DeleteRefund(r Refund) error
+	LoadRefunds() ([]Refund, error)
+	StoreRejected(r Rejected) error
+	DeleteRejected(source msg.ChainId, nonce msg.Nonce) error
+	LoadRejected() ([]Rejected, error)
+	StoreMigration(execution MultiSignTx) error
+	LoadMigration() (*MultiSignTx, error)
+	Close() error
+}
+
+var _ MultisigStorer = &EmptyMultisigStore{}
+var _ MultisigStorer = &MultisigStore{}
+
+// EmptyMultisigStore keeps nothing, it is used for testing only
+type EmptyMultisigStore struct{}
+
+func (s *EmptyMultisigStore) StoreMultisig(_ MultiSigAsMulti) error { return nil }
+func (s *EmptyMultisigStore) DeleteMultisig(_ types.Hash) error     { return nil }
+func (s *EmptyMultisigStore) LoadMultisigs() (map[types.Hash]MultiSigAsMulti, error) {
+	return map[types.Hash]MultiSigAsMulti{}, nil
+}
+func (s *EmptyMultisigStore) StoreMessage(_ Dest) error                       { return nil }
+func (s *EmptyMultisigStore) DeleteMessage(_ Dest) error                      { return nil }
+func (s *EmptyMultisigStore) LoadMessages() ([]Dest, error)                   { return nil, nil }
+func (s *EmptyMultisigStore) StoreRefund(_ Refund) error                      { return nil }
+func (s *EmptyMultisigStore) DeleteRefund(_ Refund) error                     { return nil }
+func (s *EmptyMultisigStore) LoadRefunds() ([]Refund, error)                  { return nil, nil }
+func (s *EmptyMultisigStore) StoreRejected(_ Rejected) error                  { return nil }
+func (s *EmptyMultisigStore) DeleteRejected(_ msg.ChainId, _ msg.Nonce) error { return nil }
+func (s *EmptyMultisigStore) LoadRejected() ([]Rejected, error)               { return nil, nil }
+func (s *EmptyMultisigStore) StoreMigration(_ MultiSignTx) error              { return nil }
+func (s *EmptyMultisigStore) LoadMigration() (*MultiSignTx, error)            { return nil, nil }
+func (s *EmptyMultisigStore) Close() error                                    { return nil }
+
+// MultisigStore is a leveldb backed MultisigStorer, it lives next to the blockstore of the relayer.
+type MultisigStore struct {
+	db *leveldb.DB
+}
+
+// NewMultisigStore opens (or creates) the multisig store for the chain/relayer pair.
+// Passing an empty string for path will cause it to use the blockstore default directory.
+func NewMultisigStore(path string, chain msg.ChainId, relayer string) (*MultisigStore, error) {
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, blockstore.PathPostfix)
+	}
+
+	db, err := leveldb.OpenFile(filepath.Join(path, fmt.Sprintf("%s-%d.multisig", relayer, chain)), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &MultisigStore{db: db}, nil
+}
+
+// StoreMultisig writes the multisig record under its call hash.
+func (s *MultisigStore) StoreMultisig(ms MultiSigAsMulti) error {
+	data, err := json.Marshal(ms)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(multisigKey(ms.CallHash), data, nil)
+}
+
+func (s *MultisigStore) DeleteMultisig(callHash types.Hash) error {
+	return s.db.Delete(multisigKey(callHash), nil)
+}
+
+// LoadMultisigs returns every multisig record that has not been executed yet.
+// Executed and failed records are pruned from the store while loading, the multisig of a failed
+// call is closed on chain and a resumed redemption opens a new one.
+func (s *MultisigStore) LoadMultisigs() (map[types.Hash]MultiSigAsMulti, error) {
+	res := make(map[types.Hash]MultiSigAsMulti)
+	iter := s.db.NewIterator(util.BytesPrefix(multisigPrefix), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		var ms MultiSigAsMulti
+		err := json.Unmarshal(iter.Value(), &ms)
+		if err != nil {
+			return nil, err
+		}
+		if ms.Executed || ms.Failed {
+			err = s.db.Delete(iter.Key(), nil)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		res[ms.CallHash] = ms
+	}
+	return res, iter.Error()
+}
+
+// StoreMessage writes a redemption the writer is still processing.
+func (s *MultisigStore) StoreMessage(dest Dest) error {
+	data, err := json.Marshal(dest)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(messageKey(dest.DepositNonce), data, nil)
+}
+
+func (s *MultisigStore) DeleteMessage(dest Dest) error {
+	return s.db.Delete(messageKey(dest.DepositNonce), nil)
+}
+
+// LoadMessages returns every redemption that was not finished before the relayer stopped.
+func (s *MultisigStore) LoadMessages() ([]Dest, error) {
+	var res []Dest
+	iter := s.db.NewIterator(util.BytesPrefix(messagePrefix), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		var dest Dest
+		err := json.Unmarshal(iter.Value(), &dest)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, dest)
+	}
+	return res, iter.Error()
+}
+
+// StoreRefund records a rejected deposit until it is returned to the sender.
+func (s *MultisigStore) StoreRefund(r Refund) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(refundKey(r.DepositNonce), data, nil)
+}
+
+func (s *MultisigStore) DeleteRefund(r Refund) error {
+	return s.db.Delete(refundKey(r.DepositNonce), nil)
+}
+
+// LoadRefunds returns every rejected deposit that was not refunded yet.
+func (s *MultisigStore) LoadRefunds() ([]Refund, error) {
+	var res []Refund
+	iter := s.db.NewIterator(util.BytesPrefix(refundPrefix), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		var r Refund
+		err := json.Unmarshal(iter.Value(), &r)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+	return res, iter.Error()
+}
+
+// StoreRejected records a burn whose redemption can not be made, until its deposit is requeued and redeemed.
+func (s *MultisigStore) StoreRejected(r Rejected) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(rejectedKey(r.Source, r.DepositNonce), data, nil)
+}
+
+func (s *MultisigStore) DeleteRejected(source msg.ChainId, nonce msg.Nonce) error {
+	return s.db.Delete(rejectedKey(source, nonce), nil)
+}
+
+// LoadRejected returns every burn whose redemption was rejected and not made since.
+func (s *MultisigStore) LoadRejected() ([]Rejected, error) {
+	var res []Rejected
+	iter := s.db.NewIterator(util.BytesPrefix(rejectedPrefix), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		var r Rejected
+		err := json.Unmarshal(iter.Value(), &r)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+	return res, iter.Error()
+}
+
+// StoreMigration records the extrinsic that moved the funds of the multisig to the next relayer set.
+func (s *MultisigStore) StoreMigration(execution MultiSignTx) error {
+	data, err := json.Marshal(execution)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(migrationKey, data, nil)
+}
+
+// LoadMigration returns the extrinsic of the migration, nil if the funds were not moved yet.
+func (s *MultisigStore) LoadMigration() (*MultiSignTx, error) {
+	data, err := s.db.Get(migrationKey, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var execution MultiSignTx
+	err = json.Unmarshal(data, &execution)
+	if err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
+func (s *MultisigStore) Close() error {
+	return s.db.Close()
+}
+
+func multisigKey(callHash types.Hash) []byte {
+	return append(append([]byte{}, multisigPrefix...), callHash[:]...)
+}
+
+func messageKey(nonce msg.Nonce) []byte {
+	return nonceKey(messagePrefix, nonce)
+}
+
+func refundKey(nonce msg.Nonce) []byte {
+	return nonceKey(refundPrefix, nonce)
+}
+
+// rejectedKey is made of the source and the nonce, the nonces of two chains may be the same
+func rejectedKey(source msg.ChainId, nonce msg.Nonce) []byte {
+	return nonceKey(append(append([]byte{}, rejectedPrefix...), byte(source)), nonce)
+}
+
+func nonceKey(prefix []byte, nonce msg.Nonce) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(nonce))
+	return append(append([]byte{}, prefix...), key...)
+}
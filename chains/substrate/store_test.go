@@ -0,0 +1,195 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+func TestMultisigStore(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "multisig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewMultisigStore(dir, 1, "relayer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending := MultiSigAsMulti{
+		CallHash:    types.Hash{0x01},
+		OriginMsTx:  MultiSignTx{BlockNumber: 100, MultiSignTxId: 2},
+		Threshold:   2,
+		Others:      []OtherSignatories{{"0x01", "0x02"}},
+		DestAddress: "0xabcd",
+		DestAmount:  "1000",
+	}
+	executed := MultiSigAsMulti{
+		CallHash:   types.Hash{0x02},
+		OriginMsTx: MultiSignTx{BlockNumber: 101, MultiSignTxId: 1},
+		Executed:   true,
+	}
+	failed := MultiSigAsMulti{
+		CallHash:   types.Hash{0x03},
+		OriginMsTx: MultiSignTx{BlockNumber: 102, MultiSignTxId: 1},
+		Failed:     true,
+		Execution:  MultiSignTx{BlockNumber: 110, MultiSignTxId: 3},
+	}
+	for _, ms := range []MultiSigAsMulti{pending, executed, failed} {
+		err = s.StoreMultisig(ms)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The big-endian bytes of 1e18 are not valid UTF-8
+	amount, _ := big.NewInt(0).SetString("1000000000000000000", 10)
+	transfer := msg.NewFungibleTransfer(2, 1, 7, amount, msg.ResourceId{1}, []byte("0xabcd"))
+	transfer.Source = 2
+	dest := newDest(transfer)
+	err = s.StoreMessage(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen the store to simulate a restart
+	err = s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = NewMultisigStore(dir, 1, "relayer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	msTxs, err := s.LoadMultisigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msTxs) != 1 || !reflect.DeepEqual(msTxs[pending.CallHash], pending) {
+		t.Fatalf("Got: %v Expected only: %v", msTxs, pending)
+	}
+
+	// Executed and failed multisigs should have been pruned from the database
+	for _, ms := range []MultiSigAsMulti{executed, failed} {
+		ok, err := s.db.Has(multisigKey(ms.CallHash), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Fatalf("multisig %s was not pruned", ms.CallHash.Hex())
+		}
+	}
+
+	dests, err := s.LoadMessages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dests) != 1 || !reflect.DeepEqual(dests[0], dest) {
+		t.Fatalf("Got: %v Expected: %v", dests, dest)
+	}
+	m := dests[0].message(dests[0].Source, 1, transfer.ResourceId)
+	if !reflect.DeepEqual(m, transfer) {
+		t.Fatalf("Got: %v Expected: %v", m, transfer)
+	}
+
+	err = s.DeleteMessage(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dests, err = s.LoadMessages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dests) != 0 {
+		t.Fatalf("Got: %v Expected no messages", dests)
+	}
+
+	refund := Refund{DepositNonce: 1000012, Sender: "0x01", Amount: "10", Reason: "below fee"}
+	err = s.StoreRefund(refund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refunds, err := s.LoadRefunds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refunds) != 1 || refunds[0] != refund {
+		t.Fatalf("Got: %v Expected: %v", refunds, refund)
+	}
+	err = s.DeleteRefund(refund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refunds, err = s.LoadRefunds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refunds) != 0 {
+		t.Fatalf("Got: %v Expected no refunds", refunds)
+	}
+
+	migration, err := s.LoadMigration()
+	if err != nil || migration != nil {
+		t.Fatalf("Got: %v %v Expected no migration", migration, err)
+	}
+	execution := MultiSignTx{BlockNumber: 1005, MultiSignTxId: 2}
+	err = s.StoreMigration(execution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migration, err = s.LoadMigration()
+	if err != nil || migration == nil || *migration != execution {
+		t.Fatalf("Got: %v %v Expected: %v", migration, err, execution)
+	}
+}
+
+func TestDestMessage(t *testing.T) {
+	transfer := Dest{DepositNonce: 7, DestAddress: "0xabcd", Amount: "1000", Type: msg.FungibleTransfer}
+	m := transfer.message(2, 1, msg.ResourceId{1})
+	if m.Type != msg.FungibleTransfer || len(m.Payload) != 2 {
+		t.Fatalf("Got: %v Expected a fungible transfer", m)
+	}
+	if !reflect.DeepEqual(m.Payload[0], big.NewInt(1000).Bytes()) {
+		t.Fatalf("Got: %v Expected: %v", m.Payload[0], big.NewInt(1000).Bytes())
+	}
+
+	nft := Dest{DepositNonce: 7, DestAddress: "0xabcd", Amount: "5", Type: msg.NonFungibleTransfer}
+	m = nft.message(2, 1, msg.ResourceId{3})
+	if m.Type != msg.NonFungibleTransfer || len(m.Payload) != 3 {
+		t.Fatalf("Got: %v Expected a non fungible transfer", m)
+	}
+	if !reflect.DeepEqual(m.Payload[0], []byte{5}) {
+		t.Fatalf("Got: %v Expected: %v", m.Payload[0], []byte{5})
+	}
+}
+
+func TestNewDest(t *testing.T) {
+	generic := msg.NewGenericTransfer(2, 1, 7, msg.ResourceId{4}, []byte{1, 2})
+	generic.Source = 2
+	d := newDest(generic)
+	if !reflect.DeepEqual(d.Metadata, []byte{1, 2}) || d.DestAddress != "" || d.Source != 2 {
+		t.Fatalf("Got: %v Expected the metadata of: %v", d, generic)
+	}
+	m := d.message(d.Source, 1, generic.ResourceId)
+	if !reflect.DeepEqual(m, generic) {
+		t.Fatalf("Got: %v Expected: %v", m, generic)
+	}
+
+	transfer := msg.NewFungibleTransfer(2, 1, 7, big.NewInt(1000), msg.ResourceId{1}, []byte("0xabcd"))
+	d = newDest(transfer)
+	if d.DestAddress != "0xabcd" || d.Amount != "1000" || d.Type != msg.FungibleTransfer {
+		t.Fatalf("Got: %v Expected the transfer of: %v", d, transfer)
+	}
+}
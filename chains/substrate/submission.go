@@ -0,0 +1,232 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v2/rpc/author"
+	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
+	rtypes "github.com/rjmand/go-substrate-rpc-client/v2/types"
+)
+
+// Time to wait for a submitted extrinsic to be included in a block
+var SubmissionTimeout = time.Minute * 2
+
+// Classes of failed submissions, the errors returned by submitTx wrap one of them
+var (
+	// ErrAlreadyApproved the relayer already approved the multisig
+	ErrAlreadyApproved = errors.New("multisig already approved")
+	// ErrTimepoint the multisig on chain does not match the one known to the listener, it was opened meanwhile
+	ErrTimepoint = errors.New("multisig timepoint mismatch")
+	// ErrInsufficientBalance the relayer can not pay the fees or the multisig deposit
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	// ErrNotIncluded the extrinsic was dropped, invalid or not included in time
+	ErrNotIncluded = errors.New("extrinsic not included")
+	// ErrExtrinsicFailed the extrinsic failed for another reason
+	ErrExtrinsicFailed = errors.New("extrinsic failed")
+)
+
+// Class of the module errors of ExtrinsicFailed
+var dispatchErrors = map[string]error{
+	"Multisig.AlreadyApproved":     ErrAlreadyApproved,
+	"Multisig.NoTimepoint":         ErrTimepoint,
+	"Multisig.WrongTimepoint":      ErrTimepoint,
+	"Multisig.UnexpectedTimepoint": ErrTimepoint,
+	"Multisig.AlreadyStored":       ErrTimepoint,
+	"Balances.InsufficientBalance": ErrInsufficientBalance,
+	"Balances.KeepAlive":           ErrInsufficientBalance,
+}
+
+// submitTx signs the call, submits it and tracks it until it is in a block. The outcome of the
+// extrinsic is decoded from its events, a failure is returned wrapped in one of the classes above.
+func (w *writer) submitTx(c types.Call) error {
+	ext, err := w.signTx(c)
+	if err != nil {
+		return err
+	}
+
+	sub, err := w.msApi.RPC.Author.SubmitAndWatchExtrinsic(ext)
+	if err != nil {
+		return classifyPoolError(err)
+	}
+	defer sub.Unsubscribe()
+
+	hash, err := w.watchSubmission(sub)
+	if err != nil {
+		return err
+	}
+	return w.extrinsicResult(hash, ext)
+}
+
+// signTx builds the extrinsic of the call signed by the relayer with its next nonce
+func (w *writer) signTx(c types.Call) (types.Extrinsic, error) {
+	w.UpdateMetadate()
+	var err error
+	for i := 0; i < BlockRetryLimit; i++ {
+		if i != 0 {
+			w.log.Warn("Failed to prepare extrinsic, retrying", "err", err)
+			time.Sleep(BlockRetryInterval)
+		}
+
+		var key types.StorageKey
+		key, err = types.CreateStorageKey(w.meta, "System", "Account", w.relayer.kr.PublicKey, nil)
+		if err != nil {
+			continue
+		}
+		var accountInfo types.AccountInfo
+		var ok bool
+		ok, err = w.msApi.RPC.State.GetStorageLatest(key, &accountInfo)
+		if err != nil {
+			continue
+		}
+		if !ok {
+			err = fmt.Errorf("%w: relayer account %s does not exist", ErrInsufficientBalance, w.relayer.kr.Address)
+			continue
+		}
+
+		var o signingOptions
+		o, err = w.conn.signingOptions(uint64(accountInfo.Nonce))
+		if err != nil {
+			continue
+		}
+		ext := types.NewExtrinsic(c)
+		err = ext.MultiSign(w.relayer.kr, o.centrifuge())
+		if err != nil {
+			return types.Extrinsic{}, err
+		}
+		return ext, nil
+	}
+	return types.Extrinsic{}, fmt.Errorf("failed to prepare extrinsic after %d attempts: %w", BlockRetryLimit, err)
+}
+
+// watchSubmission waits for the extrinsic to be in a block and returns the hash of the block
+func (w *writer) watchSubmission(sub *author.ExtrinsicStatusSubscription) (types.Hash, error) {
+	timeout := time.After(SubmissionTimeout)
+	for {
+		select {
+		case <-w.conn.stop:
+			return types.Hash{}, TerminatedError
+		case <-timeout:
+			return types.Hash{}, fmt.Errorf("%w: not in a block after %s", ErrNotIncluded, SubmissionTimeout)
+		case status := <-sub.Chan():
+			switch {
+			case status.IsInBlock:
+				w.log.Debug("Extrinsic included in block", "block", status.AsInBlock.Hex())
+				return status.AsInBlock, nil
+			case status.IsFinalized:
+				w.log.Debug("Extrinsic finalized", "block", status.AsFinalized.Hex())
+				return status.AsFinalized, nil
+			case status.IsRetracted:
+				// The extrinsic goes back to the pool and may be included in another block
+				w.log.Debug("Extrinsic retracted", "block", status.AsRetracted.Hex())
+			case status.IsDropped:
+				return types.Hash{}, fmt.Errorf("%w: dropped from the pool", ErrNotIncluded)
+			case status.IsUsurped:
+				return types.Hash{}, fmt.Errorf("%w: usurped by %s", ErrNotIncluded, status.AsUsurped.Hex())
+			case status.IsInvalid:
+				return types.Hash{}, fmt.Errorf("%w: invalid", ErrNotIncluded)
+			}
+		case err := <-sub.Err():
+			return types.Hash{}, fmt.Errorf("%w: subscription error: %s", ErrNotIncluded, err)
+		}
+	}
+}
+
+// extrinsicResult finds the extrinsic in the block and decodes its ExtrinsicSuccess or ExtrinsicFailed event
+func (w *writer) extrinsicResult(blockHash types.Hash, ext types.Extrinsic) error {
+	encoded, err := types.EncodeToBytes(ext)
+	if err != nil {
+		return err
+	}
+	block, err := w.msApi.RPC.Chain.GetBlock(blockHash)
+	if err != nil {
+		return err
+	}
+	index := -1
+	for i, e := range block.Block.Extrinsics {
+		b, err := types.EncodeToBytes(e)
+		if err == nil && bytes.Equal(b, encoded) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("extrinsic not found in block %s", blockHash.Hex())
+	}
+
+	events, err := w.listener.getEvents(rtypes.Hash(blockHash))
+	if err != nil {
+		return err
+	}
+	for _, evt := range events.GetSystemExtrinsicSuccess() {
+		if evt.Phase.IsApplyExtrinsic && int(evt.Phase.AsApplyExtrinsic) == index {
+			return nil
+		}
+	}
+	for _, evt := range events.GetSystemExtrinsicFailed() {
+		if evt.Phase.IsApplyExtrinsic && int(evt.Phase.AsApplyExtrinsic) == index {
+			return classifyDispatchError(w.listener.client.Meta, evt.DispatchError)
+		}
+	}
+	return fmt.Errorf("no result event for extrinsic %d of block %s", index, blockHash.Hex())
+}
+
+// classifyDispatchError names the module error of a failed extrinsic and wraps its class
+func classifyDispatchError(meta *rtypes.Metadata, dispatchErr rtypes.DispatchError) error {
+	if !dispatchErr.HasModule {
+		return fmt.Errorf("%w: dispatch error %d", ErrExtrinsicFailed, dispatchErr.Error)
+	}
+	name, ok := moduleErrorName(meta, dispatchErr.Module, dispatchErr.Error)
+	if !ok {
+		return fmt.Errorf("%w: module %d error %d", ErrExtrinsicFailed, dispatchErr.Module, dispatchErr.Error)
+	}
+	if class, ok := dispatchErrors[name]; ok {
+		return fmt.Errorf("%w: %s", class, name)
+	}
+	return fmt.Errorf("%w: %s", ErrExtrinsicFailed, name)
+}
+
+// moduleErrorName returns the Module.Error name of a module error from the metadata
+func moduleErrorName(meta *rtypes.Metadata, module, index uint8) (string, bool) {
+	var name string
+	var errs []rtypes.ErrorMetadataV8
+	switch {
+	case meta.IsMetadataV12:
+		for _, m := range meta.AsMetadataV12.Modules {
+			if m.Index == module {
+				name, errs = string(m.Name), m.Errors
+			}
+		}
+	case meta.IsMetadataV11:
+		if int(module) < len(meta.AsMetadataV11.Modules) {
+			m := meta.AsMetadataV11.Modules[module]
+			name, errs = string(m.Name), m.Errors
+		}
+	case meta.IsMetadataV10:
+		if int(module) < len(meta.AsMetadataV10.Modules) {
+			m := meta.AsMetadataV10.Modules[module]
+			name, errs = string(m.Name), m.Errors
+		}
+	}
+	if name == "" || int(index) >= len(errs) {
+		return "", false
+	}
+	return name + "." + string(errs[index].Name), true
+}
+
+// classifyPoolError wraps the error of a submission rejected by the transaction pool
+func classifyPoolError(err error) error {
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "inability to pay some fees"), strings.Contains(msg, "balance too low"):
+		return fmt.Errorf("%w: %s", ErrInsufficientBalance, err)
+	default:
+		return fmt.Errorf("%w: %s", ErrNotIncluded, err)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"errors"
+	"testing"
+
+	rtypes "github.com/rjmand/go-substrate-rpc-client/v2/types"
+)
+
+func testMetadata() *rtypes.Metadata {
+	errs := func(names ...string) []rtypes.ErrorMetadataV8 {
+		var res []rtypes.ErrorMetadataV8
+		for _, n := range names {
+			res = append(res, rtypes.ErrorMetadataV8{Name: rtypes.Text(n)})
+		}
+		return res
+	}
+	return &rtypes.Metadata{
+		Version:       12,
+		IsMetadataV12: true,
+		AsMetadataV12: rtypes.MetadataV12{
+			Modules: []rtypes.ModuleMetadataV12{
+				{Name: "System", Index: 0},
+				{Name: "Balances", Errors: errs("VestingBalance", "LiquidityRestrictions", "Overflow", "InsufficientBalance"), Index: 5},
+				{Name: "Multisig", Errors: errs("MinimumThreshold", "AlreadyApproved", "NoApprovalsNeeded", "TooFewSignatories",
+					"TooManySignatories", "SignatoriesOutOfOrder", "SenderInSignatories", "NotFound", "NotOwner", "NoTimepoint", "WrongTimepoint"), Index: 31},
+			},
+		},
+	}
+}
+
+func TestModuleErrorName(t *testing.T) {
+	meta := testMetadata()
+	testCases := []struct {
+		module   uint8
+		index    uint8
+		expected string
+		ok       bool
+	}{
+		{31, 1, "Multisig.AlreadyApproved", true},
+		{31, 9, "Multisig.NoTimepoint", true},
+		{5, 3, "Balances.InsufficientBalance", true},
+		{5, 4, "", false},
+		{2, 0, "", false},
+	}
+
+	for _, tc := range testCases {
+		name, ok := moduleErrorName(meta, tc.module, tc.index)
+		if name != tc.expected || ok != tc.ok {
+			t.Fatalf("Got: %s %v Expected: %s %v", name, ok, tc.expected, tc.ok)
+		}
+	}
+}
+
+func TestClassifyDispatchError(t *testing.T) {
+	meta := testMetadata()
+	testCases := []struct {
+		err      rtypes.DispatchError
+		expected error
+	}{
+		{rtypes.DispatchError{HasModule: true, Module: 31, Error: 1}, ErrAlreadyApproved},
+		{rtypes.DispatchError{HasModule: true, Module: 31, Error: 9}, ErrTimepoint},
+		{rtypes.DispatchError{HasModule: true, Module: 31, Error: 10}, ErrTimepoint},
+		{rtypes.DispatchError{HasModule: true, Module: 5, Error: 3}, ErrInsufficientBalance},
+		{rtypes.DispatchError{HasModule: true, Module: 31, Error: 7}, ErrExtrinsicFailed},
+		{rtypes.DispatchError{HasModule: true, Module: 2, Error: 0}, ErrExtrinsicFailed},
+		{rtypes.DispatchError{Error: 1}, ErrExtrinsicFailed},
+	}
+
+	for _, tc := range testCases {
+		err := classifyDispatchError(meta, tc.err)
+		if !errors.Is(err, tc.expected) {
+			t.Fatalf("Got: %v Expected: %v", err, tc.expected)
+		}
+	}
+}
+
+func TestClassifyPoolError(t *testing.T) {
+	err := classifyPoolError(errors.New("1010: Invalid Transaction: Inability to pay some fees , e.g. account balance too low"))
+	if !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("Got: %v Expected: %v", err, ErrInsufficientBalance)
+	}
+	err = classifyPoolError(errors.New("1014: Priority is too low"))
+	if !errors.Is(err, ErrNotIncluded) {
+		t.Fatalf("Got: %v Expected: %v", err, ErrNotIncluded)
+	}
+}
@@ -5,9 +5,11 @@ package substrate
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/centrifuge/go-substrate-rpc-client/v2/scale"
 	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
 	"github.com/rjman-self/platdot-utils/msg"
+	"golang.org/x/crypto/blake2b"
 	"math/big"
 )
 
@@ -24,7 +26,80 @@ type Round struct {
 type Dest struct {
 	DepositNonce msg.Nonce
 	DestAddress  string
-	DestAmount   string
+	Amount       string // Decimal amount, or the token id of a non fungible transfer
+	ResourceId   string // Hex encoded
+	Source       msg.ChainId
+	Type         msg.TransferType
+	Metadata     []byte // The metadata of a generic transfer
+}
+
+// newDest records the transfer of a message to resume it after a restart
+func newDest(m msg.Message) Dest {
+	d := Dest{
+		DepositNonce: m.DepositNonce,
+		ResourceId:   m.ResourceId.Hex(),
+		Source:       m.Source,
+		Type:         m.Type,
+	}
+	if m.Type == msg.GenericTransfer {
+		d.Metadata = m.Payload[0].([]byte)
+		return d
+	}
+	d.DestAddress = string(m.Payload[1].([]byte))
+	d.Amount = big.NewInt(0).SetBytes(m.Payload[0].([]byte)).String()
+	return d
+}
+
+// message rebuilds the transfer that created the Dest, it is used to resume a stored redemption
+func (d Dest) message(source, dest msg.ChainId, resourceId msg.ResourceId) msg.Message {
+	m := msg.Message{
+		Source:       source,
+		Destination:  dest,
+		Type:         msg.FungibleTransfer,
+		DepositNonce: d.DepositNonce,
+		ResourceId:   resourceId,
+		Payload: []interface{}{
+			d.amount(),
+			[]byte(d.DestAddress),
+		},
+	}
+	switch d.Type {
+	case msg.NonFungibleTransfer:
+		// The metadata of the instance is kept by the substrate chain, it is not needed to release it
+		m.Type = msg.NonFungibleTransfer
+		m.Payload = append(m.Payload, []byte{})
+	case msg.GenericTransfer:
+		m.Type = msg.GenericTransfer
+		m.Payload = []interface{}{d.Metadata}
+	}
+	return m
+}
+
+// amount returns the big-endian amount of the transfer
+func (d Dest) amount() []byte {
+	amount, ok := big.NewInt(0).SetString(d.Amount, 10)
+	if !ok {
+		return nil
+	}
+	return amount.Bytes()
+}
+
+// Rejected is a burn on another chain whose redemption can not be made, e.g. its amount is below the fee or its
+// recipient is invalid. The tokens are already burned, it is kept for the operator, who requeues the deposit on
+// its source chain once the cause is fixed or returns the tokens otherwise.
+type Rejected struct {
+	Dest
+	Reason string
+}
+
+// Refund is a deposit into the multisig that can not be bridged, it is returned to the sender
+type Refund struct {
+	DepositNonce msg.Nonce
+	Sender       string
+	Amount       string // The amount returned once the refund fee is paid, the instance for a class of the Uniques pallet
+	Fee          string // The refund fee kept by the multisig
+	Asset        Asset
+	Reason       string
 }
 
 func EncodeCall(call types.Call) []byte {
@@ -33,3 +108,29 @@ func EncodeCall(call types.Call) []byte {
 	_ = encoderGoRPC.Encode(call)
 	return buffer.Bytes()
 }
+
+// CallHash returns the blake2-256 hash of the encoded call, the Multisig pallet identifies a multisig operation by it
+func CallHash(call types.Call) types.Hash {
+	return blake2b.Sum256(EncodeCall(call))
+}
+
+// redeemRemark is remarked together with a redemption, it binds the deposit nonce into the call. The
+// burns of other chains than the one of the resource also bind their source, the nonces of two chains
+// may be the same.
+func redeemRemark(res Resource, source msg.ChainId, nonce msg.Nonce) []byte {
+	if source == res.Dest {
+		return []byte(fmt.Sprintf("platdot:redeem:%d", nonce))
+	}
+	return []byte(fmt.Sprintf("platdot:redeem:%d:%d", source, nonce))
+}
+
+// genericRemark is remarked together with the call of a generic transfer, it binds the source and the
+// deposit nonce into the call
+func genericRemark(source msg.ChainId, nonce msg.Nonce) []byte {
+	return []byte(fmt.Sprintf("platdot:generic:%d:%d", source, nonce))
+}
+
+// refundRemark is remarked together with a refund, it binds the deposit nonce into the call
+func refundRemark(nonce msg.Nonce) []byte {
+	return []byte(fmt.Sprintf("platdot:refund:%d", nonce))
+}
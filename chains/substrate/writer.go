@@ -8,12 +8,14 @@ import (
 	"fmt"
 	"github.com/ChainSafe/log15"
 	gsrpc "github.com/centrifuge/go-substrate-rpc-client/v2"
-	"github.com/centrifuge/go-substrate-rpc-client/v2/rpc/author"
 	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rjman-self/Platdot/chains"
 	utils "github.com/rjman-self/Platdot/shared/substrate"
 	"github.com/rjman-self/platdot-utils/core"
 	metrics "github.com/rjman-self/platdot-utils/metrics/types"
 	"github.com/rjman-self/platdot-utils/msg"
+	"math"
 	"math/big"
 	"sync"
 	"time"
@@ -24,7 +26,6 @@ var _ core.Writer = &writer{}
 var TerminatedError = errors.New("terminated")
 
 const RoundInterval = time.Second * 6
-const oneToken = 1000000
 
 var NotExecuted = MultiSignTx{
 	BlockNumber:   -1,
@@ -36,6 +37,11 @@ var YesVoted = MultiSignTx{
 	MultiSignTxId: 1,
 }
 
+var ExecutionFailed = MultiSignTx{
+	BlockNumber:   -1,
+	MultiSignTxId: 2,
+}
+
 type writer struct {
 	meta       *types.Metadata
 	conn       *Connection
@@ -47,7 +53,11 @@ type writer struct {
 	msApi      *gsrpc.SubstrateAPI
 	relayer    Relayer
 	maxWeight  uint64
-	messages   map[Dest]bool
+	messages   map[types.Hash]Dest
+	refunds    map[types.Hash]Refund
+	retracted  map[types.Hash]bool
+	msgLock    sync.RWMutex
+	skips      *chains.SkipList
 }
 
 func NewWriter(conn *Connection, listener *listener, log log15.Logger, sysErr chan<- error,
@@ -75,191 +85,518 @@ func NewWriter(conn *Connection, listener *listener, log log15.Logger, sysErr ch
 		msApi:      msApi,
 		relayer:    relayer,
 		maxWeight:  weight,
-		messages:   make(map[Dest]bool, InitCapacity),
+		messages:   make(map[types.Hash]Dest, InitCapacity),
+		refunds:    make(map[types.Hash]Refund, InitCapacity),
+		retracted:  make(map[types.Hash]bool),
+		skips:      chains.NewSkipList(),
 	}
 }
+
 func (w *writer) ResolveMessage(m msg.Message) bool {
-	w.checkRepeat(m)
+	if m.Type == chains.RetractTransfer {
+		return w.retract(chains.Retracted(m))
+	}
+	if w.skips.Has(m.Source, m.DepositNonce) {
+		w.log.Warn("Message is skipped", "Source", m.Source, "DepositNonce", m.DepositNonce)
+		return true
+	}
 	w.log.Info("Start a redeemTx...", "DepositNonce", m.DepositNonce)
 
+	call, err := w.redeemCall(m)
+	if err != nil {
+		w.reject(m, err)
+		return false
+	}
+	w.clearRejected(m)
+	callHash := CallHash(call)
+	// The deposit may be included again after the reorg that retracted it
+	w.msgLock.Lock()
+	delete(w.retracted, callHash)
+	w.msgLock.Unlock()
+
 	/// Mark isProcessing
-	destMessage := Dest{
-		DepositNonce: m.DepositNonce,
-		DestAddress:  string(m.Payload[1].([]byte)),
-		DestAmount:   string(m.Payload[0].([]byte)),
+	if !w.storeMessage(callHash, newDest(m)) {
+		w.log.Info("Message is already processing", "DepositNonce", m.DepositNonce, "CallHash", callHash.Hex())
+		return true
+	}
+
+	w.resolve(w.relayer, m.Source, m.DepositNonce, call, callHash, func() { w.deleteMessage(callHash) })
+	return true
+}
+
+// start resumes the redemptions and refunds that were still in progress when the relayer stopped,
+// then processes the refunds of the deposits rejected by the listener
+func (w *writer) start() error {
+	dests, err := w.listener.msStore.LoadMessages()
+	if err != nil {
+		return fmt.Errorf("failed to load pending messages: %w", err)
+	}
+
+	for _, dest := range dests {
+		w.log.Info("Resume a redeemTx from store", "DepositNonce", dest.DepositNonce)
+		res, ok := w.listener.resources.ByResource(msg.ResourceIdFromSlice(common.FromHex(dest.ResourceId)))
+		if !ok {
+			return fmt.Errorf("no resource %s for the stored redemption of nonce %d", dest.ResourceId, dest.DepositNonce)
+		}
+		m := dest.message(dest.Source, w.listener.chainId, res.ResourceId)
+		call, err := w.redeemCall(m)
+		if err != nil {
+			return fmt.Errorf("failed to construct redeem call of nonce %d: %w", dest.DepositNonce, err)
+		}
+		callHash := CallHash(call)
+		// A message routed before the start, by a replay, is already being processed
+		if !w.storeMessage(callHash, dest) {
+			w.log.Info("Message is already processing", "DepositNonce", dest.DepositNonce, "CallHash", callHash.Hex())
+			continue
+		}
+		w.resolve(w.relayer, m.Source, m.DepositNonce, call, callHash, func() { w.deleteMessage(callHash) })
+	}
+
+	refunds, err := w.listener.msStore.LoadRefunds()
+	if err != nil {
+		return fmt.Errorf("failed to load pending refunds: %w", err)
+	}
+	for _, r := range refunds {
+		w.log.Info("Resume a refund from store", "DepositNonce", r.DepositNonce)
+		err = w.resolveRefund(r)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Every relayer follows the migration, the deposits into the multisig of the previous set are bridged until it is executed
+	if w.relayer.rotation != nil {
+		go w.migrate()
 	}
-	w.messages[destMessage] = true
 
+	go func() {
+		for {
+			select {
+			case r := <-w.listener.refunds:
+				err := w.resolveRefund(r)
+				if err != nil {
+					w.log.Error("Failed to process refund", "DepositNonce", r.DepositNonce, "err", err)
+				}
+			case <-w.listener.stop:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// reject records a burn whose redemption call can not be made, the tokens are burned on the source chain so the
+// operator recovers them from the record
+func (w *writer) reject(m msg.Message, reason error) {
+	r := Rejected{Dest: newDest(m), Reason: reason.Error()}
+	w.log.Error("Redemption rejected, the burned tokens have to be recovered", "Source", m.Source, "DepositNonce", m.DepositNonce,
+		"ResourceId", r.ResourceId, "Type", r.Type, "Amount", r.Amount, "Recipient", r.DestAddress, "err", reason)
+	err := w.listener.msStore.StoreRejected(r)
+	if err != nil {
+		w.log.Error("Failed to write rejected redemption to multisig store", "Source", m.Source, "DepositNonce", m.DepositNonce, "err", err)
+	}
+}
+
+// clearRejected deletes the record of an earlier rejection of the message, e.g. once its deposit is requeued
+func (w *writer) clearRejected(m msg.Message) {
+	err := w.listener.msStore.DeleteRejected(m.Source, m.DepositNonce)
+	if err != nil {
+		w.log.Error("Failed to delete rejected redemption from multisig store", "Source", m.Source, "DepositNonce", m.DepositNonce, "err", err)
+	}
+}
+
+// resolveRefund returns a rejected deposit to its sender through the multisig
+func (w *writer) resolveRefund(r Refund) error {
+	call, err := w.refundCall(r)
+	if err != nil {
+		return fmt.Errorf("failed to construct refund call of nonce %d: %w", r.DepositNonce, err)
+	}
+	callHash := CallHash(call)
+	w.msgLock.Lock()
+	_, ok := w.refunds[callHash]
+	w.refunds[callHash] = r
+	w.msgLock.Unlock()
+	if ok {
+		return nil
+	}
+
+	w.log.Info("Start a refundTx...", "DepositNonce", r.DepositNonce, "Sender", r.Sender, "Amount", r.Amount, "Fee", r.Fee, "Reason", r.Reason)
+	w.resolve(w.relayer, w.listener.chainId, r.DepositNonce, call, callHash, func() {
+		w.msgLock.Lock()
+		delete(w.refunds, callHash)
+		w.msgLock.Unlock()
+		err := w.listener.msStore.DeleteRefund(r)
+		if err != nil {
+			w.log.Error("Failed to delete refund from multisig store", "DepositNonce", r.DepositNonce, "err", err)
+		}
+	})
+	return nil
+}
+
+// resolve votes for the call of the multisig until it is executed or skipped, then finish is called.
+// The relayer set controlling the multisig at the finalized block votes.
+func (w *writer) resolve(relayer Relayer, source msg.ChainId, nonce msg.Nonce, call types.Call, callHash types.Hash, finish func()) {
 	go func() {
 		// calculate spend time
 		start := time.Now()
 		defer func() {
 			cost := time.Since(start)
-			fmt.Printf("Relayer #%v finish depositNonce %v cost %v\n", w.relayer.currentRelayer, m.DepositNonce, cost)
+			fmt.Printf("Relayer #%v finish depositNonce %v cost %v\n", w.relayer.currentRelayer, nonce, cost)
 		}()
 
 		for {
-			isFinished, currentTx := w.redeemTx(m)
-			if isFinished {
-				var mutex sync.Mutex
-				mutex.Lock()
+			select {
+			case <-w.conn.stop:
+				return
+			default:
+			}
+			if w.skips.Has(source, nonce) {
+				w.log.Warn("Message is skipped, stop voting", "Source", source, "DepositNonce", nonce, "CallHash", callHash.Hex())
+				w.listener.deleteMultisig(callHash)
+				finish()
+				break
+			}
+			if w.isRetracted(callHash) {
+				w.log.Warn("Message is retracted, stop voting", "Source", source, "DepositNonce", nonce, "CallHash", callHash.Hex())
+				w.listener.deleteMultisig(callHash)
+				finish()
+				break
+			}
 
+			isFinished, currentTx := w.redeemTx(relayer, nonce, call, callHash)
+			if isFinished {
 				/// If currentTx is Vote
 				if currentTx == YesVoted {
 					//fmt.Printf("I have Vote, wait executing\n")
 					time.Sleep(RoundInterval * time.Duration(w.relayer.totalRelayers) / 2)
 				}
 
+				/// If the call was dispatched with an error, leave it to the operator
+				if currentTx == ExecutionFailed {
+					w.log.Error("MultiSig extrinsic executed with a failed call, it is kept for a retry on restart or re-delivery", "DepositNonce", nonce, "CallHash", callHash.Hex())
+					w.listener.deleteMultisig(callHash)
+					w.release(callHash)
+					break
+				}
+
 				if currentTx != YesVoted && currentTx != NotExecuted {
-					w.log.Info("MultiSig extrinsic executed!", "DepositNonce", m.DepositNonce, "OriginBlock", currentTx.BlockNumber)
+					w.log.Info("MultiSig extrinsic executed!", "DepositNonce", nonce, "OriginBlock", currentTx.BlockNumber)
 					/// Delete Listener msTx
-					delete(w.listener.msTxAsMulti, currentTx)
-
-					var mutex sync.Mutex
-					mutex.Lock()
-					/// Delete Message
-					dm := Dest{
-						DepositNonce: m.DepositNonce,
-						DestAddress:  string(m.Payload[1].([]byte)),
-						DestAmount:   string(m.Payload[0].([]byte)),
-					}
-					delete(w.messages, dm)
-					mutex.Unlock()
-
-					w.log.Info("finish a redeemTx", "DepositNonce", m.DepositNonce)
+					w.listener.deleteMultisig(callHash)
+
+					finish()
+
+					w.log.Info("finish a multisig transaction", "DepositNonce", nonce)
 					break
 				}
 			}
 		}
 	}()
+}
+
+// retract stops the redemption of a message whose deposit was orphaned by a reorg on the source chain
+func (w *writer) retract(m msg.Message) bool {
+	call, err := w.redeemCall(m)
+	if err != nil {
+		w.log.Error("Failed to construct redeem call of retracted message", "DepositNonce", m.DepositNonce, "err", err)
+		return false
+	}
+	callHash := CallHash(call)
+	w.log.Warn("Retract message", "Source", m.Source, "DepositNonce", m.DepositNonce, "CallHash", callHash.Hex())
+
+	w.msgLock.Lock()
+	w.retracted[callHash] = true
+	w.msgLock.Unlock()
 	return true
 }
 
-func (w *writer) checkRepeat(m msg.Message) bool {
-	for {
-		isRepeat := false
-		/// Lock
-		var mutex sync.Mutex
-		mutex.Lock()
-		for dest := range w.messages {
-			if dest.DepositNonce != m.DepositNonce && dest.DestAmount == string(m.Payload[0].([]byte)) && dest.DestAddress == string(m.Payload[1].([]byte)) {
-				isRepeat = true
-			}
-		}
-		mutex.Unlock()
+func (w *writer) isRetracted(callHash types.Hash) bool {
+	w.msgLock.RLock()
+	defer w.msgLock.RUnlock()
+	return w.retracted[callHash]
+}
 
-		/// Check Repeat
-		if isRepeat {
-			repeatTime := RoundInterval
-			w.log.Info("Meet a Repeat Transaction", "DepositNonce", m.DepositNonce, "Waiting", repeatTime)
-			time.Sleep(repeatTime)
-		} else {
-			break
-		}
+// storeMessage marks the message as processing and persists it to the multisig store.
+// It returns false if the message is already processing.
+func (w *writer) storeMessage(callHash types.Hash, dest Dest) bool {
+	w.msgLock.Lock()
+	if _, ok := w.messages[callHash]; ok {
+		w.msgLock.Unlock()
+		return false
+	}
+	w.messages[callHash] = dest
+	w.msgLock.Unlock()
+
+	err := w.listener.msStore.StoreMessage(dest)
+	if err != nil {
+		w.log.Error("Failed to write message to multisig store", "DepositNonce", dest.DepositNonce, "err", err)
 	}
 	return true
 }
 
-func (w *writer) redeemTx(m msg.Message) (bool, MultiSignTx) {
+// release stops processing the call without deleting it from the multisig store, so a re-delivery of the
+// message or a restart processes it again
+func (w *writer) release(callHash types.Hash) {
+	w.msgLock.Lock()
+	delete(w.messages, callHash)
+	delete(w.refunds, callHash)
+	w.msgLock.Unlock()
+}
+
+// deleteMessage removes the finished message from memory and from the multisig store
+func (w *writer) deleteMessage(callHash types.Hash) {
+	w.msgLock.Lock()
+	dest, ok := w.messages[callHash]
+	delete(w.messages, callHash)
+	w.msgLock.Unlock()
+	if !ok {
+		return
+	}
+	err := w.listener.msStore.DeleteMessage(dest)
+	if err != nil {
+		w.log.Error("Failed to delete message from multisig store", "DepositNonce", dest.DepositNonce, "err", err)
+	}
+}
+
+// checkBytesCall checks in the metadata that the only argument of the call is a byte vector
+func checkBytesCall(meta *types.Metadata, call string) error {
+	if !meta.IsMetadataV12 {
+		return fmt.Errorf("unsupported metadata version to check call %s", call)
+	}
+	index, err := meta.FindCallIndex(call)
+	if err != nil {
+		return err
+	}
+	for _, mod := range meta.AsMetadataV12.Modules {
+		if !mod.HasCalls || mod.Index != index.SectionIndex {
+			continue
+		}
+		args := mod.Calls[index.MethodIndex].Args
+		if len(args) != 1 || (args[0].Type != "Vec<u8>" && args[0].Type != "Bytes") {
+			return fmt.Errorf("call %s does not take a byte vector: %v", call, args)
+		}
+		return nil
+	}
+	return fmt.Errorf("no module of call %s", call)
+}
+
+// redeemCall creates the call the multisig account executes for a redemption. The transfer is batched
+// with a remark of the deposit nonce, so identical transfers get different call hashes.
+func (w *writer) redeemCall(m msg.Message) (types.Call, error) {
+	res, ok := w.listener.resources.ByResource(m.ResourceId)
+	if !ok {
+		return types.Call{}, fmt.Errorf("%w: no asset for resource %s", ErrUnknownAsset, m.ResourceId.Hex())
+	}
+	if !w.listener.dests.Has(m.Source) {
+		return types.Call{}, fmt.Errorf("%w: burn of chain %d", ErrInvalidDest, m.Source)
+	}
+
 	w.UpdateMetadate()
 	types.SetSerDeOptions(types.SerDeOptions{NoPalletIndices: true})
+	if m.Type == msg.GenericTransfer && res.IsGeneric() {
+		return w.genericCall(res, m)
+	}
 
-	// BEGIN: Create a call of transfer
-	method := string(utils.BalancesTransferKeepAliveMethod)
+	var amount *big.Int
+	var err error
+	switch {
+	case m.Type == msg.FungibleTransfer && !res.Asset.IsUnique && !res.IsGeneric():
+		amount, err = w.redeemAmount(m)
+		if err != nil {
+			return types.Call{}, err
+		}
+	case m.Type == msg.NonFungibleTransfer && res.Asset.IsUnique:
+		// The token id is the instance locked in the multisig
+		amount = big.NewInt(0).SetBytes(m.Payload[0].([]byte))
+		w.log.Info("Release an instance", "DepositNonce", m.DepositNonce, "Asset", res.Asset, "Instance", amount)
+	default:
+		return types.Call{}, fmt.Errorf("%s of resource %s can not be redeemed", m.Type, m.ResourceId.Hex())
+	}
 
+	// Get recipient of Polkadot, the hex encoded account id or its ss58 address
+	recipientId, err := utils.ParseAccountID(string(m.Payload[1].([]byte)), w.listener.ss58Prefix)
+	if err != nil {
+		return types.Call{}, fmt.Errorf("invalid recipient: %w", err)
+	}
+	recipient := types.NewMultiAddressFromAccountID(recipientId)
+	w.log.Info("Redeem to recipient", "DepositNonce", m.DepositNonce, "Recipient", w.listener.address(recipientId))
+
+	// Create a transfer call of the asset
+	c, err := w.transferCall(res.Asset, recipient, amount)
+	if err != nil {
+		return types.Call{}, err
+	}
+
+	// Bind the deposit nonce into the call
+	remark, err := types.NewCall(w.meta, string(utils.SystemRemark), types.NewBytes(redeemRemark(res, m.Source, m.DepositNonce)))
+	if err != nil {
+		return types.Call{}, err
+	}
+
+	return types.NewCall(w.meta, string(utils.UtilityBatchAll), []types.Call{c, remark})
+}
+
+// genericCall creates the call the multisig account executes for a generic transfer. The call is one of the
+// GenericCalls and the metadata of the transfer is its only argument, it is never decoded as other arguments.
+func (w *writer) genericCall(res Resource, m msg.Message) (types.Call, error) {
+	metadata := m.Payload[0].([]byte)
+	w.log.Info("Execute a generic transfer", "DepositNonce", m.DepositNonce, "Call", res.Call, "Metadata", types.HexEncodeToString(metadata))
+
+	if !GenericCalls[res.Call] {
+		return types.Call{}, fmt.Errorf("%s is not one of the generic calls", res.Call)
+	}
+	err := checkBytesCall(w.meta, res.Call)
+	if err != nil {
+		return types.Call{}, err
+	}
+	c, err := types.NewCall(w.meta, res.Call, types.NewBytes(metadata))
+	if err != nil {
+		return types.Call{}, err
+	}
+
+	// Bind the source and deposit nonce into the call
+	remark, err := types.NewCall(w.meta, string(utils.SystemRemark), types.NewBytes(genericRemark(m.Source, m.DepositNonce)))
+	if err != nil {
+		return types.Call{}, err
+	}
+
+	return types.NewCall(w.meta, string(utils.UtilityBatchAll), []types.Call{c, remark})
+}
+
+// redeemAmount converts the amount of a fungible transfer to the substrate chain and deducts the fee
+func (w *writer) redeemAmount(m msg.Message) (*big.Int, error) {
 	// Convert AKSM amount to KSM amount
 	amount := big.NewInt(0).SetBytes(m.Payload[0].([]byte))
-	receiveAmount := big.NewInt(0).Div(amount, big.NewInt(oneToken))
+	receiveAmount, dust := w.listener.decimals.Get(m.ResourceId).ToSource(amount)
+	if dust.Sign() != 0 {
+		w.log.Warn("Redeem amount has dust that can not be paid out", "DepositNonce", m.DepositNonce, "Amount", amount, "Dust", dust)
+	}
 
 	// calculate fee and sendAmount
-	fixedFee := big.NewInt(FixedFee)
-	additionalFee := big.NewInt(0).Div(receiveAmount, big.NewInt(FeeRate))
-	fee := big.NewInt(0).Add(fixedFee, additionalFee)
-	actualAmount := big.NewInt(0).Sub(receiveAmount, fee)
-	sendAmount := types.NewUCompact(actualAmount)
-	fmt.Printf("AKSM to KSM, Amount is %v, Fee is %v, Actual_KSM_Amount = %v\n", receiveAmount, fee, actualAmount)
-
-	// Get recipient of Polkadot
-	recipient, _ := types.NewMultiAddressFromHexAccountID(string(m.Payload[1].([]byte)))
-
-	// Create a transfer_keep_alive call
-	c, err := types.NewCall(
-		w.meta,
-		method,
-		recipient,
-		sendAmount,
-	)
+	fee, err := w.listener.fees.Breakdown(m.ResourceId, Redeem, receiveAmount)
+	if err != nil {
+		return nil, err
+	}
+	actualAmount := fee.Net
+	fmt.Printf("AKSM to KSM, Amount is %v, Fee is %v, Actual_KSM_Amount = %v\n", receiveAmount, fee.Fee, actualAmount)
+	return actualAmount, nil
+}
 
+// refundCall creates the call the multisig account executes to return a rejected deposit to its sender
+func (w *writer) refundCall(r Refund) (types.Call, error) {
+	w.UpdateMetadate()
+	types.SetSerDeOptions(types.SerDeOptions{NoPalletIndices: true})
+
+	amount, ok := big.NewInt(0).SetString(r.Amount, 10)
+	if !ok {
+		return types.Call{}, fmt.Errorf("invalid refund amount %s", r.Amount)
+	}
+	senderId, err := utils.ParseAccountID(r.Sender, w.listener.ss58Prefix)
 	if err != nil {
-		fmt.Printf("NewCall err\n")
-		panic(err)
+		return types.Call{}, err
 	}
+	sender := types.NewMultiAddressFromAccountID(senderId)
 
-	// BEGIN: Create a call of MultiSignTransfer
-	mulMethod := string(utils.MultisigAsMulti)
-	var threshold = w.relayer.multiSignThreshold
+	c, err := w.transferCall(r.Asset, sender, amount)
+	if err != nil {
+		return types.Call{}, err
+	}
+	remark, err := types.NewCall(w.meta, string(utils.SystemRemark), types.NewBytes(refundRemark(r.DepositNonce)))
+	if err != nil {
+		return types.Call{}, err
+	}
 
-	// Get parameters of multiSignature
-	destAddress := string(m.Payload[1].([]byte))
+	return types.NewCall(w.meta, string(utils.UtilityBatchAll), []types.Call{c, remark})
+}
 
-	defer func() {
-		/// Single thread send one time each round
-		time.Sleep(RoundInterval)
-	}()
+// transferCall creates the call transferring an amount of the asset from the multisig to the recipient,
+// Balances.transfer_keep_alive for the native token, Assets.transfer for the assets and Uniques.transfer
+// for a class of the Uniques pallet. The amount of a class is the instance that is transferred.
+func (w *writer) transferCall(asset Asset, recipient types.MultiAddress, amount *big.Int) (types.Call, error) {
+	if asset.IsUnique {
+		if !amount.IsUint64() || amount.Uint64() > math.MaxUint32 {
+			return types.Call{}, fmt.Errorf("invalid instance %s of %s", amount, asset)
+		}
+		return types.NewCall(w.meta, string(utils.UniquesTransferMethod), types.NewUCompactFromUInt(uint64(asset.Id)), types.NewUCompact(amount), recipient)
+	}
+	if asset.IsAsset {
+		return types.NewCall(w.meta, string(utils.AssetsTransferMethod), types.NewUCompactFromUInt(uint64(asset.Id)), recipient, types.NewUCompact(amount))
+	}
+	return types.NewCall(w.meta, string(utils.BalancesTransferKeepAliveMethod), recipient, types.NewUCompact(amount))
+}
+
+func (w *writer) redeemTx(relayer Relayer, nonce msg.Nonce, c types.Call, callHash types.Hash) (bool, MultiSignTx) {
+	w.UpdateMetadate()
+	types.SetSerDeOptions(types.SerDeOptions{NoPalletIndices: true})
+
+	// BEGIN: Create a call of MultiSignTransfer
+	mulMethod := string(utils.MultisigAsMulti)
 
 	for {
-		processRound := (w.relayer.currentRelayer + uint64(m.DepositNonce)) % w.relayer.totalRelayers
-		round := w.getRound()
+		round, err := w.currentRound()
+		if err != nil {
+			w.log.Warn("Failed to get the round, retry", "depositNonce", nonce, "err", err)
+			time.Sleep(RoundInterval)
+			return false, NotExecuted
+		}
+		r := relayer.at(round.blockHeight.Uint64())
+		// A multisig opened by the previous set before the rotation is finished by that set
+		if ms, ok := w.listener.getMultisig(callHash); ok && !ms.Executed {
+			if set, ok := relayer.setOf(ms.Multisig); ok {
+				r = set
+			}
+		}
+		if !r.isMember() {
+			// The relayer is not a signatory of the set controlling the multisig, the others execute the call
+			if ms, ok := w.listener.getMultisig(callHash); ok && ms.Executed {
+				return true, ms.OriginMsTx
+			}
+			time.Sleep(RoundInterval)
+			return false, NotExecuted
+		}
+		threshold := r.multiSignThreshold
+		processRound := (r.currentRelayer + uint64(nonce)) % r.totalRelayers
+		round.blockRound.Mod(round.blockHeight, big.NewInt(int64(r.totalRelayers)))
 		if round.blockRound.Uint64() == processRound {
-			//fmt.Printf("process the message in block #%v, round #%v, depositnonce is %v\n", round.blockHeight, processRound, m.DepositNonce)
+			//fmt.Printf("process the message in block #%v, round #%v, depositnonce is %v\n", round.blockHeight, processRound, nonce)
 			// Try to find a exist MultiSignTx
-			var maybeTimePoint interface{}
+			var maybeTimePoint interface{} = []byte{}
 			maxWeight := types.Weight(0)
 
-			// Traverse all of matched Tx, included New、Approve、Executed
-			for _, ms := range w.listener.msTxAsMulti {
-				// Validate parameter
-				if ms.DestAddress == destAddress[2:] && ms.DestAmount == actualAmount.String() {
-					/// Once MultiSign Extrinsic is executed, stop sending Extrinsic to Polkadot
-					finished, executed := w.isFinish(ms)
-					if finished {
-						return finished, executed
-					}
-
-					/// Match the correct TimePoint
-					height := types.U32(ms.OriginMsTx.BlockNumber)
-					maybeTimePoint = TimePointSafe32{
-						Height: types.NewOptionU32(height),
-						Index:  types.U32(ms.OriginMsTx.MultiSignTxId),
-					}
-					maxWeight = types.Weight(w.maxWeight)
-					break
-				} else {
-					maybeTimePoint = []byte{}
+			// The multisig of the redemption is identified by its call hash, like the Multisig pallet does
+			if ms, ok := w.listener.getMultisig(callHash); ok && (ms.Executed || ms.isOf(r.multisig)) {
+				/// Once MultiSign Extrinsic is executed, stop sending Extrinsic to Polkadot
+				finished, executed := w.isFinish(r, ms)
+				if finished {
+					return finished, executed
 				}
-			}
 
-			if len(w.listener.msTxAsMulti) == 0 {
-				maybeTimePoint = []byte{}
+				/// Match the correct TimePoint
+				height := types.U32(ms.OriginMsTx.BlockNumber)
+				maybeTimePoint = TimePointSafe32{
+					Height: types.NewOptionU32(height),
+					Index:  types.U32(ms.OriginMsTx.MultiSignTxId),
+				}
+				maxWeight = types.Weight(w.maxWeight)
 			}
 
 			if maxWeight == 0 {
-				w.log.Info("Try to make a New MultiSign Tx!", "depositNonce", m.DepositNonce)
+				w.log.Info("Try to make a New MultiSign Tx!", "depositNonce", nonce, "CallHash", callHash.Hex())
 			} else {
 				_, height := maybeTimePoint.(TimePointSafe32).Height.Unwrap()
-				w.log.Info("Try to Approve a MultiSignTx!", "Block", height, "Index", maybeTimePoint.(TimePointSafe32).Index, "depositNonce", m.DepositNonce)
+				w.log.Info("Try to Approve a MultiSignTx!", "Block", height, "Index", maybeTimePoint.(TimePointSafe32).Index, "depositNonce", nonce)
 			}
 
-			mc, err := types.NewCall(w.meta, mulMethod, threshold, w.relayer.otherSignatories, maybeTimePoint, EncodeCall(c), false, maxWeight)
+			mc, err := types.NewCall(w.meta, mulMethod, threshold, r.otherSignatories, maybeTimePoint, EncodeCall(c), false, maxWeight)
 			if err != nil {
-				fmt.Printf("New MultiCall err\n")
-				panic(err)
+				// The metadata is updated before the next attempt, e.g. after a runtime upgrade
+				return w.submissionResult(nonce, callHash, fmt.Errorf("failed to construct the as_multi call: %w", err))
 			}
 			///END: Create a call of MultiSignTransfer
 
 			///BEGIN: Submit a MultiSignExtrinsic to Polkadot
-			w.submitTx(mc)
-			return false, NotExecuted
+			err = w.submitTx(mc)
+			return w.submissionResult(nonce, callHash, err)
 			///END: Submit a MultiSignExtrinsic to Polkadot
 		} else {
 			///Round over, wait a RoundInterval
@@ -268,117 +605,77 @@ func (w *writer) redeemTx(m msg.Message) (bool, MultiSignTx) {
 	}
 }
 
-func (w *writer) submitTx(c types.Call) {
-	// BEGIN: Get the essential information first
-	w.UpdateMetadate()
-	retryTimes := BlockRetryLimit
-	for {
-		// No more retries, stop submitting Tx
-		if retryTimes == 0 {
-			fmt.Printf("submit Tx failed, check it\n")
-		}
-		genesisHash, err := w.msApi.RPC.Chain.GetBlockHash(0)
-		if err != nil {
-			fmt.Printf("GetBlockHash err\n")
-			retryTimes--
-			continue
-		}
-		rv, err := w.msApi.RPC.State.GetRuntimeVersionLatest()
-		if err != nil {
-			fmt.Printf("GetRuntimeVersionLatest err\n")
-			retryTimes--
-			continue
-		}
-
-		key, err := types.CreateStorageKey(w.meta, "System", "Account", w.relayer.kr.PublicKey, nil)
-		if err != nil {
-			fmt.Printf("CreateStorageKey err\n")
-			retryTimes--
-			continue
-		}
-		// END: Get the essential information
-
-		// Validate account and get account information
-		var accountInfo types.AccountInfo
-		ok, err := w.msApi.RPC.State.GetStorageLatest(key, &accountInfo)
-		if err != nil || !ok {
-			fmt.Printf("GetStorageLatest err\n")
-			retryTimes--
-			continue
-		}
-		// Extrinsic nonce
-		nonce := uint32(accountInfo.Nonce)
-
-		// Construct signature option
-		o := types.SignatureOptions{
-			BlockHash:          genesisHash,
-			Era:                types.ExtrinsicEra{IsMortalEra: false},
-			GenesisHash:        genesisHash,
-			Nonce:              types.NewUCompactFromUInt(uint64(nonce)),
-			SpecVersion:        rv.SpecVersion,
-			Tip:                types.NewUCompactFromUInt(0),
-			TransactionVersion: rv.TransactionVersion,
-		}
-
-		// Create and Sign the MultiSign
-		ext := types.NewExtrinsic(c)
-		err = ext.MultiSign(w.relayer.kr, o)
-		if err != nil {
-			fmt.Printf("MultiTx Sign err\n")
-			panic(err)
-		}
-
-		// Do the transfer and track the actual status
-		_, _ = w.msApi.RPC.Author.SubmitAndWatchExtrinsic(ext)
-		//fmt.Printf("submit Tx, Relayer nonce is %v\n", nonce)
-		break
+// submissionResult decides how the redemption goes on after the extrinsic of the relayer was tracked
+func (w *writer) submissionResult(nonce msg.Nonce, callHash types.Hash, err error) (bool, MultiSignTx) {
+	switch {
+	case err == nil:
+		w.log.Info("MultiSign extrinsic succeeded, wait others", "depositNonce", nonce, "CallHash", callHash.Hex())
+		return true, YesVoted
+	case errors.Is(err, ErrAlreadyApproved):
+		w.log.Info("Relayer already approved, wait others", "depositNonce", nonce, "CallHash", callHash.Hex())
+		return true, YesVoted
+	case errors.Is(err, TerminatedError):
+		return false, NotExecuted
+	case errors.Is(err, ErrTimepoint):
+		// The multisig was opened or changed meanwhile, vote again once the listener caught up
+		w.log.Warn("MultiSign timepoint mismatch, retry with the listener's multisig", "depositNonce", nonce, "err", err)
+		time.Sleep(RoundInterval)
+	case errors.Is(err, ErrInsufficientBalance):
+		w.log.Error("Relayer can not pay for the MultiSign extrinsic, fund the relayer account", "depositNonce", nonce, "err", err)
+		time.Sleep(RoundInterval * time.Duration(w.relayer.totalRelayers))
+	default:
+		w.log.Warn("MultiSign extrinsic failed, retry", "depositNonce", nonce, "err", err)
+		time.Sleep(RoundInterval)
 	}
+	return false, NotExecuted
 }
 
-func (w *writer) getRound() Round {
+// currentRound returns the round of the latest finalized block
+func (w *writer) currentRound() (Round, error) {
 	finalizedHash, err := w.listener.client.Api.RPC.Chain.GetFinalizedHead()
 	if err != nil {
-		w.listener.log.Error("Writer Failed to fetch finalized hash", "err", err)
+		return Round{}, err
 	}
-
-	// Get finalized block header
 	finalizedHeader, err := w.listener.client.Api.RPC.Chain.GetHeader(finalizedHash)
 	if err != nil {
-		w.listener.log.Error("Failed to fetch finalized header", "err", err)
+		return Round{}, err
 	}
 
 	blockHeight := big.NewInt(int64(finalizedHeader.Number))
-	blockRound := big.NewInt(0)
-	blockRound.Mod(blockHeight, big.NewInt(int64(w.relayer.totalRelayers))).Uint64()
-
-	round := Round{
-		blockHeight: blockHeight,
-		blockRound:  blockRound,
-	}
-
-	return round
+	blockRound := big.NewInt(0).Mod(blockHeight, big.NewInt(int64(w.relayer.at(blockHeight.Uint64()).totalRelayers)))
+	return Round{blockHeight: blockHeight, blockRound: blockRound}, nil
 }
 
-func (w *writer) isFinish(ms MultiSigAsMulti) (bool, MultiSignTx) {
+func (w *writer) isFinish(r Relayer, ms MultiSigAsMulti) (bool, MultiSignTx) {
 	/// Check isExecuted
+	if ms.Failed {
+		return true, ExecutionFailed
+	}
 	if ms.Executed {
 		return true, ms.OriginMsTx
 	}
 
 	/// Check isVoted
 	/// if already voted, avoid sending duplicated Tx until being executed
+	relayer := types.NewAccountID(r.kr.PublicKey)
+	for _, voter := range ms.YesVote {
+		if voter == relayer {
+			w.log.Info("relayer has vote, wait others!", "Relayer", r.currentRelayer, "Block", ms.OriginMsTx.BlockNumber, "Index", ms.OriginMsTx.MultiSignTxId)
+			return true, YesVoted
+		}
+	}
 	for _, others := range ms.Others {
 		var isVote = true
 		for _, signatory := range others {
 			voter, _ := types.NewAddressFromHexAccountID(signatory)
-			relayer := types.NewAddressFromAccountID(w.relayer.kr.PublicKey)
+			relayer := types.NewAddressFromAccountID(r.kr.PublicKey)
 			if voter == relayer {
 				isVote = false
 			}
 		}
 
 		if isVote {
-			w.log.Info("relayer has vote, wait others!", "Relayer", w.relayer.currentRelayer, "Block", ms.OriginMsTx.BlockNumber, "Index", ms.OriginMsTx.MultiSignTxId)
+			w.log.Info("relayer has vote, wait others!", "Relayer", r.currentRelayer, "Block", ms.OriginMsTx.BlockNumber, "Index", ms.OriginMsTx.MultiSignTxId)
 			return true, YesVoted
 		}
 	}
@@ -386,28 +683,6 @@ func (w *writer) isFinish(ms MultiSigAsMulti) (bool, MultiSignTx) {
 	return false, NotExecuted
 }
 
-func (w *writer) watchSubmission(sub *author.ExtrinsicStatusSubscription) error {
-	for {
-		select {
-		case status := <-sub.Chan():
-			switch {
-			case status.IsInBlock:
-				w.log.Info("Extrinsic included in block", status.AsInBlock.Hex())
-				return nil
-			case status.IsRetracted:
-				fmt.Printf("extrinsic retracted: %s", status.AsRetracted.Hex())
-			case status.IsDropped:
-				fmt.Printf("extrinsic dropped from network\n")
-			case status.IsInvalid:
-				fmt.Printf("extrinsic invalid\n")
-			}
-		case err := <-sub.Err():
-			w.log.Trace("Extrinsic subscription error\n", "err", err)
-			return err
-		}
-	}
-}
-
 func (w *writer) UpdateMetadate() {
 	meta, _ := w.msApi.RPC.State.GetMetadataLatest()
 	if meta != nil {
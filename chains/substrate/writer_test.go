@@ -0,0 +1,90 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ChainSafe/log15"
+	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
+	"github.com/rjman-self/Platdot/chains"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+func TestRejectedRedemption(t *testing.T) {
+	resources := NewResourceRegistry()
+	err := resources.Add(Resource{ResourceId: msg.ResourceId{1}, Asset: NativeAsset, Dest: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dests := NewDestRegistry()
+	err = dests.Add(1, "atp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir(os.TempDir(), "multisig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewMultisigStore(dir, 1, "relayer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &writer{
+		listener: &listener{
+			resources: resources,
+			dests:     dests,
+			msStore:   store,
+			log:       log15.New(),
+		},
+		log:       log15.New(),
+		messages:  make(map[types.Hash]Dest),
+		refunds:   make(map[types.Hash]Refund),
+		retracted: make(map[types.Hash]bool),
+		skips:     chains.NewSkipList(),
+	}
+
+	// A burn of an unknown resource and a burn of a chain that is not a dest, with the same nonce
+	unknown := msg.NewFungibleTransfer(1, 2, 7, big.NewInt(1000), msg.ResourceId{2}, []byte("0xabcd"))
+	invalid := msg.NewFungibleTransfer(3, 2, 7, big.NewInt(1000), msg.ResourceId{1}, []byte("0xabcd"))
+	for _, m := range []msg.Message{unknown, invalid} {
+		if w.ResolveMessage(m) {
+			t.Fatalf("Message of chain %d is resolved", m.Source)
+		}
+	}
+
+	rejected, err := store.LoadRejected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejected) != 2 {
+		t.Fatalf("Got: %d Expected: %d", len(rejected), 2)
+	}
+	for i, m := range []msg.Message{unknown, invalid} {
+		r := rejected[i]
+		if r.Source != m.Source || r.DepositNonce != m.DepositNonce || r.Amount != "1000" || r.DestAddress != "0xabcd" {
+			t.Fatalf("Got: %+v Expected: the burn of chain %d", r, m.Source)
+		}
+		if r.Reason == "" {
+			t.Fatalf("Rejection of chain %d has no reason", m.Source)
+		}
+	}
+	if len(w.messages) != 0 {
+		t.Fatalf("Got: %d Expected: %d", len(w.messages), 0)
+	}
+
+	// A requeued burn that is redeemed clears its record
+	w.clearRejected(unknown)
+	rejected, err = store.LoadRejected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejected) != 1 || rejected[0].Source != invalid.Source {
+		t.Fatalf("Got: %+v Expected: the burn of chain %d", rejected, invalid.Source)
+	}
+}
@@ -80,6 +80,8 @@ func newTestContext(description string, flags []string, values []interface{}) (*
 			set.String(flags[i], v, "")
 		case uint:
 			set.Uint(flags[i], v, "")
+		case uint64:
+			set.Uint64(flags[i], v, "")
 		default:
 			return nil, fmt.Errorf("unexpected cli value type: %T", values[i])
 		}
@@ -111,6 +113,11 @@ func newTestContext(description string, flags []string, values []interface{}) (*
 			if err != nil {
 				return nil, fmt.Errorf("failed to set cli flag: %T", flags[i])
 			}
+		case uint64:
+			err := ctx.Set(flags[i], strconv.FormatUint(v, 10))
+			if err != nil {
+				return nil, fmt.Errorf("failed to set cli flag: %T", flags[i])
+			}
 		default:
 			return nil, fmt.Errorf("unexpected cli value type: %T", values[i])
 		}
@@ -0,0 +1,159 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	log "github.com/ChainSafe/log15"
+	"github.com/rjman-self/Platdot/chains"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+// adminServer serves the admin API of the relayer:
+//
+//	GET  /admin/status                                   state of every chain
+//	POST /admin/chains/{id}/pause?pause=true|false       pause or resume the listener of the chain
+//	POST /admin/chains/{id}/requeue?dest={id}&nonce={n}  route the deposit made on the chain again
+//	POST /admin/chains/{id}/skip?source={id}&nonce={n}   drop the message in the writer of the chain
+//
+// Every request must carry the admin token as "Authorization: Bearer <token>".
+type adminServer struct {
+	token  string
+	chains map[msg.ChainId]adminChain
+}
+
+type adminChain struct {
+	name  string
+	admin chains.Admin
+}
+
+func newAdminServer(token string) *adminServer {
+	return &adminServer{
+		token:  token,
+		chains: make(map[msg.ChainId]adminChain),
+	}
+}
+
+func (s *adminServer) addChain(id msg.ChainId, name string, admin chains.Admin) {
+	s.chains[id] = adminChain{name: name, admin: admin}
+}
+
+// register adds the admin handlers to the mux
+func (s *adminServer) register(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/status", s.authenticate(http.MethodGet, s.handleStatus))
+	mux.HandleFunc("/admin/chains/", s.authenticate(http.MethodPost, s.handleAction))
+}
+
+func (s *adminServer) authenticate(method string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid admin token"))
+			return
+		}
+		if r.Method != method {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *adminServer) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	res := make(map[string]interface{})
+	for id, c := range s.chains {
+		res[fmt.Sprintf("%d-%s", id, c.name)] = c.admin.AdminStatus()
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *adminServer) handleAction(w http.ResponseWriter, r *http.Request) {
+	// /admin/chains/{id}/{action}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/admin/chains/"), "/")
+	if len(parts) != 2 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown path %s", r.URL.Path))
+		return
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 8)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid chain id %s", parts[0]))
+		return
+	}
+	c, ok := s.chains[msg.ChainId(id)]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown chain %d", id))
+		return
+	}
+
+	query := r.URL.Query()
+	switch parts[1] {
+	case "pause":
+		pause := true
+		if v := query.Get("pause"); v != "" {
+			pause, err = strconv.ParseBool(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid pause %s", v))
+				return
+			}
+		}
+		log.Warn("Admin changed listener pause", "chain", c.name, "pause", pause)
+		c.admin.PauseListener(pause)
+	case "requeue":
+		dest, nonce, err := chainAndNonce(query.Get("dest"), query.Get("nonce"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		log.Warn("Admin requeued a deposit", "chain", c.name, "dest", dest, "nonce", nonce)
+		err = c.admin.Requeue(dest, nonce)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	case "skip":
+		source, nonce, err := chainAndNonce(query.Get("source"), query.Get("nonce"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		log.Warn("Admin skipped a message", "chain", c.name, "source", source, "nonce", nonce)
+		c.admin.Skip(source, nonce)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown action %s", parts[1]))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+func chainAndNonce(chain, nonce string) (msg.ChainId, msg.Nonce, error) {
+	id, err := strconv.ParseUint(chain, 10, 8)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid chain id %q", chain)
+	}
+	n, err := strconv.ParseUint(nonce, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid nonce %q", nonce)
+	}
+	return msg.ChainId(id), msg.Nonce(n), nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Error("Failed to write admin response", "err", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+type mockAdmin struct {
+	paused  bool
+	skipped []msg.Nonce
+}
+
+func (m *mockAdmin) AdminStatus() interface{}                 { return map[string]bool{"paused": m.paused} }
+func (m *mockAdmin) PauseListener(pause bool)                 { m.paused = pause }
+func (m *mockAdmin) Requeue(_ msg.ChainId, _ msg.Nonce) error { return nil }
+func (m *mockAdmin) Skip(_ msg.ChainId, nonce msg.Nonce)      { m.skipped = append(m.skipped, nonce) }
+
+func TestAdminServer(t *testing.T) {
+	chain := &mockAdmin{}
+	s := newAdminServer("secret")
+	s.addChain(1, "kusama", chain)
+	mux := http.NewServeMux()
+	s.register(mux)
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		code   int
+	}{
+		{"no token", http.MethodGet, "/admin/status", "", http.StatusUnauthorized},
+		{"wrong token", http.MethodGet, "/admin/status", "Bearer guess", http.StatusUnauthorized},
+		{"token without bearer", http.MethodGet, "/admin/status", "secret", http.StatusUnauthorized},
+		{"token of another scheme", http.MethodGet, "/admin/status", "Basic secret", http.StatusUnauthorized},
+		{"status", http.MethodGet, "/admin/status", "Bearer secret", http.StatusOK},
+		{"wrong method", http.MethodGet, "/admin/chains/1/pause", "Bearer secret", http.StatusMethodNotAllowed},
+		{"unknown chain", http.MethodPost, "/admin/chains/9/pause", "Bearer secret", http.StatusNotFound},
+		{"unknown action", http.MethodPost, "/admin/chains/1/reset", "Bearer secret", http.StatusNotFound},
+		{"pause", http.MethodPost, "/admin/chains/1/pause", "Bearer secret", http.StatusOK},
+		{"skip without nonce", http.MethodPost, "/admin/chains/1/skip?source=2", "Bearer secret", http.StatusBadRequest},
+		{"skip", http.MethodPost, "/admin/chains/1/skip?source=2&nonce=7", "Bearer secret", http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tc.code {
+				t.Fatalf("Got: %d Expected: %d (%s)", rec.Code, tc.code, rec.Body.String())
+			}
+		})
+	}
+
+	if !chain.paused {
+		t.Fatal("listener was not paused")
+	}
+	if len(chain.skipped) != 1 || chain.skipped[0] != 7 {
+		t.Fatalf("Got: %v Expected: [7]", chain.skipped)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/status", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	var status map[string]map[string]bool
+	err := json.Unmarshal(rec.Body.Bytes(), &status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status["1-kusama"]["paused"] {
+		t.Fatalf("Got: %v Expected kusama to be paused", status)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+
+	log "github.com/ChainSafe/log15"
+	"github.com/rjman-self/Platdot/chains"
+	"github.com/rjman-self/platdot-utils/core"
+)
+
+// checkResourceHandlers compares the handlers configured for the resources with the handlers the bridges of their
+// destinations map them to, the relayer refuses to run if they disagree. The resources bridged to a chain that is
+// not configured are not checked.
+func checkResourceHandlers(initialized []core.Chain) error {
+	for _, c := range initialized {
+		resources, ok := c.(chains.BridgedResources)
+		if !ok {
+			continue
+		}
+		for _, res := range resources.ResourceHandlers() {
+			bridge := resolverOf(initialized, res)
+			if bridge == nil {
+				log.Warn("Handler of the resource not checked, the destination is not configured", "Chain", c.Name(), "ResourceId", res.ResourceId.Hex(), "Dest", res.Dest)
+				continue
+			}
+			handler, err := bridge.ResourceHandler(res.ResourceId)
+			if err != nil {
+				return fmt.Errorf("failed to get the handler of resource %s on chain %d: %w", res.ResourceId.Hex(), res.Dest, err)
+			}
+			if handler != res.Handler {
+				return fmt.Errorf("handler of resource %s of chain %s is %s, the bridge of chain %d maps it to %s",
+					res.ResourceId.Hex(), c.Name(), res.Handler.Hex(), res.Dest, handler.Hex())
+			}
+			log.Info("Resource handler agrees with the bridge", "Chain", c.Name(), "ResourceId", res.ResourceId.Hex(), "Dest", res.Dest, "Handler", handler.Hex())
+		}
+	}
+	return nil
+}
+
+// resolverOf returns the chain the resource is bridged to, nil if it is not configured or has no bridge
+func resolverOf(initialized []core.Chain, res chains.ResourceHandler) chains.HandlerResolver {
+	for _, c := range initialized {
+		if c.Id() != res.Dest {
+			continue
+		}
+		if bridge, ok := c.(chains.HandlerResolver); ok {
+			return bridge
+		}
+	}
+	return nil
+}
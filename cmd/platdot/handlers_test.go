@@ -0,0 +1,68 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rjman-self/Platdot/chains"
+	"github.com/rjman-self/platdot-utils/core"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+// mockChain is a chain with an id, only the methods the checks call are implemented
+type mockChain struct {
+	core.Chain
+	id msg.ChainId
+}
+
+func (c *mockChain) Id() msg.ChainId { return c.id }
+func (c *mockChain) Name() string    { return "mock" }
+
+type mockResources struct {
+	mockChain
+	handlers []chains.ResourceHandler
+}
+
+func (c *mockResources) ResourceHandlers() []chains.ResourceHandler { return c.handlers }
+
+type mockResolver struct {
+	mockChain
+	handlers map[msg.ResourceId]common.Address
+}
+
+func (c *mockResolver) ResourceHandler(id msg.ResourceId) (common.Address, error) {
+	return c.handlers[id], nil
+}
+
+func TestCheckResourceHandlers(t *testing.T) {
+	rId := msg.ResourceId{1}
+	handler := common.Address{1}
+	resources := &mockResources{
+		mockChain: mockChain{id: 1},
+		handlers:  []chains.ResourceHandler{{ResourceId: rId, Dest: 2, Handler: handler}},
+	}
+	bridge := &mockResolver{
+		mockChain: mockChain{id: 2},
+		handlers:  map[msg.ResourceId]common.Address{rId: handler},
+	}
+
+	err := checkResourceHandlers([]core.Chain{resources, bridge})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The destination is not configured
+	err = checkResourceHandlers([]core.Chain{resources})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bridge.handlers[rId] = common.Address{2}
+	err = checkResourceHandlers([]core.Chain{resources, bridge})
+	if err == nil {
+		t.Fatal("Expected error for a handler the bridge does not map the resource to")
+	}
+}
@@ -16,6 +16,7 @@ import (
 
 	log "github.com/ChainSafe/log15"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rjman-self/Platdot/chains"
 	"github.com/rjman-self/Platdot/chains/substrate"
 	"github.com/rjman-self/Platdot/config"
 	"github.com/rjman-self/platdot-utils/core"
@@ -110,6 +111,8 @@ func init() {
 	app.EnableBashCompletion = true
 	app.Commands = []*cli.Command{
 		&accountCommand,
+		&statusCommand,
+		&replayCommand,
 	}
 	app.Flags = append(app.Flags, cliFlags...)
 	app.Flags = append(app.Flags, devFlags...)
@@ -150,57 +153,43 @@ func run(ctx *cli.Context) error {
 		return err
 	}
 
-	// Check for test key flag
-	var ks string
-	var insecure bool
-	if key := ctx.String(config.TestKeyFlag.Name); key != "" {
-		ks = key
-		insecure = true
-	} else {
-		ks = cfg.KeystorePath
-	}
-
 	// Used to signal core shutdown due to fatal error
 	sysErr := make(chan error)
 	c := core.NewCore(sysErr)
+	admin := newAdminServer(os.Getenv(config.AdminToken))
 
+	var initialized []core.Chain
 	for _, chain := range cfg.Chains {
-		chainId, err := strconv.Atoi(chain.Id)
+		newChain, err := initializeChain(ctx, cfg, chain, sysErr)
 		if err != nil {
 			return err
 		}
-		chainConfig := &core.ChainConfig{
-			Name:           chain.Name,
-			Id:             msg.ChainId(chainId),
-			Endpoint:       chain.Endpoint,
-			From:           chain.From,
-			KeystorePath:   ks,
-			Insecure:       insecure,
-			BlockstorePath: ctx.String(config.BlockstorePathFlag.Name),
-			FreshStart:     ctx.Bool(config.FreshStartFlag.Name),
-			LatestBlock:    ctx.Bool(config.LatestBlockFlag.Name),
-			Opts:           chain.Opts,
+		c.AddChain(newChain)
+		initialized = append(initialized, newChain)
+		if a, ok := newChain.(chains.Admin); ok {
+			admin.addChain(newChain.Id(), newChain.Name(), a)
 		}
-		var newChain core.Chain
-		var m *metrics.ChainMetrics
-
-		logger := log.Root().New("chain", chainConfig.Name)
+	}
 
-		if ctx.Bool(config.MetricsFlag.Name) {
-			m = metrics.NewChainMetrics(chain.Name)
-		}
+	// Refuse to run if a bridge maps a resource to another handler than the one configured for it
+	err = checkResourceHandlers(initialized)
+	if err != nil {
+		return err
+	}
 
-		if chain.Type == "ethereum" {
-			newChain, err = platdot.InitializeChain(chainConfig, logger, sysErr, m)
-		} else if chain.Type == "substrate" {
-			newChain, err = substrate.InitializeChain(chainConfig, logger, sysErr, m)
-		} else {
-			return errors.New("unrecognized Chain Type")
-		}
+	// Refuse to run if the relayers of the bridges and of the substrate multisigs disagree
+	checks, err := newRelayerChecks(cfg, initialized)
+	if err != nil {
+		return err
+	}
+	for _, check := range checks {
+		err = check.checkAll()
 		if err != nil {
 			return err
 		}
-		c.AddChain(newChain)
+		if check.watchRelayers {
+			check.watch()
+		}
 	}
 
 	// Start prometheus and health server
@@ -219,6 +208,11 @@ func run(ctx *cli.Context) error {
 		go func() {
 			http.Handle("/metrics", promhttp.Handler())
 			http.HandleFunc("/health", h.HealthStatus)
+			if admin.token != "" {
+				admin.register(http.DefaultServeMux)
+			} else {
+				log.Info("Admin API disabled, set " + config.AdminToken + " to enable it")
+			}
 			err := http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
 			if errors.Is(err, http.ErrServerClosed) {
 				log.Info("Health status server is shutting down", err)
@@ -232,3 +226,48 @@ func run(ctx *cli.Context) error {
 
 	return nil
 }
+
+// initializeChain creates the chain described by the raw config, with the keystore and blockstore of the command line
+func initializeChain(ctx *cli.Context, cfg *config.Config, chain config.RawChainConfig, sysErr chan<- error) (core.Chain, error) {
+	chainId, err := strconv.Atoi(chain.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check for test key flag
+	var ks string
+	var insecure bool
+	if key := ctx.String(config.TestKeyFlag.Name); key != "" {
+		ks = key
+		insecure = true
+	} else {
+		ks = cfg.KeystorePath
+	}
+
+	chainConfig := &core.ChainConfig{
+		Name:           chain.Name,
+		Id:             msg.ChainId(chainId),
+		Endpoint:       chain.Endpoint,
+		From:           chain.From,
+		KeystorePath:   ks,
+		Insecure:       insecure,
+		BlockstorePath: ctx.String(config.BlockstorePathFlag.Name),
+		FreshStart:     ctx.Bool(config.FreshStartFlag.Name),
+		LatestBlock:    ctx.Bool(config.LatestBlockFlag.Name),
+		Opts:           chain.Opts,
+	}
+	var m *metrics.ChainMetrics
+
+	logger := log.Root().New("chain", chainConfig.Name)
+
+	if ctx.Bool(config.MetricsFlag.Name) {
+		m = metrics.NewChainMetrics(chain.Name)
+	}
+
+	if chain.Type == "ethereum" {
+		return platdot.InitializeChain(chainConfig, logger, sysErr, m)
+	} else if chain.Type == "substrate" {
+		return substrate.InitializeChain(chainConfig, logger, sysErr, m)
+	}
+	return nil, errors.New("unrecognized Chain Type")
+}
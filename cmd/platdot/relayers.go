@@ -0,0 +1,115 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/ChainSafe/log15"
+	"github.com/rjman-self/Platdot/chains"
+	"github.com/rjman-self/Platdot/config"
+	"github.com/rjman-self/platdot-utils/core"
+)
+
+// Interval of the relayer checks of a bridge that can not be watched
+var RelayerPollInterval = time.Minute
+
+// relayerCheck compares the relayers of the bridges with the signatories of a substrate multisig
+type relayerCheck struct {
+	name     string
+	registry *chains.RelayerRegistry
+	multisig chains.MultisigRelayers
+	bridges  map[string]chains.RelayerRole
+	// Check the relayers again when they change on a bridge, with the watchRelayers option
+	watchRelayers bool
+}
+
+// newRelayerChecks returns the checks of the substrate chains with the relayerRegistry option. The option is
+// the path of the signed registry mapping the EVM relayers to their sr25519 accounts.
+func newRelayerChecks(cfg *config.Config, initialized []core.Chain) ([]*relayerCheck, error) {
+	bridges := make(map[string]chains.RelayerRole)
+	for _, c := range initialized {
+		if bridge, ok := c.(chains.RelayerRole); ok {
+			bridges[c.Name()] = bridge
+		}
+	}
+
+	var checks []*relayerCheck
+	for i, chain := range cfg.Chains {
+		path, ok := chain.Opts["relayerRegistry"]
+		if !ok {
+			continue
+		}
+		multisig, ok := initialized[i].(chains.MultisigRelayers)
+		if !ok {
+			return nil, fmt.Errorf("relayerRegistry is not supported by chain %s", chain.Name)
+		}
+		registry, err := chains.LoadRelayerRegistry(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the relayer registry of chain %s: %w", chain.Name, err)
+		}
+		checks = append(checks, &relayerCheck{
+			name:          chain.Name,
+			registry:      registry,
+			multisig:      multisig,
+			bridges:       bridges,
+			watchRelayers: chain.Opts["watchRelayers"] == "true",
+		})
+	}
+	return checks, nil
+}
+
+// check compares the relayers of the bridge with the relayer sets of the multisig
+func (r *relayerCheck) check(name string, bridge chains.RelayerRole) error {
+	relayers, threshold, err := bridge.RoleRelayers()
+	if err != nil {
+		return fmt.Errorf("failed to get the relayers of chain %s: %w", name, err)
+	}
+	err = r.registry.Check(relayers, threshold, r.multisig.RelayerSets())
+	if err != nil {
+		return fmt.Errorf("relayers of chain %s and chain %s: %w", name, r.name, err)
+	}
+	return nil
+}
+
+// checkAll compares the relayers of every bridge, the relayer refuses to run if they disagree
+func (r *relayerCheck) checkAll() error {
+	for name, bridge := range r.bridges {
+		err := r.check(name, bridge)
+		if err != nil {
+			return err
+		}
+		log.Info("Relayer sets agree", "Chain", name, "Substrate", r.name)
+	}
+	return nil
+}
+
+// watch checks the relayers of the bridges again when they change and raises an alert if they disagree.
+// A bridge whose events can not be subscribed to is checked every RelayerPollInterval.
+func (r *relayerCheck) watch() {
+	for name, bridge := range r.bridges {
+		changed := make(chan struct{})
+		err := bridge.WatchRelayers(changed)
+		if err != nil {
+			log.Warn("Unable to watch the relayers of the bridge, polling instead", "Chain", name, "err", err)
+			go func() {
+				for range time.Tick(RelayerPollInterval) {
+					changed <- struct{}{}
+				}
+			}()
+		}
+
+		go func(name string, bridge chains.RelayerRole) {
+			for range changed {
+				err := r.check(name, bridge)
+				if err != nil {
+					log.Error("ALERT: relayer sets disagree", "Chain", name, "Substrate", r.name, "err", err)
+				} else {
+					log.Debug("Relayer sets agree", "Chain", name, "Substrate", r.name)
+				}
+			}
+		}(name, bridge)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rjman-self/Platdot/chains"
+)
+
+type mockBridge struct {
+	relayers  []common.Address
+	threshold uint8
+	err       error
+}
+
+func (b *mockBridge) RoleRelayers() ([]common.Address, uint8, error) {
+	return b.relayers, b.threshold, b.err
+}
+
+func (b *mockBridge) WatchRelayers(changed chan<- struct{}) error {
+	return errors.New("not supported")
+}
+
+type mockMultisig []chains.RelayerSet
+
+func (m mockMultisig) RelayerSets() []chains.RelayerSet {
+	return m
+}
+
+func TestRelayerCheck(t *testing.T) {
+	registry, err := chains.NewRelayerRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bridge := &mockBridge{}
+	check := &relayerCheck{
+		name:     "substrate",
+		registry: registry,
+		multisig: mockMultisig{{}},
+		bridges:  map[string]chains.RelayerRole{"platon": bridge},
+	}
+
+	err = check.checkAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The relayer is not in the registry
+	bridge.relayers = []common.Address{{0x01}}
+	err = check.checkAll()
+	if !errors.Is(err, chains.ErrRelayersMismatch) {
+		t.Fatalf("Got: %v Expected: %v", err, chains.ErrRelayersMismatch)
+	}
+
+	bridge.err = errors.New("connection refused")
+	err = check.checkAll()
+	if !errors.Is(err, bridge.err) {
+		t.Fatalf("Got: %v Expected: %v", err, bridge.err)
+	}
+}
+
+func TestRelayerCheck_Poll(t *testing.T) {
+	interval := RelayerPollInterval
+	RelayerPollInterval = time.Millisecond
+	defer func() { RelayerPollInterval = interval }()
+
+	registry, err := chains.NewRelayerRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	polled := make(chan struct{}, 1)
+	bridge := &pollBridge{polled: polled}
+	check := &relayerCheck{
+		name:     "substrate",
+		registry: registry,
+		multisig: mockMultisig{{}},
+		bridges:  map[string]chains.RelayerRole{"platon": bridge},
+	}
+
+	// The bridge can not be watched, it is polled instead
+	check.watch()
+	select {
+	case <-polled:
+	case <-time.After(time.Second):
+		t.Fatal("bridge was not polled")
+	}
+}
+
+type pollBridge struct {
+	mockBridge
+	polled chan struct{}
+}
+
+func (b *pollBridge) RoleRelayers() ([]common.Address, uint8, error) {
+	select {
+	case b.polled <- struct{}{}:
+	default:
+	}
+	return b.mockBridge.RoleRelayers()
+}
@@ -0,0 +1,289 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+
+	log "github.com/ChainSafe/log15"
+	"github.com/rjman-self/Platdot/chains"
+	"github.com/rjman-self/Platdot/chains/platdot"
+	"github.com/rjman-self/Platdot/chains/substrate"
+	"github.com/rjman-self/Platdot/config"
+	"github.com/rjman-self/platdot-utils/core"
+	"github.com/rjman-self/platdot-utils/msg"
+	"github.com/urfave/cli/v2"
+)
+
+var replayFlags = []cli.Flag{
+	config.ConfigFileFlag,
+	config.VerbosityFlag,
+	config.KeystorePathFlag,
+	config.BlockstorePathFlag,
+	config.TestKeyFlag,
+	config.ChainFlag,
+	config.BlockFlag,
+	config.IndexFlag,
+	config.NonceFlag,
+	config.DryRunFlag,
+}
+
+var replayCommand = cli.Command{
+	Action: handleReplayCmd,
+	Name:   "replay",
+	Usage:  "route a past deposit to its destination chain again",
+	Flags:  replayFlags,
+	Description: "The replay command processes the deposits made on --chain in --block, as the listener does, and delivers them to the destination writer.\n" +
+		"\tUse --index or --nonce to replay only some of the deposits of the block.\n" +
+		"\tFor substrate chains --index is the extrinsic index and replays all of its deposits, for ethereum chains the position of the deposit event in the block.\n" +
+		"\tWith --dry-run the messages and the hashes of their proposals are printed and nothing is submitted, only the\n" +
+		"\tsource and destination chains are connected to and no keystore is opened.\n" +
+		"\tOtherwise the chains are started as by the relayer until interrupted, so stop the running relayer first.",
+}
+
+func handleReplayCmd(ctx *cli.Context) error {
+	err := startLogger(ctx)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.GetConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !ctx.IsSet(config.ChainFlag.Name) || !ctx.IsSet(config.BlockFlag.Name) {
+		return fmt.Errorf("--%s and --%s are required", config.ChainFlag.Name, config.BlockFlag.Name)
+	}
+	if ctx.IsSet(config.IndexFlag.Name) && ctx.IsSet(config.NonceFlag.Name) {
+		return fmt.Errorf("--%s and --%s can not be used together", config.IndexFlag.Name, config.NonceFlag.Name)
+	}
+	chainId, err := strconv.Atoi(ctx.String(config.ChainFlag.Name))
+	if err != nil {
+		return fmt.Errorf("invalid chain id: %w", err)
+	}
+	block := ctx.Uint64(config.BlockFlag.Name)
+
+	if ctx.Bool(config.DryRunFlag.Name) {
+		return dryRunReplay(ctx, cfg, msg.ChainId(chainId), block)
+	}
+
+	// The chains share a router, the replayed messages are sent through it to the destination writer
+	sysErr := make(chan error)
+	c := core.NewCore(sysErr)
+	router := core.NewRouter(log.Root().New("system", "router"))
+	var source chains.Replayer
+	var sourceType string
+	for _, chain := range cfg.Chains {
+		newChain, err := initializeChain(ctx, cfg, chain, sysErr)
+		if err != nil {
+			return err
+		}
+		newChain.SetRouter(router)
+		c.Registry = append(c.Registry, newChain)
+		if r, ok := newChain.(chains.Replayer); ok && newChain.Id() == msg.ChainId(chainId) {
+			source = r
+			sourceType = chain.Type
+		}
+	}
+	if source == nil {
+		return fmt.Errorf("chain %d is not configured", chainId)
+	}
+
+	// The deposits are processed before the listeners start
+	messages, err := source.Replay(block)
+	if err != nil {
+		return fmt.Errorf("failed to process block %d: %w", block, err)
+	}
+	messages, err = selectDeposits(ctx, sourceType, block, messages)
+	if err != nil {
+		return err
+	}
+
+	// The writers resume their stored messages when they start, the replayed messages are sent once they
+	// are started so a message is not processed twice
+	for _, chain := range c.Registry {
+		err = chain.Start()
+		if err != nil {
+			return fmt.Errorf("failed to start chain %s: %w", chain.Name(), err)
+		}
+		log.Info(fmt.Sprintf("Started %s chain", chain.Name()))
+	}
+	defer func() {
+		for _, chain := range c.Registry {
+			chain.Stop()
+		}
+	}()
+
+	for _, m := range messages {
+		log.Info("Replay deposit", "src", m.Source, "dest", m.Destination, "nonce", m.DepositNonce)
+		err = router.Send(m)
+		if err != nil {
+			return err
+		}
+	}
+	log.Info("Deposits replayed, the relayer runs until interrupted", "count", len(messages))
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+	select {
+	case err := <-sysErr:
+		log.Error("FATAL ERROR. Shutting down.", "err", err)
+	case <-sigc:
+		log.Warn("Interrupt received, shutting down now.")
+	}
+	return nil
+}
+
+// dryRunReplay prints the deposits of the block and the hashes of their proposals. Only the source chain
+// and the destinations of the deposits are connected to, no keystore is opened.
+func dryRunReplay(ctx *cli.Context, cfg *config.Config, chainId msg.ChainId, block uint64) error {
+	replayers := make(map[msg.ChainId]chains.Replayer)
+	replayer := func(id msg.ChainId) (chains.Replayer, config.RawChainConfig, error) {
+		for _, chain := range cfg.Chains {
+			if chain.Id != strconv.Itoa(int(id)) {
+				continue
+			}
+			if r, ok := replayers[id]; ok {
+				return r, chain, nil
+			}
+			r, err := initializeReplayer(ctx, chain)
+			if err != nil {
+				return nil, chain, err
+			}
+			replayers[id] = r
+			return r, chain, nil
+		}
+		return nil, config.RawChainConfig{}, nil
+	}
+
+	source, chain, err := replayer(chainId)
+	if err != nil {
+		return err
+	}
+	if source == nil {
+		return fmt.Errorf("chain %d is not configured", chainId)
+	}
+	messages, err := source.Replay(block)
+	if err != nil {
+		return fmt.Errorf("failed to process block %d: %w", block, err)
+	}
+	messages, err = selectDeposits(ctx, chain.Type, block, messages)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+	for _, m := range messages {
+		printMessage(w, m)
+		dest, _, err := replayer(m.Destination)
+		if err != nil {
+			return err
+		}
+		if dest == nil {
+			fmt.Fprintf(w, "  proposal hash:\tunknown, chain %d is not configured\n", m.Destination)
+			continue
+		}
+		hash, err := dest.ProposalHash(m)
+		if err != nil {
+			return fmt.Errorf("failed to compute the proposal hash of nonce %d: %w", m.DepositNonce, err)
+		}
+		fmt.Fprintf(w, "  proposal hash:\t%#x\n", hash)
+	}
+	return nil
+}
+
+// initializeReplayer connects to the chain without opening its keystore and stores
+func initializeReplayer(ctx *cli.Context, chain config.RawChainConfig) (chains.Replayer, error) {
+	chainId, err := strconv.Atoi(chain.Id)
+	if err != nil {
+		return nil, err
+	}
+	chainConfig := &core.ChainConfig{
+		Name:           chain.Name,
+		Id:             msg.ChainId(chainId),
+		Endpoint:       chain.Endpoint,
+		From:           chain.From,
+		BlockstorePath: ctx.String(config.BlockstorePathFlag.Name),
+		Opts:           chain.Opts,
+	}
+	logger := log.Root().New("chain", chainConfig.Name)
+
+	switch chain.Type {
+	case "ethereum":
+		return platdot.InitializeReplayer(chainConfig, logger)
+	case "substrate":
+		return substrate.InitializeReplayer(chainConfig, logger)
+	default:
+		return nil, errors.New("unrecognized Chain Type")
+	}
+}
+
+// selectDeposits keeps the deposits matching --index or --nonce, all the deposits of the block if neither is set
+func selectDeposits(ctx *cli.Context, sourceType string, block uint64, messages []msg.Message) ([]msg.Message, error) {
+	var nonce msg.Nonce
+	switch {
+	case ctx.IsSet(config.NonceFlag.Name):
+		nonce = msg.Nonce(ctx.Uint64(config.NonceFlag.Name))
+	case ctx.IsSet(config.IndexFlag.Name) && sourceType == "substrate":
+		// An extrinsic may make several deposits, e.g. a batch of two transfers
+		index := ctx.Uint64(config.IndexFlag.Name)
+		var res []msg.Message
+		for _, m := range messages {
+			if _, i, _ := substrate.DecodeDepositNonce(m.DepositNonce); i == index {
+				res = append(res, m)
+			}
+		}
+		if len(res) == 0 {
+			return nil, fmt.Errorf("no deposit found at extrinsic %d-%d", block, index)
+		}
+		return res, nil
+	case ctx.IsSet(config.IndexFlag.Name):
+		index := ctx.Uint64(config.IndexFlag.Name)
+		if index >= uint64(len(messages)) {
+			return nil, fmt.Errorf("block %d has %d deposits, no deposit at index %d", block, len(messages), index)
+		}
+		return messages[index : index+1], nil
+	default:
+		if len(messages) == 0 {
+			return nil, fmt.Errorf("no deposit found in block %d", block)
+		}
+		return messages, nil
+	}
+
+	for _, m := range messages {
+		if m.DepositNonce == nonce {
+			return []msg.Message{m}, nil
+		}
+	}
+	return nil, fmt.Errorf("no deposit with nonce %d found in block %d", nonce, block)
+}
+
+func printMessage(w *tabwriter.Writer, m msg.Message) {
+	fmt.Fprintf(w, "deposit %d from chain %d to chain %d\n", m.DepositNonce, m.Source, m.Destination)
+	fmt.Fprintf(w, "  type:\t%s\n", m.Type)
+	fmt.Fprintf(w, "  resource id:\t%s\n", m.ResourceId.Hex())
+	for i, p := range m.Payload {
+		b, ok := p.([]byte)
+		switch {
+		case !ok:
+			fmt.Fprintf(w, "  payload %d:\t%v\n", i, p)
+		case m.Type == msg.FungibleTransfer && i == 0:
+			fmt.Fprintf(w, "  amount:\t%s\n", new(big.Int).SetBytes(b))
+		case m.Type == msg.FungibleTransfer && i == 1:
+			fmt.Fprintf(w, "  recipient:\t%#x (%q)\n", b, b)
+		default:
+			fmt.Fprintf(w, "  payload %d:\t%#x\n", i, b)
+		}
+	}
+}
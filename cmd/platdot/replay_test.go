@@ -0,0 +1,72 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"testing"
+
+	"github.com/rjman-self/Platdot/chains/substrate"
+	"github.com/rjman-self/Platdot/config"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+func TestSelectDeposits(t *testing.T) {
+	const block = 100
+	nonce := func(index, position uint64) msg.Nonce {
+		n, err := substrate.DepositNonce(block, index, position)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	substrateDeposits := []msg.Message{{DepositNonce: nonce(2, 0)}, {DepositNonce: nonce(5, 0)}, {DepositNonce: nonce(5, 1)}}
+	ethereumDeposits := []msg.Message{{DepositNonce: 7}, {DepositNonce: 8}}
+
+	testCases := []struct {
+		name       string
+		sourceType string
+		messages   []msg.Message
+		flags      []string
+		values     []interface{}
+		expected   msg.Nonce
+		count      int
+		err        bool
+	}{
+		{"all", "substrate", substrateDeposits, nil, nil, nonce(2, 0), 3, false},
+		{"none", "substrate", nil, nil, nil, 0, 0, true},
+		{"substrate index", "substrate", substrateDeposits, []string{config.IndexFlag.Name}, []interface{}{uint64(5)}, nonce(5, 0), 2, false},
+		{"substrate missing index", "substrate", substrateDeposits, []string{config.IndexFlag.Name}, []interface{}{uint64(3)}, 0, 0, true},
+		{"substrate nonce", "substrate", substrateDeposits, []string{config.NonceFlag.Name}, []interface{}{uint64(nonce(5, 0))}, nonce(5, 0), 1, false},
+		{"substrate second deposit", "substrate", substrateDeposits, []string{config.NonceFlag.Name}, []interface{}{uint64(nonce(5, 1))}, nonce(5, 1), 1, false},
+		{"ethereum index", "ethereum", ethereumDeposits, []string{config.IndexFlag.Name}, []interface{}{uint64(1)}, 8, 1, false},
+		{"ethereum index out of range", "ethereum", ethereumDeposits, []string{config.IndexFlag.Name}, []interface{}{uint64(2)}, 0, 0, true},
+		{"ethereum nonce", "ethereum", ethereumDeposits, []string{config.NonceFlag.Name}, []interface{}{uint64(7)}, 7, 1, false},
+		{"ethereum missing nonce", "ethereum", ethereumDeposits, []string{config.NonceFlag.Name}, []interface{}{uint64(9)}, 0, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, err := newTestContext(tc.name, tc.flags, tc.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := selectDeposits(ctx, tc.sourceType, block, tc.messages)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("Expected an error, got %d deposits", len(res))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res) != tc.count {
+				t.Fatalf("Got: %d Expected: %d", len(res), tc.count)
+			}
+			if res[0].DepositNonce != tc.expected {
+				t.Fatalf("Got: %d Expected: %d", res[0].DepositNonce, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	gsrpc "github.com/centrifuge/go-substrate-rpc-client/v2"
+	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/rjman-self/Platdot/chains/platdot"
+	"github.com/rjman-self/Platdot/chains/substrate"
+	"github.com/rjman-self/Platdot/config"
+	utils "github.com/rjman-self/Platdot/shared/substrate"
+	"github.com/rjman-self/platdot-utils/blockstore"
+	"github.com/rjman-self/platdot-utils/msg"
+	"github.com/urfave/cli/v2"
+)
+
+// Number of blocks searched for proposals when --fromBlock is not set
+const defaultStatusRange = 1000
+
+var statusFlags = []cli.Flag{
+	config.ConfigFileFlag,
+	config.VerbosityFlag,
+	config.BlockstorePathFlag,
+	config.FromBlockFlag,
+	config.ToBlockFlag,
+}
+
+var statusCommand = cli.Command{
+	Action: handleStatusCmd,
+	Name:   "status",
+	Usage:  "show the state of the bridge",
+	Flags:  statusFlags,
+	Description: "The status command connects to each configured chain and prints what is pending.\n" +
+		"\tFor every chain the latest block and the block processed by the relayer (from the blockstore) are printed.\n" +
+		"\tFor ethereum chains the bridge proposals found between --fromBlock and --toBlock are listed with their votes.\n" +
+		"\tFor substrate chains the open multisigs of the MultiSignAddress are listed with their approvals.",
+}
+
+func handleStatusCmd(ctx *cli.Context) error {
+	err := startLogger(ctx)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.GetConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	for _, chain := range cfg.Chains {
+		chainId, err := strconv.Atoi(chain.Id)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s (%s, id %d)\n", chain.Name, chain.Type, chainId)
+
+		switch chain.Type {
+		case "ethereum":
+			err = ethereumStatus(ctx, w, chain, msg.ChainId(chainId))
+		case "substrate":
+			err = substrateStatus(ctx, w, chain, msg.ChainId(chainId))
+		default:
+			err = fmt.Errorf("unrecognized Chain Type")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get status of chain %s: %w", chain.Name, err)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+func ethereumStatus(ctx *cli.Context, w *tabwriter.Writer, chain config.RawChainConfig, id msg.ChainId) error {
+	client, err := ethclient.Dial(chain.Endpoint)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	header, err := client.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	latest := header.Number.Uint64()
+
+	relayer := parseAlayaAddress(chain.From)
+	processed, err := processedBlock(ctx, id, relayer.String())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "  latest block:\t%d\n", latest)
+	fmt.Fprintf(w, "  processed block:\t%s\n", processed)
+
+	from, to := ctx.Uint64(config.FromBlockFlag.Name), ctx.Uint64(config.ToBlockFlag.Name)
+	if to == 0 || to > latest {
+		to = latest
+	}
+	if !ctx.IsSet(config.FromBlockFlag.Name) && to > defaultStatusRange {
+		from = to - defaultStatusRange
+	}
+
+	proposals, err := platdot.QueryProposals(client, parseAlayaAddress(chain.Opts[platdot.BridgeOpt]), relayer, from, to)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "  proposals in blocks %d-%d:\t%d\n", from, to, len(proposals))
+	if len(proposals) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "  SOURCE\tNONCE\tSTATUS\tYES VOTES\tVOTED\tBLOCK\tDATA HASH")
+	for _, p := range proposals {
+		fmt.Fprintf(w, "  %d\t%d\t%s\t%d\t%t\t%d\t%#x\n", p.Source, p.DepositNonce, platdot.ProposalStatusName(p.Status), p.YesVotes, p.HasVoted, p.Block, p.DataHash)
+	}
+	return nil
+}
+
+func substrateStatus(ctx *cli.Context, w *tabwriter.Writer, chain config.RawChainConfig, id msg.ChainId) error {
+	api, err := gsrpc.NewSubstrateAPI(chain.Endpoint)
+	if err != nil {
+		return err
+	}
+	meta, err := api.RPC.State.GetMetadataLatest()
+	if err != nil {
+		return err
+	}
+
+	finalized, err := substrate.FinalizedBlock(api)
+	if err != nil {
+		return err
+	}
+	processed, err := processedBlock(ctx, id, chain.From)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "  finalized block:\t%d\n", finalized)
+	fmt.Fprintf(w, "  processed block:\t%s\n", processed)
+
+	address, ok := chain.Opts["MultiSignAddress"]
+	if !ok {
+		fmt.Fprintln(w, "  no MultiSignAddress configured")
+		return nil
+	}
+	prefix, err := ss58Prefix(chain)
+	if err != nil {
+		return err
+	}
+	pub, err := utils.ParseAccountID(address, prefix)
+	if err != nil {
+		return fmt.Errorf("invalid MultiSignAddress: %w", err)
+	}
+
+	multisigs, err := substrate.QueryMultisigs(api, meta, types.NewAccountID(pub))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "  open multisigs on %s:\t%d\n", utils.FormatAddress(pub, prefix), len(multisigs))
+	if len(multisigs) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "  CALL HASH\tOPENED AT\tDEPOSIT\tDEPOSITOR\tAPPROVALS")
+	for _, ms := range multisigs {
+		approvals := make([]string, len(ms.Approvals))
+		for i, a := range ms.Approvals {
+			approvals[i] = utils.FormatAddress(a[:], prefix)
+		}
+		fmt.Fprintf(w, "  %s\t%d-%d\t%s\t%s\t%d [%s]\n", ms.CallHash.Hex(), ms.When.Height, ms.When.Index, ms.Deposit.String(),
+			utils.FormatAddress(ms.Depositor[:], prefix), len(ms.Approvals), strings.Join(approvals, ", "))
+	}
+	return nil
+}
+
+// ss58Prefix reads the ss58Prefix option of a substrate chain, Polkadot by default
+func ss58Prefix(chain config.RawChainConfig) (uint16, error) {
+	prefix, ok := chain.Opts["ss58Prefix"]
+	if !ok {
+		return utils.PolkadotSS58Prefix, nil
+	}
+	res, err := strconv.ParseUint(prefix, 10, 16)
+	if err != nil || uint16(res) > utils.MaxSS58Prefix {
+		return 0, fmt.Errorf("invalid ss58Prefix %s", prefix)
+	}
+	return uint16(res), nil
+}
+
+// processedBlock loads the latest block the relayer stored in its blockstore
+func processedBlock(ctx *cli.Context, id msg.ChainId, relayer string) (string, error) {
+	bs, err := blockstore.NewBlockstore(ctx.String(config.BlockstorePathFlag.Name), id, relayer)
+	if err != nil {
+		return "", err
+	}
+	block, err := bs.TryLoadLatestBlock()
+	if err != nil {
+		return "", err
+	}
+	if block.Sign() == 0 {
+		return "none", nil
+	}
+	return block.String(), nil
+}
+
+// parseAlayaAddress accepts a bech32 Alaya address or a hex address
+func parseAlayaAddress(address string) common.Address {
+	if b, err := common.PlatonToEth(address); err == nil {
+		return common.BytesToAddress(b)
+	}
+	return common.HexToAddress(address)
+}
@@ -11,6 +11,8 @@ import (
 // Env vars
 var (
 	HealthBlockTimeout = "BLOCK_TIMEOUT"
+	// AdminToken is the bearer token of the admin API, the API is disabled when it is not set
+	AdminToken = "ADMIN_TOKEN"
 )
 
 var (
@@ -94,6 +96,42 @@ var (
 	}
 )
 
+// Status subcommand flags
+var (
+	FromBlockFlag = &cli.Uint64Flag{
+		Name:  "fromBlock",
+		Usage: "First block searched for bridge proposals, defaults to 1000 blocks before --toBlock",
+	}
+	ToBlockFlag = &cli.Uint64Flag{
+		Name:  "toBlock",
+		Usage: "Last block searched for bridge proposals, defaults to the latest block",
+	}
+)
+
+// Replay subcommand flags
+var (
+	ChainFlag = &cli.StringFlag{
+		Name:  "chain",
+		Usage: "Id of the chain the deposit was made on",
+	}
+	BlockFlag = &cli.Uint64Flag{
+		Name:  "block",
+		Usage: "Block the deposit was made in",
+	}
+	IndexFlag = &cli.Uint64Flag{
+		Name:  "index",
+		Usage: "Extrinsic index of a substrate deposit, or position of an ethereum deposit event in the block",
+	}
+	NonceFlag = &cli.Uint64Flag{
+		Name:  "nonce",
+		Usage: "Deposit nonce of the deposit",
+	}
+	DryRunFlag = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Print the messages and proposal hashes without submitting them",
+	}
+)
+
 // Test Setting Flags
 var (
 	TestKeyFlag = &cli.StringFlag{
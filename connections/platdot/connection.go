@@ -9,12 +9,15 @@ import (
 	"fmt"
 	"github.com/rjman-self/platdot-utils/crypto/secp256k1"
 	"github.com/ChainSafe/log15"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	ethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rpc"
 	"math/big"
+	"net"
 	"os"
 	"strconv"
 	"sync"
@@ -23,6 +26,11 @@ import (
 
 var BlockRetryInterval = time.Second * 5
 
+// MaxGasBumps is the number of times Transact replaces a pending transaction before it gives up
+var MaxGasBumps = 10
+
+var ErrTxNotMined = errors.New("transaction not mined")
+
 type Connection struct {
 	endpoint      string
 	http          bool
@@ -30,10 +38,14 @@ type Connection struct {
 	gasLimit      *big.Int
 	maxGasPrice   *big.Int
 	gasMultiplier *big.Float
+	gasPricer     GasPricer
+	bumpBlocks    uint64 // Blocks a transaction may stay pending before it is resubmitted, 0 disables bumping
+	bumpPercent   uint64 // Gas price increase of a resubmitted transaction
 	conn          *ethclient.Client
 	opts          *bind.TransactOpts
 	callOpts      *bind.CallOpts
-	nonce         uint64
+	nonces        *NonceManager
+	nonceMetrics  *NonceMetrics
 	optsLock      sync.Mutex
 	log           log15.Logger
 	stop          chan int // All routines should exit when this channel is closed
@@ -48,6 +60,7 @@ func NewConnection(endpoint string, http bool, kp *secp256k1.Keypair, log log15.
 		gasLimit:      gasLimit,
 		maxGasPrice:   gasPrice,
 		gasMultiplier: gasMultiplier,
+		gasPricer:     SuggestedGasPrice{Multiplier: gasMultiplier},
 		log:           log,
 		stop:          make(chan int),
 	}
@@ -76,7 +89,7 @@ func (c *Connection) Connect() error {
 		return err
 	}
 	c.opts = opts
-	c.nonce = 0
+	c.nonces = NewNonceManager(c.conn, c.kp.CommonAddress(), c.nonceMetrics)
 	c.callOpts = &bind.CallOpts{From: c.kp.CommonAddress()}
 	return nil
 }
@@ -124,16 +137,30 @@ func (c *Connection) CallOpts() *bind.CallOpts {
 	return c.callOpts
 }
 
-func (c *Connection) SafeEstimateGas(ctx context.Context) (*big.Int, error) {
+// SetGasPricer replaces the default strategy, the suggested price times the gas multiplier
+func (c *Connection) SetGasPricer(p GasPricer) {
+	c.gasPricer = p
+}
+
+// SetBumping enables the resubmission of the transactions sent by Transact that are not mined within
+// blocks blocks, with a gas price raised by percent
+func (c *Connection) SetBumping(blocks, percent uint64) {
+	c.bumpBlocks = blocks
+	c.bumpPercent = percent
+}
 
-	suggestedGasPrice, err := c.conn.SuggestGasPrice(context.TODO())
+// SetNonceMetrics sets the metrics of the nonce manager, must be called before Connection.Connect()
+func (c *Connection) SetNonceMetrics(m *NonceMetrics) {
+	c.nonceMetrics = m
+}
 
+// SafeEstimateGas returns the price of the gas price strategy, capped at the max gas price
+func (c *Connection) SafeEstimateGas(ctx context.Context) (*big.Int, error) {
+	gasPrice, err := c.gasPricer.GasPrice(ctx, c.conn)
 	if err != nil {
 		return nil, err
 	}
 
-	gasPrice := multiplyGasPrice(suggestedGasPrice, c.gasMultiplier)
-
 	// Check we aren't exceeding our limit
 	if gasPrice.Cmp(c.maxGasPrice) == 1 {
 		return c.maxGasPrice, nil
@@ -142,31 +169,20 @@ func (c *Connection) SafeEstimateGas(ctx context.Context) (*big.Int, error) {
 	}
 }
 
-func multiplyGasPrice(gasEstimate *big.Int, gasMultiplier *big.Float) *big.Int {
-
-	gasEstimateFloat := new(big.Float).SetInt(gasEstimate)
-
-	result := gasEstimateFloat.Mul(gasEstimateFloat, gasMultiplier)
-
-	gasPrice := new(big.Int)
-
-	result.Int(gasPrice)
-
-	return gasPrice
-}
-
 // LockAndUpdateOpts acquires a lock on the opts before updating the nonce
-// and gas price.
+// and gas price. The nonce is reserved from the nonce manager, Transact
+// should be preferred as it gives the nonce back when the tx is rejected.
 func (c *Connection) LockAndUpdateOpts() error {
 	c.optsLock.Lock()
 
 	gasPrice, err := c.SafeEstimateGas(context.TODO())
 	if err != nil {
+		c.optsLock.Unlock()
 		return err
 	}
 	c.opts.GasPrice = gasPrice
 
-	nonce, err := c.conn.PendingNonceAt(context.Background(), c.opts.From)
+	nonce, err := c.nonces.Reserve(context.Background())
 	if err != nil {
 		c.optsLock.Unlock()
 		return err
@@ -179,6 +195,160 @@ func (c *Connection) UnlockOpts() {
 	c.optsLock.Unlock()
 }
 
+// Transact sends the transaction built by send with a nonce reserved from the nonce manager and the strategy's
+// gas price, node errors are wrapped in ErrNonceTooLow, ErrTxUnderpriced or ErrTxKnown. A transaction the node
+// already knows is sent. When bumping is enabled it waits for the transaction to be mined, a transaction still
+// pending after bumpBlocks blocks is replaced with the same nonce and a higher gas price, up to the max gas price.
+// The transaction of the nonce that was mined is returned. After MaxGasBumps waits without any of them mined, the
+// last transaction sent is returned with ErrTxNotMined.
+func (c *Connection) Transact(send func(opts *bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	gasPrice, err := c.SafeEstimateGas(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := c.nonces.Reserve(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+
+	// Each transaction is sent with its own copy of the opts, concurrent transactions don't wait for each other
+	c.optsLock.Lock()
+	opts := *c.opts
+	c.optsLock.Unlock()
+	opts.Nonce = new(big.Int).SetUint64(nonce)
+	opts.GasPrice = gasPrice
+	tx, err := sendTx(send, &opts)
+	if err != nil {
+		c.releaseNonce(nonce, err)
+		return nil, err
+	}
+	if c.bumpBlocks == 0 {
+		return tx, nil
+	}
+
+	// Every transaction sent for the nonce, any of them may be mined
+	sent := []*types.Transaction{tx}
+	for bumps := 0; ; bumps++ {
+		mined, err := c.waitMined(sent, c.bumpBlocks)
+		if err != nil {
+			return tx, err
+		}
+		if mined != nil {
+			return mined, nil
+		}
+		if bumps >= MaxGasBumps {
+			// The chain may have dropped the transactions, the nonce manager moves back to the chain in time
+			c.resyncNonce()
+			return tx, fmt.Errorf("%w: nonce %d after %d blocks", ErrTxNotMined, nonce, uint64(bumps+1)*c.bumpBlocks)
+		}
+		if opts.GasPrice.Cmp(c.maxGasPrice) >= 0 {
+			c.log.Warn("Transaction pending at the max gas price", "tx", tx.Hash(), "nonce", opts.Nonce, "gasPrice", opts.GasPrice)
+			continue
+		}
+
+		opts.GasPrice = bumpGasPrice(opts.GasPrice, c.bumpPercent, c.maxGasPrice)
+		c.log.Info("Transaction not mined, resubmitting with a higher gas price", "tx", tx.Hash(), "nonce", opts.Nonce, "gasPrice", opts.GasPrice)
+		replacement, err := sendTx(send, &opts)
+		switch {
+		case err == nil:
+			tx = replacement
+			sent = append(sent, tx)
+		case errors.Is(err, ErrNonceTooLow):
+			// One of the transactions of the nonce was mined meanwhile
+			mined, err := c.waitMined(sent, c.bumpBlocks)
+			if err != nil {
+				return tx, err
+			}
+			if mined == nil {
+				return tx, fmt.Errorf("nonce %d is used by another transaction: %w", nonce, ErrNonceTooLow)
+			}
+			return mined, nil
+		default:
+			return tx, err
+		}
+	}
+}
+
+// sendTx sends the transaction built by send, a transaction already known by the node is returned as sent
+func sendTx(send func(opts *bind.TransactOpts) (*types.Transaction, error), opts *bind.TransactOpts) (*types.Transaction, error) {
+	var signed *types.Transaction
+	signer := opts.Signer
+	txOpts := *opts
+	txOpts.Signer = func(address ethcommon.Address, tx *types.Transaction) (*types.Transaction, error) {
+		tx, err := signer(address, tx)
+		signed = tx
+		return tx, err
+	}
+
+	tx, err := send(&txOpts)
+	err = classifyTxError(err)
+	if errors.Is(err, ErrTxKnown) && signed != nil {
+		return signed, nil
+	}
+	return tx, err
+}
+
+// releaseNonce gives back the nonce of a rejected transaction, or reconciles the nonce manager
+// with the chain when the nonce turned out to be taken. The nonce of a transaction whose sending timed
+// out is kept, the node may have accepted it.
+func (c *Connection) releaseNonce(nonce uint64, err error) {
+	switch {
+	case errors.Is(err, ErrNonceTooLow), errors.Is(err, ErrTxUnderpriced), errors.Is(err, ErrTxKnown):
+		c.log.Debug("Nonce already used, resyncing with the chain", "nonce", nonce, "err", err)
+	case isTimeout(err):
+		c.log.Warn("Sending timed out, the nonce is kept", "nonce", nonce, "err", err)
+	default:
+		c.nonces.Release(nonce)
+		return
+	}
+	c.resyncNonce()
+}
+
+func (c *Connection) resyncNonce() {
+	if err := c.nonces.Resync(context.TODO()); err != nil {
+		c.log.Warn("Failed to resync nonce", "err", err)
+	}
+}
+
+// isTimeout is true if the request sending the transaction timed out, the node may have received it
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// waitMined polls the receipts of the transactions until one is found or blocks blocks are produced, the mined
+// transaction is returned, nil if none was mined
+func (c *Connection) waitMined(txs []*types.Transaction, blocks uint64) (*types.Transaction, error) {
+	start, err := c.LatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	target := new(big.Int).Add(start, new(big.Int).SetUint64(blocks))
+	for {
+		select {
+		case <-c.stop:
+			return nil, errors.New("connection terminated")
+		default:
+			for _, tx := range txs {
+				_, err := c.conn.TransactionReceipt(context.Background(), tx.Hash())
+				if err == nil {
+					return tx, nil
+				} else if err != ethereum.NotFound {
+					c.log.Warn("Failed to query transaction receipt", "tx", tx.Hash(), "err", err)
+				}
+			}
+
+			current, err := c.LatestBlock()
+			if err != nil {
+				c.log.Warn("Failed to query latest block", "err", err)
+			} else if current.Cmp(target) >= 0 {
+				return nil, nil
+			}
+			time.Sleep(BlockRetryInterval)
+		}
+	}
+}
+
 // LatestBlock returns the latest block from the current chain
 func (c *Connection) LatestBlock() (*big.Int, error) {
 	header, err := c.conn.HeaderByNumber(context.Background(), nil)
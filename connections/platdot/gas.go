@@ -0,0 +1,147 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package platdot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	ErrNonceTooLow   = errors.New("nonce too low")
+	ErrTxUnderpriced = errors.New("transaction underpriced")
+	ErrTxKnown       = errors.New("transaction already known")
+)
+
+// GasPriceClient is the part of the client used by the gas price strategies
+type GasPriceClient interface {
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+}
+
+// GasPricer is a strategy computing the gas price of the transactions sent by the connection
+type GasPricer interface {
+	GasPrice(ctx context.Context, client GasPriceClient) (*big.Int, error)
+}
+
+// StaticGasPrice always uses the same price
+type StaticGasPrice struct {
+	Price *big.Int
+}
+
+func (s StaticGasPrice) GasPrice(ctx context.Context, client GasPriceClient) (*big.Int, error) {
+	return new(big.Int).Set(s.Price), nil
+}
+
+// SuggestedGasPrice multiplies the price suggested by the node
+type SuggestedGasPrice struct {
+	Multiplier *big.Float
+}
+
+func (s SuggestedGasPrice) GasPrice(ctx context.Context, client GasPriceClient) (*big.Int, error) {
+	suggested, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return multiplyGasPrice(suggested, s.Multiplier), nil
+}
+
+// PercentileGasPrice uses a percentile of the prices paid by the transactions of the latest blocks,
+// the suggested price when these blocks are empty
+type PercentileGasPrice struct {
+	Percentile uint64 // From 1 to 100
+	Blocks     uint64 // Number of blocks sampled
+}
+
+func (p PercentileGasPrice) GasPrice(ctx context.Context, client GasPriceClient) (*big.Int, error) {
+	latest, err := client.BlockByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := gasPrices(latest)
+	number := latest.NumberU64()
+	for i := uint64(1); i < p.Blocks && i <= number; i++ {
+		block, err := client.BlockByNumber(ctx, new(big.Int).SetUint64(number-i))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read block %d: %w", number-i, err)
+		}
+		prices = append(prices, gasPrices(block)...)
+	}
+
+	if len(prices) == 0 {
+		return client.SuggestGasPrice(ctx)
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Cmp(prices[j]) < 0 })
+	return new(big.Int).Set(prices[percentileIndex(len(prices), p.Percentile)]), nil
+}
+
+func gasPrices(block *types.Block) []*big.Int {
+	var res []*big.Int
+	for _, tx := range block.Transactions() {
+		res = append(res, tx.GasPrice())
+	}
+	return res
+}
+
+// percentileIndex returns the index of the percentile in a sorted list of n elements, using the nearest rank
+func percentileIndex(n int, percentile uint64) int {
+	rank := (uint64(n)*percentile + 99) / 100
+	if rank == 0 {
+		return 0
+	}
+	if rank > uint64(n) {
+		return n - 1
+	}
+	return int(rank) - 1
+}
+
+// bumpGasPrice raises the price by percent, by at least 1, without exceeding max
+func bumpGasPrice(price *big.Int, percent uint64, max *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(price, new(big.Int).SetUint64(100+percent))
+	bumped.Div(bumped, big.NewInt(100))
+	if bumped.Cmp(price) <= 0 {
+		bumped.Add(price, big.NewInt(1))
+	}
+	if bumped.Cmp(max) > 0 {
+		return new(big.Int).Set(max)
+	}
+	return bumped
+}
+
+func multiplyGasPrice(gasEstimate *big.Int, gasMultiplier *big.Float) *big.Int {
+
+	gasEstimateFloat := new(big.Float).SetInt(gasEstimate)
+
+	result := gasEstimateFloat.Mul(gasEstimateFloat, gasMultiplier)
+
+	gasPrice := new(big.Int)
+
+	result.Int(gasPrice)
+
+	return gasPrice
+}
+
+// classifyTxError wraps the errors returned by the node for a rejected transaction so they can be matched with errors.Is
+func classifyTxError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "nonce too low"):
+		return fmt.Errorf("%w: %s", ErrNonceTooLow, err)
+	case strings.Contains(msg, "underpriced"):
+		return fmt.Errorf("%w: %s", ErrTxUnderpriced, err)
+	case strings.Contains(msg, "already known"), strings.Contains(msg, "known transaction"):
+		return fmt.Errorf("%w: %s", ErrTxKnown, err)
+	}
+	return err
+}
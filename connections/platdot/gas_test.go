@@ -0,0 +1,201 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package platdot
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// newPricedBackend creates a chain with one block per price, holding a transaction paying that price
+func newPricedBackend(t *testing.T, prices ...int64) *backends.SimulatedBackend {
+	key, err := ethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := ethcrypto.PubkeyToAddress(key.PublicKey)
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{from: {Balance: big.NewInt(1e18)}}, 8000000)
+
+	for nonce, price := range prices {
+		tx := types.NewTransaction(uint64(nonce), from, big.NewInt(1), 21000, big.NewInt(price), nil)
+		signed, err := types.SignTx(tx, types.HomesteadSigner{}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = backend.SendTransaction(context.Background(), signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		backend.Commit()
+	}
+	return backend
+}
+
+func TestStaticGasPrice(t *testing.T) {
+	backend := newPricedBackend(t)
+	price, err := StaticGasPrice{Price: big.NewInt(42)}.GasPrice(context.Background(), backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price.Cmp(big.NewInt(42)) != 0 {
+		t.Fatalf("Got: %s Expected: %d", price, 42)
+	}
+}
+
+func TestSuggestedGasPrice(t *testing.T) {
+	backend := newPricedBackend(t)
+	suggested, err := backend.SuggestGasPrice(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	price, err := SuggestedGasPrice{Multiplier: big.NewFloat(3)}.GasPrice(context.Background(), backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := new(big.Int).Mul(suggested, big.NewInt(3))
+	if price.Cmp(expected) != 0 {
+		t.Fatalf("Got: %s Expected: %s", price, expected)
+	}
+}
+
+func TestPercentileGasPrice(t *testing.T) {
+	backend := newPricedBackend(t, 50, 10, 40, 20, 30)
+
+	testCases := []struct {
+		percentile uint64
+		blocks     uint64
+		expected   int64
+	}{
+		{50, 5, 30},
+		{100, 5, 50},
+		{1, 5, 10},
+		{20, 5, 10},
+		{21, 5, 20},
+		// Only the blocks of the last three prices are sampled
+		{100, 3, 40},
+		{1, 3, 20},
+		// More blocks than the chain has
+		{100, 100, 50},
+	}
+
+	for _, tc := range testCases {
+		price, err := PercentileGasPrice{Percentile: tc.percentile, Blocks: tc.blocks}.GasPrice(context.Background(), backend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if price.Cmp(big.NewInt(tc.expected)) != 0 {
+			t.Fatalf("Percentile %d of %d blocks. Got: %s Expected: %d", tc.percentile, tc.blocks, price, tc.expected)
+		}
+	}
+}
+
+func TestPercentileGasPrice_EmptyBlocks(t *testing.T) {
+	backend := newPricedBackend(t)
+	backend.Commit()
+	suggested, err := backend.SuggestGasPrice(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	price, err := PercentileGasPrice{Percentile: 50, Blocks: 10}.GasPrice(context.Background(), backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price.Cmp(suggested) != 0 {
+		t.Fatalf("Got: %s Expected: %s", price, suggested)
+	}
+}
+
+func TestBumpGasPrice(t *testing.T) {
+	testCases := []struct {
+		price    int64
+		percent  uint64
+		max      int64
+		expected int64
+	}{
+		{100, 10, 1000, 110},
+		{100, 25, 1000, 125},
+		{100, 10, 105, 105},
+		// Small prices are raised by at least 1
+		{5, 10, 1000, 6},
+	}
+
+	for _, tc := range testCases {
+		res := bumpGasPrice(big.NewInt(tc.price), tc.percent, big.NewInt(tc.max))
+		if res.Cmp(big.NewInt(tc.expected)) != 0 {
+			t.Fatalf("Got: %s Expected: %d", res, tc.expected)
+		}
+	}
+}
+
+func TestClassifyTxError(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected error
+	}{
+		{errors.New("nonce too low"), ErrNonceTooLow},
+		{errors.New("replacement transaction underpriced"), ErrTxUnderpriced},
+		{errors.New("transaction underpriced"), ErrTxUnderpriced},
+		{errors.New("already known"), ErrTxKnown},
+		{errors.New("known transaction: 0x1234"), ErrTxKnown},
+	}
+
+	for _, tc := range testCases {
+		if err := classifyTxError(tc.err); !errors.Is(err, tc.expected) {
+			t.Fatalf("Got: %v Expected: %v", err, tc.expected)
+		}
+	}
+
+	other := errors.New("execution reverted")
+	if err := classifyTxError(other); err != other {
+		t.Fatalf("Got: %v Expected: %v", err, other)
+	}
+}
+
+func TestSendTx_Known(t *testing.T) {
+	key, err := ethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Nonce = big.NewInt(1)
+	opts.GasPrice = big.NewInt(10)
+
+	// The node rejects the replacement it already has, it was sent before
+	send := func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		_, err := opts.Signer(opts.From, types.NewTransaction(opts.Nonce.Uint64(), ethcommon.Address{}, nil, 21000, opts.GasPrice, nil))
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.New("already known")
+	}
+	tx, err := sendTx(send, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx == nil || tx.Nonce() != 1 || tx.GasPrice().Cmp(opts.GasPrice) != 0 {
+		t.Fatalf("Got: %v Expected the signed transaction", tx)
+	}
+
+	send = func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return nil, errors.New("nonce too low")
+	}
+	_, err = sendTx(send, opts)
+	if !errors.Is(err, ErrNonceTooLow) {
+		t.Fatalf("Got: %v Expected: %v", err, ErrNonceTooLow)
+	}
+}
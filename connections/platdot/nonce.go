@@ -0,0 +1,185 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package platdot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NonceResyncTimeout is how long the pending nonce of the chain may stay below the local nonce before the local
+// nonce is moved back to it, the transactions of the nonces in between are considered dropped
+var NonceResyncTimeout = 5 * time.Minute
+
+// NonceReader returns the next nonce of an account, including its transactions in the mempool
+type NonceReader interface {
+	PendingNonceAt(ctx context.Context, account ethcommon.Address) (uint64, error)
+}
+
+// NonceMetrics are the metrics of a NonceManager
+type NonceMetrics struct {
+	Reserved  prometheus.Counter
+	Released  prometheus.Counter
+	Resyncs   prometheus.Counter
+	NextNonce prometheus.Gauge
+}
+
+func NewNonceMetrics(chain string) *NonceMetrics {
+	metrics := &NonceMetrics{
+		Reserved: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_nonces_reserved", chain),
+			Help: "Number of nonces reserved for transactions",
+		}),
+		Released: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_nonces_released", chain),
+			Help: "Number of reserved nonces released by transactions that were not sent",
+		}),
+		Resyncs: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_nonce_resyncs", chain),
+			Help: "Number of times the local nonce was reconciled with the chain",
+		}),
+		NextNonce: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_next_nonce", chain),
+			Help: "Next nonce reserved by the relayer",
+		}),
+	}
+
+	prometheus.MustRegister(metrics.Reserved)
+	prometheus.MustRegister(metrics.Released)
+	prometheus.MustRegister(metrics.Resyncs)
+	prometheus.MustRegister(metrics.NextNonce)
+
+	return metrics
+}
+
+// NonceManager hands out the nonces of an account locally, so concurrent transactions get distinct nonces
+// without waiting for the node to see the previous ones. The chain is only read at the first reservation
+// and when a transaction reveals that the local nonce is behind.
+type NonceManager struct {
+	client   NonceReader
+	account  ethcommon.Address
+	lock     sync.Mutex
+	synced   bool
+	next     uint64
+	released []uint64  // Nonces below next whose transactions were not sent, sorted
+	behind   time.Time // When the pending nonce of the chain was first seen below next, zero if it is not
+	metrics  *NonceMetrics
+}
+
+// NewNonceManager creates a NonceManager for the account, m may be nil
+func NewNonceManager(client NonceReader, account ethcommon.Address, m *NonceMetrics) *NonceManager {
+	return &NonceManager{
+		client:  client,
+		account: account,
+		metrics: m,
+	}
+}
+
+// Reserve returns a nonce no other reservation holds, the lowest released nonce first so no gap is left
+func (n *NonceManager) Reserve(ctx context.Context) (uint64, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if !n.synced {
+		err := n.sync(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var nonce uint64
+	if len(n.released) != 0 {
+		nonce = n.released[0]
+		n.released = n.released[1:]
+	} else {
+		nonce = n.next
+		n.next++
+	}
+
+	if n.metrics != nil {
+		n.metrics.Reserved.Inc()
+		n.metrics.NextNonce.Set(float64(n.next))
+	}
+	return nonce, nil
+}
+
+// Release gives back a reserved nonce whose transaction was not accepted by the node
+func (n *NonceManager) Release(nonce uint64) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if nonce >= n.next {
+		return
+	}
+	if nonce == n.next-1 {
+		n.next--
+	} else {
+		i := sort.Search(len(n.released), func(i int) bool { return n.released[i] >= nonce })
+		if i < len(n.released) && n.released[i] == nonce {
+			return
+		}
+		n.released = append(n.released, 0)
+		copy(n.released[i+1:], n.released[i:])
+		n.released[i] = nonce
+	}
+	// A released nonce at the top may uncover others
+	for len(n.released) != 0 && n.released[len(n.released)-1] == n.next-1 {
+		n.released = n.released[:len(n.released)-1]
+		n.next--
+	}
+
+	if n.metrics != nil {
+		n.metrics.Released.Inc()
+		n.metrics.NextNonce.Set(float64(n.next))
+	}
+}
+
+// Resync reconciles the local nonce with the chain after a transaction was rejected because its nonce
+// is taken or could not be confirmed. The local nonce moves forward to the pending nonce of the chain.
+// It is kept above the pending nonce of a lagging node, the transactions sent with the nonces in between
+// are still valid, until the chain stays behind for NonceResyncTimeout: the transactions were dropped and
+// the local nonce moves back so the gap is filled.
+func (n *NonceManager) Resync(ctx context.Context) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.metrics != nil {
+		n.metrics.Resyncs.Inc()
+	}
+	return n.sync(ctx)
+}
+
+func (n *NonceManager) sync(ctx context.Context) error {
+	pending, err := n.client.PendingNonceAt(ctx, n.account)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !n.synced || pending >= n.next:
+		n.next = pending
+		n.behind = time.Time{}
+	case n.behind.IsZero():
+		n.behind = time.Now()
+	case time.Since(n.behind) >= NonceResyncTimeout:
+		n.next = pending
+		n.behind = time.Time{}
+		n.released = nil
+	}
+	n.synced = true
+
+	// Released nonces used by the chain meanwhile are dropped
+	i := sort.Search(len(n.released), func(i int) bool { return n.released[i] >= pending })
+	n.released = n.released[i:]
+
+	if n.metrics != nil {
+		n.metrics.NextNonce.Set(float64(n.next))
+	}
+	return nil
+}
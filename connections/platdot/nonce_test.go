@@ -0,0 +1,290 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package platdot
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	bridge "github.com/rjman-self/Platdot/bindings/Bridge"
+	ethtest "github.com/rjman-self/Platdot/shared/platdot/testing"
+)
+
+// poolBackend queues the transactions sent out of order like the mempool of a node, the simulated
+// backend only accepts the next nonce. The pending nonce only counts the forwarded transactions,
+// as a node lagging the mempool would.
+type poolBackend struct {
+	*backends.SimulatedBackend
+	from   ethcommon.Address
+	lock   sync.Mutex
+	queued map[uint64]*types.Transaction
+}
+
+func (p *poolBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pending, err := p.SimulatedBackend.PendingNonceAt(ctx, p.from)
+	if err != nil {
+		return err
+	}
+	if tx.Nonce() < pending {
+		return errors.New("nonce too low")
+	}
+	if _, ok := p.queued[tx.Nonce()]; ok {
+		return errors.New("replacement transaction underpriced")
+	}
+	p.queued[tx.Nonce()] = tx
+
+	for next, ok := p.queued[pending]; ok; next, ok = p.queued[pending] {
+		err = p.SimulatedBackend.SendTransaction(ctx, next)
+		if err != nil {
+			return err
+		}
+		delete(p.queued, pending)
+		pending++
+	}
+	return nil
+}
+
+func newPoolBackend(t *testing.T) (*poolBackend, *ecdsa.PrivateKey) {
+	key, err := ethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := ethcrypto.PubkeyToAddress(key.PublicKey)
+	alloc := core.GenesisAlloc{from: {Balance: new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)}}
+	return &poolBackend{
+		SimulatedBackend: backends.NewSimulatedBackend(alloc, 100000000),
+		from:             from,
+		queued:           make(map[uint64]*types.Transaction),
+	}, key
+}
+
+func TestNonceManager_ConcurrentVotes(t *testing.T) {
+	backend, key := newPoolBackend(t)
+	bridgeAddr, rId := ethtest.DeploySimulatedBridge(t, backend.SimulatedBackend, key)
+	// The bridge is bound to the pool, the votes go through it
+	b, err := bridge.NewBridge(bridgeAddr, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, err := backend.PendingNonceAt(context.Background(), backend.from)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonces := NewNonceManager(backend, backend.from, nil)
+	votes := 40
+	var wg sync.WaitGroup
+	for i := 0; i < votes; i++ {
+		wg.Add(1)
+		go func(depositNonce uint64) {
+			defer wg.Done()
+			opts, err := bind.NewKeyedTransactorWithChainID(key, ethtest.SimulatedChainId)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			opts.GasLimit = 1000000
+			opts.GasPrice = big.NewInt(1)
+
+			// Every fifth vote first gives back a nonce, as for a rejected transaction
+			if depositNonce%5 == 0 {
+				nonce, err := nonces.Reserve(context.Background())
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				nonces.Release(nonce)
+			}
+
+			nonce, err := nonces.Reserve(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			opts.Nonce = new(big.Int).SetUint64(nonce)
+			_, err = b.VoteProposal(opts, 1, depositNonce, rId, [32]byte{byte(depositNonce)})
+			if err != nil {
+				t.Errorf("vote %d with nonce %d: %s", depositNonce, nonce, err)
+			}
+		}(uint64(i))
+	}
+	wg.Wait()
+
+	if len(backend.queued) != 0 {
+		t.Fatalf("Got: %d Expected: %d transactions left in the pool", len(backend.queued), 0)
+	}
+	pending, err := backend.PendingNonceAt(context.Background(), backend.from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending != start+uint64(votes) {
+		t.Fatalf("Got: %d Expected: %d", pending, start+uint64(votes))
+	}
+
+	backend.Commit()
+	for i := 0; i < votes; i++ {
+		prop, err := b.GetProposal(&bind.CallOpts{}, 1, uint64(i), [32]byte{byte(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// With a threshold of 1 a single vote passes the proposal
+		if prop.Status != 2 {
+			t.Fatalf("Proposal %d. Got: %d Expected: %d", i, prop.Status, 2)
+		}
+	}
+}
+
+func TestNonceManager_Resync(t *testing.T) {
+	backend, key := newPoolBackend(t)
+	nonces := NewNonceManager(backend, backend.from, nil)
+
+	send := func(nonce uint64) error {
+		tx := types.NewTransaction(nonce, backend.from, big.NewInt(1), 21000, big.NewInt(1), nil)
+		signed, err := types.SignTx(tx, types.HomesteadSigner{}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return backend.SendTransaction(context.Background(), signed)
+	}
+
+	nonce, err := nonces.Reserve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce != 0 {
+		t.Fatalf("Got: %d Expected: %d", nonce, 0)
+	}
+	if err := send(nonce); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process sends with the same key
+	for i := uint64(1); i <= 3; i++ {
+		if err := send(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nonce, err = nonces.Reserve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := send(nonce); err == nil {
+		t.Fatal("Expected nonce too low")
+	}
+	err = nonces.Resync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce, err = nonces.Reserve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce != 4 {
+		t.Fatalf("Got: %d Expected: %d", nonce, 4)
+	}
+	if err := send(nonce); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// staticNonceReader returns the same pending nonce, as a node that did not see the sent transactions yet
+type staticNonceReader uint64
+
+func (r staticNonceReader) PendingNonceAt(ctx context.Context, account ethcommon.Address) (uint64, error) {
+	return uint64(r), nil
+}
+
+func TestNonceManager_Release(t *testing.T) {
+	nonces := NewNonceManager(staticNonceReader(10), ethcommon.Address{}, nil)
+	reserve := func(expected uint64) {
+		nonce, err := nonces.Reserve(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nonce != expected {
+			t.Fatalf("Got: %d Expected: %d", nonce, expected)
+		}
+	}
+
+	for i := uint64(10); i < 15; i++ {
+		reserve(i)
+	}
+
+	// Released nonces are reserved again lowest first
+	nonces.Release(13)
+	nonces.Release(11)
+	reserve(11)
+	reserve(13)
+	reserve(15)
+
+	// Releasing the top nonces moves the next nonce back
+	nonces.Release(14)
+	nonces.Release(15)
+	reserve(14)
+
+	// A lagging node does not move the nonce back
+	err := nonces.Resync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reserve(15)
+}
+
+func TestNonceManager_ResyncTimeout(t *testing.T) {
+	timeout := NonceResyncTimeout
+	NonceResyncTimeout = time.Millisecond
+	defer func() { NonceResyncTimeout = timeout }()
+
+	nonces := NewNonceManager(staticNonceReader(10), ethcommon.Address{}, nil)
+	for i := uint64(10); i < 13; i++ {
+		_, err := nonces.Reserve(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	nonces.Release(11)
+
+	// The transactions of the nonces reserved above the chain are still pending
+	err := nonces.Resync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, err := nonces.Reserve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce != 11 {
+		t.Fatalf("Got: %d Expected: %d", nonce, 11)
+	}
+
+	// The chain stayed behind, the transactions were dropped
+	time.Sleep(2 * NonceResyncTimeout)
+	err = nonces.Resync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, err = nonces.Reserve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce != 10 {
+		t.Fatalf("Got: %d Expected: %d", nonce, 10)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethtest
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	bridge "github.com/rjman-self/Platdot/bindings/Bridge"
+	erc20Handler "github.com/rjman-self/Platdot/bindings/ERC20Handler"
+)
+
+// SimulatedChainId is the chain id of the simulated backend
+var SimulatedChainId = big.NewInt(1337)
+
+// DeploySimulatedBridge deploys a bridge with the key as its only relayer and a threshold of 1 on the simulated
+// backend. The returned resource id is mapped to an ERC20 handler, votes can be cast for it.
+func DeploySimulatedBridge(t *testing.T, backend *backends.SimulatedBackend, key *ecdsa.PrivateKey) (common.Address, [32]byte) {
+	from := ethcrypto.PubkeyToAddress(key.PublicKey)
+	opts, err := bind.NewKeyedTransactorWithChainID(key, SimulatedChainId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bridgeAddr, _, b, err := bridge.DeployBridge(opts, backend, 0, []common.Address{from}, big.NewInt(1), big.NewInt(0), big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+	handlerAddr, _, _, err := erc20Handler.DeployERC20Handler(opts, backend, bridgeAddr, [][32]byte{}, []common.Address{}, []common.Address{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+	rId := [32]byte{1}
+	_, err = b.AdminSetResource(opts, handlerAddr, rId, from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+	return bridgeAddr, rId
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
+	"golang.org/x/crypto/blake2b"
+)
+
+// SS58 prefixes of the networks of the relayer
+const (
+	PolkadotSS58Prefix  uint16 = 0
+	KusamaSS58Prefix    uint16 = 2
+	SubstrateSS58Prefix uint16 = 42 // Generic substrate prefix, also used by Westend
+	MaxSS58Prefix       uint16 = 16383
+)
+
+var ErrInvalidAddress = errors.New("invalid ss58 address")
+
+var ss58Context = []byte("SS58PRE")
+
+// SS58PrefixBytes returns the encoded prefix of the addresses, a byte below 64 and two bytes above
+func SS58PrefixBytes(prefix uint16) []byte {
+	if prefix < 64 {
+		return []byte{byte(prefix)}
+	}
+	return []byte{
+		byte((prefix&0xfc)>>2) | 0x40,
+		byte(prefix>>8) | byte((prefix&0x03)<<6),
+	}
+}
+
+// EncodeAddress returns the ss58 address of an account id of 32 bytes
+func EncodeAddress(accountId []byte, prefix uint16) (string, error) {
+	if len(accountId) != 32 {
+		return "", fmt.Errorf("%w: account id of %d bytes", ErrInvalidAddress, len(accountId))
+	}
+	if prefix > MaxSS58Prefix {
+		return "", fmt.Errorf("%w: prefix %d", ErrInvalidAddress, prefix)
+	}
+	payload := append(SS58PrefixBytes(prefix), accountId...)
+	return base58.Encode(append(payload, ss58Checksum(payload)...)), nil
+}
+
+// DecodeAddress returns the account id of an ss58 address, the address must have the prefix and a valid checksum
+func DecodeAddress(address string, prefix uint16) ([]byte, error) {
+	data := base58.Decode(address)
+	if len(data) < 35 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAddress, address)
+	}
+	prefixLen := 1
+	if data[0]&0x40 != 0 {
+		prefixLen = 2
+	}
+	if data[0] >= 128 || len(data) != prefixLen+34 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAddress, address)
+	}
+	if !bytes.Equal(data[:prefixLen], SS58PrefixBytes(prefix)) {
+		return nil, fmt.Errorf("%w: %q does not have the prefix %d", ErrInvalidAddress, address, prefix)
+	}
+	payload := data[:len(data)-2]
+	if !bytes.Equal(data[len(data)-2:], ss58Checksum(payload)) {
+		return nil, fmt.Errorf("%w: checksum of %q", ErrInvalidAddress, address)
+	}
+	return payload[prefixLen:], nil
+}
+
+// ParseAccountID accepts the hex encoded account id or the ss58 address with the prefix
+func ParseAccountID(address string, prefix uint16) ([]byte, error) {
+	if !strings.HasPrefix(address, "0x") {
+		return DecodeAddress(address, prefix)
+	}
+	accountId, err := types.HexDecodeString(address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAddress, err)
+	}
+	if len(accountId) != 32 {
+		return nil, fmt.Errorf("%w: account id of %d bytes", ErrInvalidAddress, len(accountId))
+	}
+	return accountId, nil
+}
+
+// FormatAddress returns the ss58 address of the account id, or its hex encoding if it is not 32 bytes.
+// It is used to print the addresses in the format of the chain.
+func FormatAddress(accountId []byte, prefix uint16) string {
+	address, err := EncodeAddress(accountId, prefix)
+	if err != nil {
+		return types.HexEncodeToString(accountId)
+	}
+	return address
+}
+
+func ss58Checksum(payload []byte) []byte {
+	h := blake2b.Sum512(append(append([]byte{}, ss58Context...), payload...))
+	return h[:2]
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
+)
+
+var alice = types.MustHexDecodeString("0xd43593c715fdd31c61141abd04a99fd6822c8558854ccde39a5684e7a56da27d")
+
+func TestEncodeAddress(t *testing.T) {
+	testCases := []struct {
+		prefix   uint16
+		expected string
+	}{
+		{PolkadotSS58Prefix, "15oF4uVJwmo4TdGW7VfQxNLavjCXviqxT9S1MgbjMNHr6Sp5"},
+		{KusamaSS58Prefix, "HNZata7iMYWmk5RvZRTiAsSDhV8366zq2YGb3tLH5Upf74F"},
+		{SubstrateSS58Prefix, "5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQY"},
+	}
+
+	for _, tc := range testCases {
+		address, err := EncodeAddress(alice, tc.prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if address != tc.expected {
+			t.Fatalf("Got: %s Expected: %s", address, tc.expected)
+		}
+		accountId, err := DecodeAddress(address, tc.prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(accountId, alice) {
+			t.Fatalf("Got: %x Expected: %x", accountId, alice)
+		}
+	}
+}
+
+func TestEncodeAddress_FullPrefix(t *testing.T) {
+	for _, prefix := range []uint16{64, 255, 1284, MaxSS58Prefix} {
+		address, err := EncodeAddress(alice, prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		accountId, err := DecodeAddress(address, prefix)
+		if err != nil {
+			t.Fatalf("%d: %s", prefix, err)
+		}
+		if !bytes.Equal(accountId, alice) {
+			t.Fatalf("Got: %x Expected: %x", accountId, alice)
+		}
+		_, err = DecodeAddress(address, prefix-1)
+		if !errors.Is(err, ErrInvalidAddress) {
+			t.Fatalf("Got: %v Expected: %v", err, ErrInvalidAddress)
+		}
+	}
+	if _, err := EncodeAddress(alice, MaxSS58Prefix+1); !errors.Is(err, ErrInvalidAddress) {
+		t.Fatalf("Got: %v Expected: %v", err, ErrInvalidAddress)
+	}
+}
+
+func TestParseAccountID(t *testing.T) {
+	testCases := []struct {
+		address string
+		err     error
+	}{
+		{"HNZata7iMYWmk5RvZRTiAsSDhV8366zq2YGb3tLH5Upf74F", nil},
+		{"0xd43593c715fdd31c61141abd04a99fd6822c8558854ccde39a5684e7a56da27d", nil},
+		// Polkadot address on Kusama
+		{"15oF4uVJwmo4TdGW7VfQxNLavjCXviqxT9S1MgbjMNHr6Sp5", ErrInvalidAddress},
+		// Bad checksum
+		{"HNZata7iMYWmk5RvZRTiAsSDhV8366zq2YGb3tLH5Upf74G", ErrInvalidAddress},
+		{"0xd43593c715fdd31c61141abd04a99fd6822c8558854ccde39a5684e7a56da2", ErrInvalidAddress},
+		{"0xzz", ErrInvalidAddress},
+		{"", ErrInvalidAddress},
+	}
+
+	for _, tc := range testCases {
+		accountId, err := ParseAccountID(tc.address, KusamaSS58Prefix)
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("%s. Got: %v Expected: %v", tc.address, err, tc.err)
+		}
+		if err == nil && !bytes.Equal(accountId, alice) {
+			t.Fatalf("Got: %x Expected: %x", accountId, alice)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcutil/bech32"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/rjman-self/platdot-utils/msg"
+)
+
+// A deposit into the multisig carries a memo in a System.remark of the same Utility.batch:
+//
+//	platdot:<version>:<dest>:<recipient>[:<metadata>]
+//
+// The version is 1. dest is the decimal id of the destination chain. recipient is the bech32 address
+// of the recipient on the destination chain. metadata is optional 0x prefixed hex encoded data of at
+// most MaxMemoMetadataLength bytes, it is passed on without being interpreted by the relayer.
+//
+// Deposits made before the memo was versioned remark the bare recipient address, they are decoded
+// as version 0 memos without a destination.
+const (
+	MemoPrefix            = "platdot"
+	MemoVersion0          = 0
+	MemoVersion1          = 1
+	MaxMemoLength         = 256
+	MaxMemoMetadataLength = 64
+)
+
+// Prefix of the bech32 addresses of Alaya
+const DefaultRecipientPrefix = "atp"
+
+var ErrInvalidMemo = errors.New("invalid deposit memo")
+var ErrMemoVersion = errors.New("unsupported deposit memo version")
+var ErrMemoRecipient = errors.New("invalid deposit memo recipient")
+
+// Memo is the destination of a deposit
+type Memo struct {
+	Version   uint8
+	Dest      msg.ChainId // Not set in version 0 memos
+	Recipient string
+	Metadata  []byte
+}
+
+// NewMemo creates a memo of the current version
+func NewMemo(dest msg.ChainId, recipient string, metadata []byte) Memo {
+	return Memo{Version: MemoVersion1, Dest: dest, Recipient: recipient, Metadata: metadata}
+}
+
+// Encode returns the remark of the memo
+func (m Memo) Encode() []byte {
+	if m.Version == MemoVersion0 {
+		return []byte(m.Recipient)
+	}
+	memo := fmt.Sprintf("%s:%d:%d:%s", MemoPrefix, m.Version, m.Dest, m.Recipient)
+	if len(m.Metadata) != 0 {
+		memo += ":" + hexutil.Encode(m.Metadata)
+	}
+	return []byte(memo)
+}
+
+// DecodeMemo parses the remark of a deposit. The recipient is only checked to be a well formed
+// field, use ValidateRecipient to check it is an address of the destination chain.
+func DecodeMemo(data []byte) (Memo, error) {
+	if len(data) == 0 || len(data) > MaxMemoLength {
+		return Memo{}, fmt.Errorf("%w: length %d", ErrInvalidMemo, len(data))
+	}
+	if !bytes.HasPrefix(data, []byte(MemoPrefix+":")) {
+		recipient := string(data)
+		if !isMemoField(recipient) {
+			return Memo{}, fmt.Errorf("%w: %q", ErrInvalidMemo, data)
+		}
+		return Memo{Version: MemoVersion0, Recipient: recipient}, nil
+	}
+
+	fields := strings.Split(string(data), ":")
+	version, err := strconv.ParseUint(fields[1], 10, 8)
+	if err != nil || fields[1] != strconv.FormatUint(version, 10) {
+		return Memo{}, fmt.Errorf("%w: version %q", ErrInvalidMemo, fields[1])
+	}
+	if version != MemoVersion1 {
+		return Memo{}, fmt.Errorf("%w: %d", ErrMemoVersion, version)
+	}
+	if len(fields) != 4 && len(fields) != 5 {
+		return Memo{}, fmt.Errorf("%w: %d fields", ErrInvalidMemo, len(fields))
+	}
+
+	dest, err := strconv.ParseUint(fields[2], 10, 8)
+	if err != nil || fields[2] != strconv.FormatUint(dest, 10) {
+		return Memo{}, fmt.Errorf("%w: dest %q", ErrInvalidMemo, fields[2])
+	}
+	if !isMemoField(fields[3]) {
+		return Memo{}, fmt.Errorf("%w: recipient %q", ErrInvalidMemo, fields[3])
+	}
+	m := Memo{Version: uint8(version), Dest: msg.ChainId(dest), Recipient: fields[3]}
+
+	if len(fields) == 5 {
+		metadata, err := hexutil.Decode(fields[4])
+		if err != nil || len(metadata) == 0 || hexutil.Encode(metadata) != fields[4] {
+			return Memo{}, fmt.Errorf("%w: metadata %q", ErrInvalidMemo, fields[4])
+		}
+		if len(metadata) > MaxMemoMetadataLength {
+			return Memo{}, fmt.Errorf("%w: metadata of %d bytes", ErrInvalidMemo, len(metadata))
+		}
+		m.Metadata = metadata
+	}
+	return m, nil
+}
+
+// ValidateRecipient checks that the recipient is a bech32 address with the prefix
+func (m Memo) ValidateRecipient(prefix string) error {
+	hrp, data, err := bech32.Decode(m.Recipient)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrMemoRecipient, err)
+	}
+	if hrp != prefix {
+		return fmt.Errorf("%w: prefix %s, expected %s", ErrMemoRecipient, hrp, prefix)
+	}
+	addr, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrMemoRecipient, err)
+	}
+	if len(addr) != common.AddressLength {
+		return fmt.Errorf("%w: address of %d bytes", ErrMemoRecipient, len(addr))
+	}
+	return nil
+}
+
+// RecipientPrefix returns the prefix of the bech32 recipient, it selects the destination of a version 0 memo
+func (m Memo) RecipientPrefix() (string, error) {
+	hrp, _, err := bech32.Decode(m.Recipient)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrMemoRecipient, err)
+	}
+	return hrp, nil
+}
+
+// isMemoField checks that a field is not empty and only has printable characters other than the separator
+func isMemoField(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range []byte(s) {
+		if c <= ' ' || c > '~' || c == ':' {
+			return false
+		}
+	}
+	return true
+}
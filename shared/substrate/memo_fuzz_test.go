@@ -0,0 +1,35 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+//go:build go1.18
+// +build go1.18
+
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzDecodeMemo(f *testing.F) {
+	f.Add([]byte("platdot:1:2:" + testRecipient))
+	f.Add([]byte("platdot:1:2:" + testRecipient + ":0x0102"))
+	f.Add([]byte(testRecipient))
+	f.Add([]byte("platdot:2:2:" + testRecipient))
+	f.Add([]byte("platdot:1:2::"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m, err := DecodeMemo(data)
+		if err != nil {
+			return
+		}
+		// A decoded memo is encoded back to the same remark
+		if !bytes.Equal(m.Encode(), data) {
+			t.Fatalf("Got: %q Expected: %q", m.Encode(), data)
+		}
+		if len(m.Metadata) > MaxMemoMetadataLength {
+			t.Fatalf("Got: %d Expected at most: %d", len(m.Metadata), MaxMemoMetadataLength)
+		}
+		_ = m.ValidateRecipient(DefaultRecipientPrefix)
+	})
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testRecipient = "atp1l7fmg5cgl4qhmucr6eg44vzdn6y6w5x2q695d9"
+
+func TestDecodeMemo(t *testing.T) {
+	testCases := []struct {
+		memo     string
+		expected Memo
+		err      error
+	}{
+		{"platdot:1:2:" + testRecipient, Memo{Version: 1, Dest: 2, Recipient: testRecipient}, nil},
+		{"platdot:1:2:" + testRecipient + ":0x0102", Memo{Version: 1, Dest: 2, Recipient: testRecipient, Metadata: []byte{1, 2}}, nil},
+		{testRecipient, Memo{Version: 0, Recipient: testRecipient}, nil},
+		{"", Memo{}, ErrInvalidMemo},
+		{"platdot:2:2:" + testRecipient, Memo{}, ErrMemoVersion},
+		{"platdot:01:2:" + testRecipient, Memo{}, ErrInvalidMemo},
+		{"platdot:1:256:" + testRecipient, Memo{}, ErrInvalidMemo},
+		{"platdot:1:2", Memo{}, ErrInvalidMemo},
+		{"platdot:1:2::0x01", Memo{}, ErrInvalidMemo},
+		{"platdot:1:2:" + testRecipient + ":0x", Memo{}, ErrInvalidMemo},
+		{"platdot:1:2:" + testRecipient + ":0xAB", Memo{}, ErrInvalidMemo},
+		{"platdot:1:2:" + testRecipient + ":0x01:0x02", Memo{}, ErrInvalidMemo},
+		{"platdot:1:2:" + testRecipient + ":0x" + string(bytes.Repeat([]byte("00"), MaxMemoMetadataLength+1)), Memo{}, ErrInvalidMemo},
+		{"atp1 l7fmg5", Memo{}, ErrInvalidMemo},
+	}
+
+	for _, tc := range testCases {
+		m, err := DecodeMemo([]byte(tc.memo))
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("%s. Got: %v Expected: %v", tc.memo, err, tc.err)
+		}
+		if !reflect.DeepEqual(m, tc.expected) {
+			t.Fatalf("%s. Got: %v Expected: %v", tc.memo, m, tc.expected)
+		}
+		if err == nil && !bytes.Equal(m.Encode(), []byte(tc.memo)) {
+			t.Fatalf("Got: %s Expected: %s", m.Encode(), tc.memo)
+		}
+	}
+}
+
+func TestValidateRecipient(t *testing.T) {
+	testCases := []struct {
+		recipient string
+		err       error
+	}{
+		{testRecipient, nil},
+		// Testnet prefix
+		{"atx1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq89qwkc", ErrMemoRecipient},
+		// Bad checksum
+		{"atp1l7fmg5cgl4qhmucr6eg44vzdn6y6w5x2q695d8", ErrMemoRecipient},
+		// 19 bytes
+		{"atp1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqscz5u3", ErrMemoRecipient},
+		{"0xff93B45308FD417dF303D6515aB04D9e89a750Ca", ErrMemoRecipient},
+	}
+
+	for _, tc := range testCases {
+		err := NewMemo(1, tc.recipient, nil).ValidateRecipient(DefaultRecipientPrefix)
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("%s. Got: %v Expected: %v", tc.recipient, err, tc.err)
+		}
+	}
+}
@@ -21,7 +21,11 @@ var SudoMethod Method = "Sudo.sudo"
 
 var BalancesTransferMethod Method = "Balances.transfer"
 var BalancesTransferKeepAliveMethod Method = "Balances.transfer_keep_alive"
+var BalancesTransferAllMethod Method = "Balances.transfer_all"
+var AssetsTransferMethod Method = "Assets.transfer"
+var UniquesTransferMethod Method = "Uniques.transfer"
 var SystemRemark Method = "System.remark"
+var SystemRemarkWithEvent Method = "System.remark_with_event"
 var UtilityBatch Method = "Utility.batch"
-var UtilityBatchAll Method = "Utility.batchall"
+var UtilityBatchAll Method = "Utility.batch_all"
 var MultisigAsMulti Method = "Multisig.as_multi"
@@ -0,0 +1,45 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
+	"golang.org/x/crypto/blake2b"
+)
+
+var ErrInvalidSignatories = errors.New("invalid multisig signatories")
+
+var multisigContext = []byte("modlpy/utilisuba")
+
+// MultisigAccountID derives the account of the Multisig pallet that is controlled by the signatories
+// with the threshold, like pallet_multisig::multi_account_id. The order of the signatories does not matter.
+func MultisigAccountID(signatories []types.AccountID, threshold uint16) (types.AccountID, error) {
+	if threshold == 0 || int(threshold) > len(signatories) {
+		return types.AccountID{}, fmt.Errorf("%w: threshold %d of %d signatories", ErrInvalidSignatories, threshold, len(signatories))
+	}
+	sorted := make([]types.AccountID, len(signatories))
+	copy(sorted, signatories)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			return types.AccountID{}, fmt.Errorf("%w: duplicate signatory %s", ErrInvalidSignatories, types.HexEncodeToString(sorted[i][:]))
+		}
+	}
+
+	encoded, err := types.EncodeToBytes(struct {
+		Signatories []types.AccountID
+		Threshold   types.U16
+	}{sorted, types.U16(threshold)})
+	if err != nil {
+		return types.AccountID{}, err
+	}
+	return types.AccountID(blake2b.Sum256(append(append([]byte{}, multisigContext...), encoded...))), nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v2/types"
+)
+
+var (
+	bob     = types.MustHexDecodeString("0x8eaf04151687736326c9fea17e25fc5287613693c912909cb226aa4794f26a48")
+	charlie = types.MustHexDecodeString("0x90b5ab205c6974c9ea841be688864633dc9ca8a357843eeacf2314649965fe22")
+)
+
+func TestMultisigAccountID(t *testing.T) {
+	// The 2 of 3 multisig of Alice, Bob and Charlie derived by polkadot.js
+	expected := "5DjYJStmdZ2rcqXbXGX7TW85JsrW6uG4y9MUcLq2BoPMpRA7"
+
+	for _, signatories := range [][]types.AccountID{
+		{types.NewAccountID(alice), types.NewAccountID(bob), types.NewAccountID(charlie)},
+		{types.NewAccountID(charlie), types.NewAccountID(alice), types.NewAccountID(bob)},
+	} {
+		multisig, err := MultisigAccountID(signatories, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		address := FormatAddress(multisig[:], SubstrateSS58Prefix)
+		if address != expected {
+			t.Fatalf("Got: %s Expected: %s", address, expected)
+		}
+	}
+
+	multisig, err := MultisigAccountID([]types.AccountID{types.NewAccountID(alice), types.NewAccountID(bob), types.NewAccountID(charlie)}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if FormatAddress(multisig[:], SubstrateSS58Prefix) == expected {
+		t.Fatal("the threshold is not part of the multisig account")
+	}
+}
+
+func TestMultisigAccountID_Invalid(t *testing.T) {
+	testCases := []struct {
+		signatories []types.AccountID
+		threshold   uint16
+	}{
+		{[]types.AccountID{types.NewAccountID(alice), types.NewAccountID(bob)}, 0},
+		{[]types.AccountID{types.NewAccountID(alice), types.NewAccountID(bob)}, 3},
+		{[]types.AccountID{types.NewAccountID(alice), types.NewAccountID(alice)}, 2},
+		{nil, 1},
+	}
+
+	for _, tc := range testCases {
+		_, err := MultisigAccountID(tc.signatories, tc.threshold)
+		if !errors.Is(err, ErrInvalidSignatories) {
+			t.Fatalf("Got: %v Expected: %v", err, ErrInvalidSignatories)
+		}
+	}
+}